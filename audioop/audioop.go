@@ -10,6 +10,14 @@ func GetSample(cp []byte, size int, offset int) (int32, error) {
 	return getSample(cp, size, offset)
 }
 
+func GetSamples(cp []byte, size int) ([]int32, error) {
+	err := checkParameters(len(cp), size)
+	if err != nil {
+		return nil, err
+	}
+	return getSamples(cp, size)
+}
+
 func Max(cp []byte, size int) (int32, error) {
 	err := checkParameters(len(cp), size)
 	if err != nil {
@@ -115,6 +123,34 @@ func RMS(cp []byte, size int) (int32, error) {
 	return int32(math.Sqrt(float64(sumSquares) / float64(sampleCount))), nil
 }
 
+// RMSBias is RMS with `bias` added to every sample first, e.g. RMSBias(cp, 1, -128)
+// measures unsigned 8-bit audio. Unlike RMS the result isn't truncated to an
+// integer, so that it can be rescaled to another sample width precisely.
+func RMSBias(cp []byte, size int, bias int) (float64, error) {
+	err := checkParameters(len(cp), size)
+	if err != nil {
+		return 0, err
+	}
+
+	sampleCount := sampleCount(cp, size)
+	if sampleCount == 0 {
+		return 0, nil
+	}
+
+	var sumSquares float64
+	for i := 0; i < sampleCount; i++ {
+		sample, err := getSample(cp, size, i)
+		if err != nil {
+			return 0, err
+		}
+
+		biased := float64(overflow(sample+int32(bias), size))
+		sumSquares += biased * biased
+	}
+
+	return math.Sqrt(sumSquares / float64(sampleCount)), nil
+}
+
 func FindFit(cp1 []byte, cp2 []byte) (int32, int32, error) {
 	size := 2
 
@@ -381,28 +417,40 @@ func Cross(cp []byte, size int) (int32, error) {
 }
 
 func Mul(cp []byte, size int, factor float64) ([]byte, error) {
-	err := checkParameters(len(cp), size)
-	if err != nil {
+	buf := make([]byte, len(cp))
+	if err := MulInto(buf, cp, size, factor); err != nil {
 		return nil, err
 	}
 
-	clip := getClipFunc(size)
-	buf := make([]byte, len(cp))
+	return buf, nil
+}
 
-	samples, err := getSamples(cp, size)
+// MulInto is the buffer reusing version of Mul, it multiplies the samples of src by
+// factor and stores the clipped products in dst. dst may be src itself to work in place.
+func MulInto(dst []byte, src []byte, size int, factor float64) error {
+	err := checkParameters(len(src), size)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	for i, sample := range samples {
-		clippedSample := clip(int32(float64(sample) * factor))
-		err := putSample(buf, size, i, clippedSample)
+	if len(dst) != len(src) {
+		return NewError("samples length should be same")
+	}
+
+	clip := getClip64Func(size)
+
+	for i := 0; i < sampleCount(src, size); i++ {
+		sample, err := getSample(src, size, i)
 		if err != nil {
-			return nil, err
+			return err
+		}
+
+		if err := putSample(dst, size, i, clip(int64(float64(sample)*factor))); err != nil {
+			return err
 		}
 	}
 
-	return buf, nil
+	return nil
 }
 
 func ToMono(cp []byte, size int, fac1, fac2 float64) ([]byte, error) {
@@ -415,7 +463,7 @@ func ToMono(cp []byte, size int, fac1, fac2 float64) ([]byte, error) {
 	buf := make([]byte, len(cp)/2)
 
 	for i := 0; i < sampleCount(cp, size); i += 2 {
-		lSample, err := getSample(cp, size, 1)
+		lSample, err := getSample(cp, size, i)
 		if err != nil {
 			return nil, err
 		}
@@ -479,7 +527,7 @@ func Add(cp1 []byte, cp2 []byte, size int) ([]byte, error) {
 		return nil, NewError("samples length should be same")
 	}
 
-	clip := getClipFunc(size)
+	clip := getClip64Func(size)
 	buf := make([]byte, len(cp1))
 
 	for i := 0; i < sampleCount(cp1, size); i++ {
@@ -493,13 +541,46 @@ func Add(cp1 []byte, cp2 []byte, size int) ([]byte, error) {
 			return nil, err
 		}
 
-		sample := clip(sample1 + sample2)
+		sample := clip(int64(sample1) + int64(sample2))
 		putSample(buf, size, i, sample)
 	}
 
 	return buf, nil
 }
 
+// AddInto is the in-place version of Add, it adds the samples of src to dst
+// and stores the clipped sums in dst, so no new buffer is allocated.
+func AddInto(dst []byte, src []byte, size int) error {
+	err := checkParameters(len(dst), size)
+	if err != nil {
+		return err
+	}
+
+	if len(dst) != len(src) {
+		return NewError("samples length should be same")
+	}
+
+	clip := getClip64Func(size)
+
+	for i := 0; i < sampleCount(dst, size); i++ {
+		sample1, err := getSample(dst, size, i)
+		if err != nil {
+			return err
+		}
+
+		sample2, err := getSample(src, size, i)
+		if err != nil {
+			return err
+		}
+
+		if err := putSample(dst, size, i, clip(int64(sample1)+int64(sample2))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func Bias(cp []byte, size int, bias int) ([]byte, error) {
 	err := checkParameters(len(cp), size)
 	if err != nil {
@@ -543,20 +624,47 @@ func Reverse(cp []byte, size int) ([]byte, error) {
 	return buf, nil
 }
 
-func Lin2Lin(cp []byte, size, size2 int) ([]byte, error) {
+// Byteswap converts big-endian samples to little-endian and vice versa.
+func Byteswap(cp []byte, size int) ([]byte, error) {
 	err := checkParameters(len(cp), size)
 	if err != nil {
 		return nil, err
 	}
 
-	err = checkSize(size2)
-	if err != nil {
-		return nil, err
+	buf := make([]byte, len(cp))
+	for i := 0; i < len(cp); i += size {
+		for j := 0; j < size; j++ {
+			buf[i+j] = cp[i+size-1-j]
+		}
 	}
 
+	return buf, nil
+}
+
+func Lin2Lin(cp []byte, size, size2 int) ([]byte, error) {
 	if size == size2 {
+		if err := checkParameters(len(cp), size); err != nil {
+			return nil, err
+		}
 		return cp, nil
 	}
+	return Lin2LinBias(cp, size, size2, 0, 0)
+}
+
+// Lin2LinBias is Lin2Lin fused with Bias: `bias` is added to every sample before
+// the conversion and `bias2` to every converted sample, all in a single pass and
+// a single allocation. It's handy for unsigned 8-bit audio, e.g.
+// Lin2LinBias(cp, 1, 2, -128, 0) converts unsigned 8-bit samples to signed 16-bit.
+func Lin2LinBias(cp []byte, size, size2 int, bias, bias2 int) ([]byte, error) {
+	err := checkParameters(len(cp), size)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkSize(size2)
+	if err != nil {
+		return nil, err
+	}
 
 	newLen := (len(cp) / size) * size2
 	buf := make([]byte, newLen)
@@ -567,13 +675,15 @@ func Lin2Lin(cp []byte, size, size2 int) ([]byte, error) {
 			return nil, err
 		}
 
+		sample = overflow(sample+int32(bias), size)
+
 		if size < size2 {
-			sample = sample << uint32(4*size2/size)
+			sample = sample << uint32(8*(size2-size))
 		} else if size > size2 {
-			sample = sample >> uint32(4*size/size2)
+			sample = sample >> uint32(8*(size-size2))
 		}
 
-		sample = overflow(sample, size2)
+		sample = overflow(sample+int32(bias2), size2)
 		err = putSample(buf, size2, i, sample)
 		if err != nil {
 			return nil, err
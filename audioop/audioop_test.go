@@ -0,0 +1,103 @@
+package audioop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddInto(t *testing.T) {
+	dst := []byte{0x01, 0x00, 0xff, 0x7f, 0x00, 0x80}
+	src := []byte{0x02, 0x00, 0x01, 0x00, 0xff, 0xff}
+
+	expected, err := Add(dst, src, 2)
+	assert.NoError(t, err)
+
+	err = AddInto(dst, src, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, dst)
+	assert.Equal(t, []byte{0x03, 0x00, 0xff, 0x7f, 0x00, 0x80}, dst)
+
+	assert.Error(t, AddInto(dst, src[:4], 2))
+}
+
+func TestToMono(t *testing.T) {
+	// L/R pairs of 16-bit samples: (100, 300), (-200, -400)
+	cp := []byte{0x64, 0x00, 0x2c, 0x01, 0x38, 0xff, 0x70, 0xfe}
+	mono, err := ToMono(cp, 2, 0.5, 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xc8, 0x00, 0xd4, 0xfe}, mono)
+
+	left, err := ToMono(cp, 2, 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x64, 0x00, 0x38, 0xff}, left)
+}
+
+func TestByteswap(t *testing.T) {
+	cp := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+
+	swapped, err := Byteswap(cp, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x02, 0x01, 0x04, 0x03, 0x06, 0x05, 0x08, 0x07}, swapped)
+
+	swapped, err = Byteswap(cp, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x04, 0x03, 0x02, 0x01, 0x08, 0x07, 0x06, 0x05}, swapped)
+
+	swapped, err = Byteswap(cp, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, cp, swapped)
+
+	_, err = Byteswap(cp[:3], 2)
+	assert.Error(t, err)
+}
+
+func TestMulInto(t *testing.T) {
+	// 16-bit samples: 100, -200, 30000
+	src := []byte{0x64, 0x00, 0x38, 0xff, 0x30, 0x75}
+	expected, err := Mul(src, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xc8, 0x00, 0x70, 0xfe, 0xff, 0x7f}, expected)
+
+	dst := make([]byte, len(src))
+	assert.NoError(t, MulInto(dst, src, 2, 2))
+	assert.Equal(t, expected, dst)
+
+	// In place
+	assert.NoError(t, MulInto(src, src, 2, 2))
+	assert.Equal(t, expected, src)
+
+	assert.Error(t, MulInto(dst[:4], src, 2, 2))
+	assert.Error(t, MulInto(dst[:3], src[:3], 2, 2))
+}
+
+// gainBenchmarkBuffer is 50MB of 16-bit audio, and gainBenchmarkChunk is 10ms of it at
+// 44.1kHz stereo, like a streaming normalizer would process.
+const (
+	gainBenchmarkBuffer = 50 << 20
+	gainBenchmarkChunk  = 441 * 2 * 2
+)
+
+func BenchmarkMul(b *testing.B) {
+	buf := make([]byte, gainBenchmarkBuffer)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for pos := 0; pos+gainBenchmarkChunk <= len(buf); pos += gainBenchmarkChunk {
+			chunk, _ := Mul(buf[pos:pos+gainBenchmarkChunk], 2, 0.5)
+			copy(buf[pos:], chunk)
+		}
+	}
+}
+
+func BenchmarkMulInto(b *testing.B) {
+	buf := make([]byte, gainBenchmarkBuffer)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for pos := 0; pos+gainBenchmarkChunk <= len(buf); pos += gainBenchmarkChunk {
+			chunk := buf[pos : pos+gainBenchmarkChunk]
+			_ = MulInto(chunk, chunk, 2, 0.5)
+		}
+	}
+}
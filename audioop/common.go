@@ -1,9 +1,7 @@
 package audioop
 
 import (
-	"bytes"
 	"encoding/binary"
-	"math"
 )
 
 func getSamples(cp []byte, size int) ([]int32, error) {
@@ -85,21 +83,22 @@ func sampleCount(cp []byte, size int) int {
 func putSample(cp []byte, size int, offset int, value int32) error {
 	start := offset * size
 	end := start + size
-
-	write := func(v interface{}) error {
-		return binary.Write(bytes.NewBuffer(cp[start:end]), binary.LittleEndian, v)
+	if end > len(cp) {
+		return NewError("offset out of range")
 	}
 
+	// Write in place, a `bytes.Buffer` over cp[start:end] would append after `end`.
 	switch size {
 	case 1:
-		return write(int8(value))
+		cp[start] = byte(int8(value))
 	case 2:
-		return write(int16(value))
+		binary.LittleEndian.PutUint16(cp[start:end], uint16(int16(value)))
 	case 4:
-		return write(int32(value))
+		binary.LittleEndian.PutUint32(cp[start:end], uint32(value))
 	default:
 		return NewError("size should be 1, 2, or 4")
 	}
+	return nil
 }
 
 func overflow(value int32, size int) int32 {
@@ -109,9 +108,12 @@ func overflow(value int32, size int) int32 {
 		return value
 	}
 
-	bits := size * 8
-	offset := int(math.Pow(2, float64(bits-1)))
-	result := (int(value) + offset) % (int(math.Pow(2, float64(bits))))
+	// Wrap around like a fixed width integer. The modulo is taken twice since
+	// `%` keeps the sign of the dividend in Go.
+	bits := uint(size * 8)
+	offset := int64(1) << (bits - 1)
+	modulo := int64(1) << bits
+	result := ((int64(value)+offset)%modulo + modulo) % modulo
 	return int32(result - offset)
 }
 
@@ -123,6 +125,22 @@ func getClipFunc(size int) func(value int32) int32 {
 	}
 }
 
+// getClip64Func is like getClipFunc but accepts values that may have overflowed int32,
+// e.g. the sum of two 32-bit samples.
+func getClip64Func(size int) func(value int64) int32 {
+	maxValue := int64(getMaxValue(size))
+	minValue := int64(getMinValue(size))
+	return func(value int64) int32 {
+		if value > maxValue {
+			return int32(maxValue)
+		}
+		if value < minValue {
+			return int32(minValue)
+		}
+		return int32(value)
+	}
+}
+
 func getMaxValue(size int) int32 {
 	switch size {
 	case 1:
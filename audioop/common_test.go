@@ -26,3 +26,37 @@ func Test_getMinValue(t *testing.T) {
 	assert.Equal(t, int32(-0x8000), getMinValue(2))
 	assert.Equal(t, int32(-0x80000000), getMinValue(4))
 }
+
+func Test_putSample(t *testing.T) {
+	buf := make([]byte, 6)
+	assert.Nil(t, putSample(buf, 2, 0, 1))
+	assert.Nil(t, putSample(buf, 2, 2, -2))
+	assert.Equal(t, []byte{0x01, 0x00, 0x00, 0x00, 0xFE, 0xFF}, buf)
+
+	assert.Error(t, putSample(buf, 2, 3, 1))
+}
+
+func Test_overflow(t *testing.T) {
+	assert.Equal(t, int32(16), overflow(-240, 1))
+	assert.Equal(t, int32(-128), overflow(128, 1))
+	assert.Equal(t, int32(127), overflow(127, 1))
+	assert.Equal(t, int32(-32768), overflow(32768, 2))
+	assert.Equal(t, int32(32767), overflow(-32769, 2))
+}
+
+func TestLin2LinBias(t *testing.T) {
+	// Unsigned 8-bit to signed 16-bit
+	data, err := Lin2LinBias([]byte{0x80, 0x90, 0x70, 0xff, 0x00}, 1, 2, -128, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x10, 0x00, 0xf0, 0x00, 0x7f, 0x00, 0x80}, data)
+
+	// And back
+	data, err = Lin2LinBias(data, 2, 1, 0, 128)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x80, 0x90, 0x70, 0xff, 0x00}, data)
+
+	// 16-bit to 32-bit shifts by 16 bits
+	data, err = Lin2Lin([]byte{0x01, 0x00}, 2, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x01, 0x00}, data)
+}
@@ -0,0 +1,91 @@
+package godub
+
+import "math"
+
+// biquad is a second order IIR filter in direct form I.
+// Coefficients are normalized, that is a0 == 1.
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+
+	x1, x2 float64
+	y1, y2 float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+func (f *biquad) reset() {
+	f.x1, f.x2, f.y1, f.y2 = 0, 0, 0, 0
+}
+
+// newLowPass returns a second order Butterworth low pass filter (RBJ cookbook).
+func newLowPass(cutoff, frameRate float64) *biquad {
+	w0 := 2 * math.Pi * cutoff / frameRate
+	alpha := math.Sin(w0) / math.Sqrt2 // Q = 1/sqrt(2)
+	cosW0 := math.Cos(w0)
+	a0 := 1 + alpha
+	return &biquad{
+		b0: (1 - cosW0) / 2 / a0,
+		b1: (1 - cosW0) / a0,
+		b2: (1 - cosW0) / 2 / a0,
+		a1: -2 * cosW0 / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
+
+// newPeaking returns a peaking EQ filter boosting or cutting `gain` dB around
+// frequency f0 (RBJ cookbook).
+func newPeaking(f0, gain, q, frameRate float64) *biquad {
+	a := math.Pow(10, gain/40)
+	w0 := 2 * math.Pi * f0 / frameRate
+	alpha := math.Sin(w0) / (2 * q)
+	cosW0 := math.Cos(w0)
+	a0 := 1 + alpha/a
+	return &biquad{
+		b0: (1 + alpha*a) / a0,
+		b1: -2 * cosW0 / a0,
+		b2: (1 - alpha*a) / a0,
+		a1: -2 * cosW0 / a0,
+		a2: (1 - alpha/a) / a0,
+	}
+}
+
+// newLowShelf returns a low shelf filter changing frequencies below f0 by `gain` dB,
+// q is the shelf slope, 1/sqrt(2) is the steepest without overshoot (RBJ cookbook).
+func newLowShelf(f0, gain, q, frameRate float64) *biquad {
+	a := math.Pow(10, gain/40)
+	w0 := 2 * math.Pi * f0 / frameRate
+	alpha := math.Sin(w0) / (2 * q)
+	cosW0 := math.Cos(w0)
+	sqrtA2alpha := 2 * math.Sqrt(a) * alpha
+	a0 := (a + 1) + (a-1)*cosW0 + sqrtA2alpha
+	return &biquad{
+		b0: a * ((a + 1) - (a-1)*cosW0 + sqrtA2alpha) / a0,
+		b1: 2 * a * ((a - 1) - (a+1)*cosW0) / a0,
+		b2: a * ((a + 1) - (a-1)*cosW0 - sqrtA2alpha) / a0,
+		a1: -2 * ((a - 1) + (a+1)*cosW0) / a0,
+		a2: ((a + 1) + (a-1)*cosW0 - sqrtA2alpha) / a0,
+	}
+}
+
+// newHighShelf is newLowShelf for frequencies above f0.
+func newHighShelf(f0, gain, q, frameRate float64) *biquad {
+	a := math.Pow(10, gain/40)
+	w0 := 2 * math.Pi * f0 / frameRate
+	alpha := math.Sin(w0) / (2 * q)
+	cosW0 := math.Cos(w0)
+	sqrtA2alpha := 2 * math.Sqrt(a) * alpha
+	a0 := (a + 1) - (a-1)*cosW0 + sqrtA2alpha
+	return &biquad{
+		b0: a * ((a + 1) + (a-1)*cosW0 + sqrtA2alpha) / a0,
+		b1: -2 * a * ((a - 1) + (a+1)*cosW0) / a0,
+		b2: a * ((a + 1) + (a-1)*cosW0 - sqrtA2alpha) / a0,
+		a1: 2 * ((a - 1) - (a+1)*cosW0) / a0,
+		a2: ((a + 1) - (a-1)*cosW0 - sqrtA2alpha) / a0,
+	}
+}
@@ -0,0 +1,159 @@
+package godub
+
+import (
+	"io"
+	"math"
+	"time"
+
+	"github.com/wonglyxng/godub/wav"
+)
+
+// CompositeSegment is a lazy concatenation of audio segments. It holds references to
+// the segments instead of copying their data, which is only streamed on export, so
+// that many clips can be stitched with constant memory.
+type CompositeSegment struct {
+	format   *AudioSegment
+	segments []*AudioSegment
+}
+
+// NewCompositeSegment 创建惰性拼接的音频,不复制音频数据
+//
+// 参数:
+//   - segments: 需要拼接的音频片段,至少一个
+//
+// 说明:
+//   - 与Concat不同,只保存音频片段的引用,在ExportWav时依次写出各片段的数据,
+//     拼接结果不会整体存放在内存中
+//   - 所有音频片段必须已经是相同的格式(声道数、采样率、采样宽度),否则返回错误,
+//     可以先调用SyncTo统一格式
+//   - 音频片段是不可变的,所以拼接之后仍然可以安全地在别处使用
+func NewCompositeSegment(segments ...*AudioSegment) (*CompositeSegment, error) {
+	if len(segments) == 0 {
+		return nil, NewAudioSegmentError("at least one segment is required")
+	}
+	if segments[0] == nil {
+		return nil, NewAudioSegmentError("segment 0 should not be nil")
+	}
+
+	format, err := segments[0].derive(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CompositeSegment{format: format}
+	if err = c.add(segments); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Append returns a new CompositeSegment with the segments appended, c is not modified.
+func (c *CompositeSegment) Append(segments ...*AudioSegment) (*CompositeSegment, error) {
+	appended := &CompositeSegment{format: c.format, segments: append([]*AudioSegment(nil), c.segments...)}
+	if err := appended.add(segments); err != nil {
+		return nil, err
+	}
+	return appended, nil
+}
+
+func (c *CompositeSegment) add(segments []*AudioSegment) error {
+	for i, seg := range segments {
+		if seg == nil {
+			return NewAudioSegmentError("segment %d should not be nil", i)
+		}
+		if seg.Format() != c.format.Format() {
+			return NewAudioSegmentError(
+				"segment %d is %d channels, %dHz, %d bytes per sample, expected %d channels, %dHz, "+
+					"%d bytes per sample, sync the segments first",
+				i, seg.channels, seg.frameRate, seg.sampleWidth,
+				c.format.channels, c.format.frameRate, c.format.sampleWidth)
+		}
+		c.segments = append(c.segments, seg)
+	}
+	return nil
+}
+
+// Segments returns the child segments in order.
+func (c *CompositeSegment) Segments() []*AudioSegment {
+	return append([]*AudioSegment(nil), c.segments...)
+}
+
+func (c *CompositeSegment) SampleWidth() uint16 {
+	return c.format.sampleWidth
+}
+
+func (c *CompositeSegment) FrameRate() uint32 {
+	return c.format.frameRate
+}
+
+func (c *CompositeSegment) FrameWidth() uint32 {
+	return c.format.frameWidth
+}
+
+func (c *CompositeSegment) Channels() uint16 {
+	return c.format.channels
+}
+
+// Len returns the total size of the data in bytes.
+func (c *CompositeSegment) Len() int {
+	n := 0
+	for _, seg := range c.segments {
+		n += len(seg.data)
+	}
+	return n
+}
+
+// FrameCount returns the total number of frames.
+func (c *CompositeSegment) FrameCount() float64 {
+	if c.format.frameWidth == 0 {
+		return 0
+	}
+	return float64(c.Len() / int(c.format.frameWidth))
+}
+
+// Duration returns the total duration in milliseconds, like AudioSegment.Duration.
+func (c *CompositeSegment) Duration() int64 {
+	if c.format.frameRate == 0 {
+		return 0
+	}
+	return int64(math.Round(1000.0 * c.FrameCount() / float64(c.format.frameRate)))
+}
+
+func (c *CompositeSegment) DurationTime() time.Duration {
+	if c.format.frameRate == 0 {
+		return 0
+	}
+	return time.Duration(int64(c.FrameCount()) * int64(time.Second) / int64(c.format.frameRate))
+}
+
+// ExportWav writes the concatenation as WAV, the data of the segments is written one by
+// one without being joined in memory. The data is written in the sample width of the
+// segments, so 24-bit sources are written as 32-bit, see AudioSegment.SourceSampleWidth;
+// use Flatten and Exporter.WithSourceSampleWidth to write 24-bit.
+func (c *CompositeSegment) ExportWav(w io.Writer) error {
+	size := c.Len()
+	if size > math.MaxUint32 {
+		return NewAudioSegmentError("%d bytes of data exceed the size limit of WAV", size)
+	}
+
+	if err := wav.EncodeHeader(w, c.format.AsWaveAudio(), uint32(size)); err != nil {
+		return err
+	}
+	for _, seg := range c.segments {
+		if _, err := w.Write(seg.data); err != nil {
+			return err
+		}
+	}
+
+	// RIFF chunks are word aligned.
+	if size%2 == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// Flatten joins the segments into one AudioSegment, which copies all the data like Concat.
+func (c *CompositeSegment) Flatten() (*AudioSegment, error) {
+	return Concat(c.segments...)
+}
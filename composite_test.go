@@ -0,0 +1,59 @@
+package godub
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompositeSegment(t *testing.T) {
+	part1 := newSineSegment(440, 0.5, 300, 8000, 2)
+	part2 := newSineSegment(880, 0.5, 200, 8000, 2)
+
+	c, err := NewCompositeSegment(part1, part2)
+	assert.NoError(t, err)
+	c, err = c.Append(part1)
+	assert.NoError(t, err)
+
+	expected, _ := Concat(part1, part2, part1)
+	assert.Equal(t, expected.Duration(), c.Duration())
+	assert.Equal(t, expected.DurationTime(), c.DurationTime())
+	assert.Equal(t, expected.FrameCount(), c.FrameCount())
+	assert.Equal(t, expected.Len(), c.Len())
+	assert.Equal(t, uint16(2), c.Channels())
+	assert.Equal(t, uint32(8000), c.FrameRate())
+	assert.Len(t, c.Segments(), 3)
+
+	var buf bytes.Buffer
+	assert.NoError(t, c.ExportWav(&buf))
+	assert.Equal(t, encodeWav(t, expected), buf.Bytes())
+
+	flattened, err := c.Flatten()
+	assert.NoError(t, err)
+	assert.True(t, expected.Equal(flattened))
+
+	// The segments must be synced first
+	mono := newSineSegment(440, 0.5, 100, 8000, 1)
+	_, err = c.Append(mono)
+	assert.Error(t, err)
+	assert.Len(t, c.Segments(), 3)
+
+	_, err = NewCompositeSegment()
+	assert.Error(t, err)
+	_, err = NewCompositeSegment(part1, nil)
+	assert.Error(t, err)
+}
+
+func TestCompositeSegmentOddSize(t *testing.T) {
+	seg8, err := NewAudioSegment([]byte{0x80, 0x90, 0xA0}, SampleWidth(1), FrameRate(1000), Channels(1), FrameWidth(1))
+	assert.NoError(t, err)
+
+	c, err := NewCompositeSegment(seg8, seg8, seg8)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, c.ExportWav(&buf))
+	expected, _ := Concat(seg8, seg8, seg8)
+	assert.Equal(t, encodeWav(t, expected), buf.Bytes())
+}
@@ -1,6 +1,7 @@
 package converter
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -15,7 +16,8 @@ import (
 
 var (
 	DefaultCodecs = map[string]string{
-		"ogg": "libvorbis",
+		"ogg":  "libvorbis",
+		"opus": "libopus",
 	}
 	ValidCoverExtensions = utils.NewSet(".png", ".jpg", ".jpeg", ".bmp", ".tif", ".tiff")
 	ValidID3TagVersions  = utils.NewSet(3, 4)
@@ -40,17 +42,31 @@ const (
 	M4ABitRatePerfect  = 256 * 1000
 )
 
+const (
+	// VBR quality of libmp3lame, 0 is the best and 9 is the worst.
+	MP3VBRQualityBest    = 0
+	MP3VBRQualityGood    = 2
+	MP3VBRQualityDefault = 4
+	MP3VBRQualityWorst   = 9
+
+	// vbrQualityUnset marks that VBR is not used, since 0 is a valid quality.
+	vbrQualityUnset = -1
+)
+
 type Converter struct {
 	w             io.Writer
 	channels      int
 	dstFormat     string
 	bitRate       int
+	vbrQuality    int
 	codec         string
 	coverPath     string
 	tags          map[string]string
 	id3TagVersion int
 	sampleRate    int
 	params        []string
+	inputParams   []string
+	pipeOutput    bool
 	cmd           *exec.Cmd
 
 	// It's a temp file
@@ -63,8 +79,9 @@ func NewConverter(w io.Writer) *Converter {
 		dstFormat:     "mp3",
 		params:        make([]string, 0),
 		id3TagVersion: 4,
+		vbrQuality:    vbrQualityUnset,
 		cmd: exec.Command(
-			GetEncoderName(),
+			FFMPEGEncoder,
 			// Always overwrite existing files
 			"-y",
 		),
@@ -110,6 +127,13 @@ func (c *Converter) WithBitRate(rate int) *Converter {
 	return c
 }
 
+// WithVBRQuality enables variable bit rate encoding with the given quality (ffmpeg `-q:a`),
+// 0 is the best and 9 is the worst for libmp3lame. It can't be used together with WithBitRate.
+func (c *Converter) WithVBRQuality(q int) *Converter {
+	c.vbrQuality = q
+	return c
+}
+
 func (c *Converter) WithSampleRate(rate int) *Converter {
 	if rate == 0 {
 		return c
@@ -127,6 +151,26 @@ func (c *Converter) WithParams(p ...string) *Converter {
 	return c
 }
 
+// WithInputParams sets ffmpeg params describing the input, they're passed before `-i`.
+// It's required for raw/headerless input whose format can't be sniffed, e.g.
+// `WithInputParams("-f", "s16le", "-ar", "48000", "-ac", "2")`.
+func (c *Converter) WithInputParams(p ...string) *Converter {
+	if len(p) == 0 {
+		return c
+	}
+
+	c.inputParams = p
+	return c
+}
+
+// WithPipeOutput makes ffmpeg write the converted audio to its stdout, which is copied to
+// the writer directly instead of going through a temp file. The muxer of the dst format
+// must support non-seekable output, e.g. wav (with unset sizes in header) or mp3.
+func (c *Converter) WithPipeOutput(v bool) *Converter {
+	c.pipeOutput = v
+	return c
+}
+
 func (c *Converter) WithCodec(codec string) *Converter {
 	if codec == "" {
 		return c
@@ -191,22 +235,25 @@ func (c *Converter) Convert(src interface{}) error {
 }
 
 func (c *Converter) doConvert() error {
-	dstFile, err := tempfile.TempFile("", "dst", "."+c.dstFormat)
-	if err != nil {
-		return err
+	if !IsCommandAvailable(FFMPEGEncoder) {
+		return EncoderNotFoundError(fmt.Sprintf("command `%s` not found", FFMPEGEncoder))
 	}
-	defer os.Remove(dstFile.Name())
 
+	c.extendCmdArgs(c.inputParams...)
 	c.extendCmdArgs("-i", c.srcFilename)
 	c.extendCodecFormatArgs()
 	c.extendChannelArgs()
 
-	err = c.extendCoverArgs()
+	err := c.extendCoverArgs()
+	if err != nil {
+		return err
+	}
+
+	err = c.extendBitRateArgs()
 	if err != nil {
 		return err
 	}
 
-	c.extendBitRateArgs()
 	c.extendSampleRateArgs()
 
 	err = c.extendTagsArgs()
@@ -215,17 +262,41 @@ func (c *Converter) doConvert() error {
 	}
 
 	c.extendExtraArgs()
+
+	var stderr bytes.Buffer
+	c.cmd.Stderr = &stderr
+
+	if c.pipeOutput {
+		c.extendCmdArgs("-f", c.dstFormat, "pipe:1")
+		c.cmd.Stdout = c.w
+
+		err = c.cmd.Run()
+		if err != nil {
+			return newEncodeError(err, &stderr)
+		}
+		return nil
+	}
+
+	dstFile, err := tempfile.TempFile("", "dst", "."+c.dstFormat)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dstFile.Name())
+
 	c.extendCmdArgs(dstFile.Name())
 
 	err = c.cmd.Run()
 	if err != nil {
-		return EncodeError(fmt.Sprintf("encoding failed: %s", err))
+		return newEncodeError(err, &stderr)
 	}
 
 	// Copy to dst writer
 	buf, err := os.ReadFile(dstFile.Name())
-	c.w.Write(buf)
+	if err != nil {
+		return err
+	}
 
+	_, err = c.w.Write(buf)
 	return err
 }
 
@@ -261,10 +332,24 @@ func (c *Converter) extendCoverArgs() error {
 	return nil
 }
 
-func (c *Converter) extendBitRateArgs() {
+func (c *Converter) extendBitRateArgs() error {
+	if c.vbrQuality != vbrQualityUnset {
+		if c.bitRate != 0 {
+			return InvalidVBRQualityError("VBR quality and bit rate are mutually exclusive")
+		}
+
+		if c.vbrQuality < MP3VBRQualityBest || c.vbrQuality > MP3VBRQualityWorst {
+			return InvalidVBRQualityError(
+				fmt.Sprintf("VBR quality '%d' is not allowed, should be in [0, 9]", c.vbrQuality))
+		}
+
+		c.extendCmdArgs("-q:a", fmt.Sprintf("%d", c.vbrQuality))
+	}
+
 	if c.bitRate != 0 {
 		c.extendCmdArgs("-b:a", fmt.Sprintf("%d", c.bitRate))
 	}
+	return nil
 }
 
 func (c *Converter) extendSampleRateArgs() {
@@ -3,6 +3,10 @@ package converter
 import (
 	"bytes"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wonglyxng/godub/wav"
 )
 
 func TestConvert(t *testing.T) {
@@ -15,3 +19,110 @@ func TestConvert(t *testing.T) {
 		t.Log(err)
 	}
 }
+
+func TestExtendBitRateArgs(t *testing.T) {
+	c := NewConverter(nil).WithVBRQuality(MP3VBRQualityGood)
+	assert.NoError(t, c.extendBitRateArgs())
+	assert.Equal(t, []string{"-q:a", "2"}, c.cmd.Args[2:])
+
+	c = NewConverter(nil).WithVBRQuality(10)
+	assert.IsType(t, InvalidVBRQualityError(""), c.extendBitRateArgs())
+
+	c = NewConverter(nil).WithVBRQuality(MP3VBRQualityBest).WithBitRate(MP3BitRateGood)
+	assert.IsType(t, InvalidVBRQualityError(""), c.extendBitRateArgs())
+}
+
+func TestNewOpusVoiceConverter(t *testing.T) {
+	c, err := newOpusVoiceConverter(nil, OpusBitRateVoice)
+	assert.NoError(t, err)
+	assert.Equal(t, "opus", c.DstFormat())
+
+	c.extendCodecFormatArgs()
+	c.extendChannelArgs()
+	assert.NoError(t, c.extendBitRateArgs())
+	c.extendSampleRateArgs()
+	c.extendExtraArgs()
+	assert.Equal(t, []string{
+		"-acodec", "libopus", "-ac", "1", "-b:a", "24000", "-ar", "16000", "-application", "voip",
+	}, c.cmd.Args[2:])
+
+	for _, bitRate := range []int{0, OpusBitRateMin - 1, OpusBitRateMax + 1} {
+		_, err = newOpusVoiceConverter(nil, bitRate)
+		assert.IsType(t, InvalidBitRateError(""), err)
+		assert.IsType(t, InvalidBitRateError(""), ExportOpus("voice.wav", nil, bitRate))
+	}
+}
+
+func TestFormatFromMIME(t *testing.T) {
+	for mimeType, expected := range map[string]string{
+		"audio/mpeg":             "mp3",
+		"audio/wav":              "wav",
+		"Audio/X-WAV":            "wav",
+		"audio/ogg; codecs=opus": "ogg",
+		"audio/flac":             "flac",
+		"audio/mp4":              "m4a",
+	} {
+		f, ok := FormatFromMIME(mimeType)
+		assert.True(t, ok, mimeType)
+		assert.Equal(t, expected, f, mimeType)
+	}
+
+	_, ok := FormatFromMIME("video/mp4")
+	assert.False(t, ok)
+}
+
+func TestFormatFromExtension(t *testing.T) {
+	for ext, expected := range map[string]string{
+		".mp3": "mp3",
+		"WAV":  "wav",
+		".m4a": "m4a",
+		"flac": "flac",
+	} {
+		f, ok := FormatFromExtension(ext)
+		assert.True(t, ok, ext)
+		assert.Equal(t, expected, f, ext)
+	}
+
+	_, ok := FormatFromExtension(".txt")
+	assert.False(t, ok)
+}
+
+func TestProbeWave(t *testing.T) {
+	var buf bytes.Buffer
+	err := wav.Encode(&buf, &wav.WaveAudio{
+		Format:        wav.AudioFormatPCM,
+		Channels:      2,
+		SampleRate:    8000,
+		BitsPerSample: 16,
+		RawData:       make([]byte, 8000*4*3/2),
+	})
+	assert.NoError(t, err)
+
+	info, err := Probe(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, ProbeInfo{
+		Duration:   1500 * time.Millisecond,
+		SampleRate: 8000,
+		Channels:   2,
+		Codec:      "pcm_s16le",
+		BitRate:    8000 * 4 * 8,
+	}, info)
+}
+
+func TestParseFFprobeOutput(t *testing.T) {
+	info, err := parseFFprobeOutput([]byte(`{
+		"streams": [{"codec_name": "mp3", "sample_rate": "44100", "channels": 2, "bit_rate": "128000"}],
+		"format": {"duration": "12.500000", "bit_rate": "130000"}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, ProbeInfo{
+		Duration:   12500 * time.Millisecond,
+		SampleRate: 44100,
+		Channels:   2,
+		Codec:      "mp3",
+		BitRate:    128000,
+	}, info)
+
+	_, err = parseFFprobeOutput([]byte(`{"streams": []}`))
+	assert.IsType(t, ProbeError(""), err)
+}
@@ -1,11 +1,51 @@
 package converter
 
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
 type EncodeError string
 
 func (e EncodeError) Error() string {
 	return string(e)
 }
 
+// newEncodeError creates an EncodeError with the last line ffmpeg wrote to stderr,
+// which usually tells why the encoding failed.
+func newEncodeError(err error, stderr *bytes.Buffer) EncodeError {
+	if line := lastLine(stderr); line != "" {
+		return EncodeError(fmt.Sprintf("encoding failed: %s: %s", err, line))
+	}
+	return EncodeError(fmt.Sprintf("encoding failed: %s", err))
+}
+
+type ProbeError string
+
+func (e ProbeError) Error() string {
+	return string(e)
+}
+
+// newProbeError is like newEncodeError, but for ffprobe.
+func newProbeError(err error, stderr *bytes.Buffer) ProbeError {
+	if line := lastLine(stderr); line != "" {
+		return ProbeError(fmt.Sprintf("probe failed: %s: %s", err, line))
+	}
+	return ProbeError(fmt.Sprintf("probe failed: %s", err))
+}
+
+func lastLine(stderr *bytes.Buffer) string {
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	return lines[len(lines)-1]
+}
+
+type EncoderNotFoundError string
+
+func (e EncoderNotFoundError) Error() string {
+	return string(e)
+}
+
 type InvalidCoverError string
 
 func (e InvalidCoverError) Error() string {
@@ -17,3 +57,15 @@ type InvalidID3TagVersionError string
 func (e InvalidID3TagVersionError) Error() string {
 	return string(e)
 }
+
+type InvalidVBRQualityError string
+
+func (e InvalidVBRQualityError) Error() string {
+	return string(e)
+}
+
+type InvalidBitRateError string
+
+func (e InvalidBitRateError) Error() string {
+	return string(e)
+}
@@ -0,0 +1,68 @@
+package converter
+
+import (
+	"mime"
+	"strings"
+)
+
+var (
+	// MIMEFormats maps audio MIME types to formats, the formats are the same as
+	// the ones accepted by WithDstFormat.
+	MIMEFormats = map[string]string{
+		"audio/mpeg":     "mp3",
+		"audio/mp3":      "mp3",
+		"audio/wav":      "wav",
+		"audio/wave":     "wav",
+		"audio/x-wav":    "wav",
+		"audio/vnd.wave": "wav",
+		"audio/ogg":      "ogg",
+		"audio/vorbis":   "ogg",
+		"audio/opus":     "opus",
+		"audio/flac":     "flac",
+		"audio/x-flac":   "flac",
+		"audio/mp4":      "m4a",
+		"audio/x-m4a":    "m4a",
+		"audio/m4a":      "m4a",
+		"audio/aac":      "aac",
+		"audio/x-aac":    "aac",
+		"audio/aiff":     "aiff",
+		"audio/x-aiff":   "aiff",
+		"audio/webm":     "webm",
+	}
+
+	// ExtensionFormats maps file extensions (without the dot) to formats.
+	ExtensionFormats = map[string]string{
+		"mp3":  "mp3",
+		"wav":  "wav",
+		"wave": "wav",
+		"ogg":  "ogg",
+		"oga":  "ogg",
+		"opus": "opus",
+		"flac": "flac",
+		"m4a":  "m4a",
+		"mp4":  "m4a",
+		"aac":  "aac",
+		"aif":  "aiff",
+		"aiff": "aiff",
+		"webm": "webm",
+	}
+)
+
+// FormatFromMIME returns the format of an audio MIME type, e.g. the Content-Type
+// of an upload. Parameters such as `; codecs=opus` are ignored.
+func FormatFromMIME(mimeType string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.ToLower(mimeType))
+	}
+	f, ok := MIMEFormats[mediaType]
+	return f, ok
+}
+
+// FormatFromExtension returns the format of a file extension, with or without the
+// leading dot, e.g. ".MP3" or "mp3".
+func FormatFromExtension(ext string) (string, bool) {
+	ext = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(ext)), ".")
+	f, ok := ExtensionFormats[ext]
+	return f, ok
+}
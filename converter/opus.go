@@ -0,0 +1,45 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// Bit rates supported by libopus.
+	OpusBitRateMin = 6 * 1000
+	OpusBitRateMax = 510 * 1000
+
+	// OpusBitRateVoice is good enough for wideband speech.
+	OpusBitRateVoice = 24 * 1000
+
+	opusVoiceSampleRate = 16000
+)
+
+// ExportOpus transcodes the audio to Ogg/Opus with ffmpeg's libopus, using defaults for
+// voice (WebRTC, voice messages): mono, 16kHz and `-application voip`. `src` is an
+// `io.Reader` or a file path like Convert, bitRate is in bits per second and must be in
+// [OpusBitRateMin, OpusBitRateMax]. If ffmpeg is built without libopus, the returned
+// EncodeError tells so.
+func ExportOpus(src interface{}, w io.Writer, bitRate int) error {
+	c, err := newOpusVoiceConverter(w, bitRate)
+	if err != nil {
+		return err
+	}
+	return c.Convert(src)
+}
+
+func newOpusVoiceConverter(w io.Writer, bitRate int) (*Converter, error) {
+	if bitRate < OpusBitRateMin || bitRate > OpusBitRateMax {
+		return nil, InvalidBitRateError(fmt.Sprintf(
+			"opus bit rate '%d' is not allowed, should be in [%d, %d]", bitRate, OpusBitRateMin, OpusBitRateMax))
+	}
+
+	return NewConverter(w).
+		WithDstFormat("opus").
+		WithCodec("libopus").
+		WithChannels(1).
+		WithSampleRate(opusVoiceSampleRate).
+		WithBitRate(bitRate).
+		WithParams("-application", "voip"), nil
+}
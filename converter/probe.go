@@ -0,0 +1,172 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/wonglyxng/godub/wav"
+)
+
+const (
+	FFPROBECommand = "ffprobe"
+)
+
+// ProbeInfo is the metadata of audio, read without decoding the samples.
+type ProbeInfo struct {
+	Duration   time.Duration
+	SampleRate int
+	Channels   int
+	// Codec is the codec name of ffprobe, e.g. "mp3", "aac" or "pcm_s16le".
+	Codec string
+	// BitRate is in bits per second, it's 0 if unknown.
+	BitRate int
+}
+
+// Probe reads the metadata of audio, it's much faster than decoding when only the
+// duration or format is needed. `src` is an `io.Reader` or a file path like Convert.
+// WAV header is parsed directly, other formats are probed by ffprobe.
+func Probe(src interface{}) (ProbeInfo, error) {
+	var r io.Reader
+	switch src := src.(type) {
+	case io.Reader:
+		r = src
+	case string:
+		f, err := os.Open(src)
+		if err != nil {
+			return ProbeInfo{}, err
+		}
+		defer f.Close()
+		r = f
+	default:
+		return ProbeInfo{}, fmt.Errorf("probe error, expected `io.Reader` or file path to original audio")
+	}
+
+	// Keep what's read by the WAV parser, ffprobe gets the whole input if it fails.
+	var header bytes.Buffer
+	br := bufio.NewReader(r)
+	if info, ok := probeWave(io.TeeReader(br, &header)); ok {
+		return info, nil
+	}
+	return probeUsingFFprobe(io.MultiReader(&header, br))
+}
+
+// probeWave parses the WAV header, it fails if the data size is unset (streamed WAV).
+func probeWave(r io.Reader) (ProbeInfo, bool) {
+	audio, dataSize, err := wav.DecodeHeader(r)
+	if err != nil || dataSize == 0 || dataSize == 0xFFFFFFFF || audio.ByteRate() == 0 {
+		return ProbeInfo{}, false
+	}
+
+	byteRate := int64(audio.ByteRate())
+	return ProbeInfo{
+		Duration:   time.Duration(int64(dataSize) * int64(time.Second) / byteRate),
+		SampleRate: int(audio.SampleRate),
+		Channels:   int(audio.Channels),
+		Codec:      waveCodec(audio),
+		BitRate:    int(byteRate * 8),
+	}, true
+}
+
+// waveCodec returns the ffprobe codec name of WAV audio.
+func waveCodec(audio *wav.WaveAudio) string {
+	switch {
+	case audio.Format == wav.AudioFormatIEEEFloat:
+		return fmt.Sprintf("pcm_f%dle", audio.BitsPerSample)
+	case audio.BitsPerSample <= 8:
+		return "pcm_u8"
+	default:
+		return fmt.Sprintf("pcm_s%dle", (audio.BitsPerSample+7)/8*8)
+	}
+}
+
+// ffprobeOutput is the JSON output of `ffprobe -show_format -show_streams`.
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecName  string `json:"codec_name"`
+		SampleRate string `json:"sample_rate"`
+		Channels   int    `json:"channels"`
+		BitRate    string `json:"bit_rate"`
+		Duration   string `json:"duration"`
+	} `json:"streams"`
+	Format struct {
+		BitRate  string `json:"bit_rate"`
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+func probeUsingFFprobe(r io.Reader) (ProbeInfo, error) {
+	if !IsCommandAvailable(FFPROBECommand) {
+		return ProbeInfo{}, EncoderNotFoundError(fmt.Sprintf("command `%s` not found", FFPROBECommand))
+	}
+
+	// ffprobe reads a file rather than stdin, the duration of some formats can
+	// only be estimated on non-seekable input.
+	file, err := os.CreateTemp("", "probe-file")
+	if err != nil {
+		return ProbeInfo{}, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err = io.Copy(file, r); err != nil {
+		return ProbeInfo{}, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(
+		FFPROBECommand,
+		"-v", "error",
+		"-select_streams", "a:0",
+		"-show_format", "-show_streams",
+		"-print_format", "json",
+		file.Name(),
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		return ProbeInfo{}, newProbeError(err, &stderr)
+	}
+
+	return parseFFprobeOutput(stdout.Bytes())
+}
+
+func parseFFprobeOutput(output []byte) (ProbeInfo, error) {
+	var out ffprobeOutput
+	if err := json.Unmarshal(output, &out); err != nil {
+		return ProbeInfo{}, ProbeError(fmt.Sprintf("probe failed: %s", err))
+	}
+	if len(out.Streams) == 0 {
+		return ProbeInfo{}, ProbeError("probe failed: no audio stream found")
+	}
+
+	stream := out.Streams[0]
+	info := ProbeInfo{
+		Channels: stream.Channels,
+		Codec:    stream.CodecName,
+	}
+	info.SampleRate, _ = strconv.Atoi(stream.SampleRate)
+
+	// Some containers only tell the duration and bit rate of the whole file.
+	duration := stream.Duration
+	if duration == "" {
+		duration = out.Format.Duration
+	}
+	if seconds, err := strconv.ParseFloat(duration, 64); err == nil {
+		info.Duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	bitRate := stream.BitRate
+	if bitRate == "" {
+		bitRate = out.Format.BitRate
+	}
+	info.BitRate, _ = strconv.Atoi(bitRate)
+
+	return info, nil
+}
@@ -0,0 +1,159 @@
+package godub
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// CrossCorrelate 计算两个音频片段的互相关,找出把b对齐到a的时间偏移
+//
+// 参数:
+//   - a: 参考音频
+//   - b: 需要对齐的音频
+//   - maxLag: 搜索的最大偏移(毫秒),在[-maxLag, maxLag]内查找
+//
+// 返回:
+//   - lag: 偏移(毫秒),把b放在a的lag处时两者最吻合,负数表示b比a开始得早
+//   - score: 重叠部分的归一化相关系数,范围[-1, 1],1表示完全相同
+//   - err: 错误信息
+//
+// 说明:
+//   - 两个片段先转换为单声道,并同步到相同的采样率
+//   - 通过FFT分块计算[-maxLag, maxLag]内的互相关,取相关值最大的偏移,精度为一帧,
+//     FFT的大小由maxLag决定而不是音频时长,长录音也不会占用大量内存
+//   - 例如b = a.Slice(500, ...)时,lag为500,score为1
+//   - 可用于多机位录音的拍手对齐和重复片段检测
+func CrossCorrelate(a, b *AudioSegment, maxLag int64) (lag int64, score float64, err error) {
+	if a == nil || b == nil {
+		return 0, 0, NewAudioSegmentError("segments should not be nil")
+	}
+	if maxLag < 0 {
+		return 0, 0, NewAudioSegmentError("max lag should not be negative")
+	}
+
+	monoA, err := a.ForkWithChannels(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	monoB, err := b.ForkWithChannels(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	results, err := syncSegments(monoA, monoB)
+	if err != nil {
+		return 0, 0, err
+	}
+	x, err := monoFloat64(results[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	y, err := monoFloat64(results[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(x) == 0 || len(y) == 0 {
+		return 0, 0, NewAudioSegmentError("%w: segments should not be empty", ErrEmptyAudio)
+	}
+
+	frameRate := int64(results[0].frameRate)
+	maxLagFrames := int(maxLag * frameRate / 1000)
+	// Lags without any overlap are meaningless.
+	minK, maxK := max(-maxLagFrames, -(len(y)-1)), min(maxLagFrames, len(x)-1)
+
+	r := correlateLags(x, y, minK, maxK)
+	bestK, best := 0, math.Inf(-1)
+	for i, v := range r {
+		if v > best {
+			bestK, best = minK+i, v
+		}
+	}
+
+	// Normalize by the energy of the overlapping parts.
+	squares := func(v []float64) []float64 {
+		prefix := make([]float64, len(v)+1)
+		for i, s := range v {
+			prefix[i+1] = prefix[i] + s*s
+		}
+		return prefix
+	}
+	px, py := squares(x), squares(y)
+	start, end := max(0, -bestK), min(len(y), len(x)-bestK)
+	energy := (px[end+bestK] - px[start+bestK]) * (py[end] - py[start])
+	if energy > 0 {
+		score = math.Max(-1, math.Min(1, best/math.Sqrt(energy)))
+	}
+
+	lag = int64(math.Round(float64(bestK) * 1000 / float64(frameRate)))
+	return lag, score, nil
+}
+
+// correlationBlockSize is the minimum number of samples of y correlated at a time by
+// correlateLags, so the FFT size is bounded by maxLag instead of the length of the audio.
+const correlationBlockSize = 1 << 14
+
+// correlateLags returns r[k-minK] = Σ x[i+k]*y[i] for k in [minK, maxK]. y is correlated
+// block by block against the window of x each block can reach within the lags.
+func correlateLags(x, y []float64, minK, maxK int) []float64 {
+	lags := maxK - minK + 1
+	r := make([]float64, lags)
+	block := max(lags, correlationBlockSize)
+	for j := 0; j < len(y); j += block {
+		yb := y[j:min(j+block, len(y))]
+		lo, hi := max(j+minK, 0), min(j+len(yb)+maxK, len(x))
+		if lo >= hi {
+			continue
+		}
+
+		c := correlate(x[lo:hi], yb)
+		n := len(c)
+		for k := minK; k <= maxK; k++ {
+			// The offset of the lag k in the window of x.
+			m := j + k - lo
+			if m <= -len(yb) || m >= hi-lo {
+				continue
+			}
+			r[k-minK] += c[(m+n)%n]
+		}
+	}
+	return r
+}
+
+// correlate returns r[k] = Σ x[i+k]*y[i] by FFT, negative lags wrap around to the end.
+func correlate(x, y []float64) []float64 {
+	n := 1
+	for n < len(x)+len(y)-1 {
+		n <<= 1
+	}
+
+	fx := make([]complex128, n)
+	fy := make([]complex128, n)
+	for i, v := range x {
+		fx[i] = complex(v, 0)
+	}
+	for i, v := range y {
+		fy[i] = complex(v, 0)
+	}
+	fft(fx)
+	fft(fy)
+
+	// The inverse FFT of X·conj(Y), by conj(fft(conj(z))) / n.
+	for i := range fx {
+		fx[i] = cmplx.Conj(fx[i] * cmplx.Conj(fy[i]))
+	}
+	fft(fx)
+
+	r := make([]float64, n)
+	for i, v := range fx {
+		r[i] = real(v) / float64(n)
+	}
+	return r
+}
+
+// monoFloat64 returns the samples of a mono segment in [-1, 1].
+func monoFloat64(seg *AudioSegment) ([]float64, error) {
+	channels, err := seg.ToFloat64()
+	if err != nil || len(channels) == 0 {
+		return nil, err
+	}
+	return channels[0], nil
+}
@@ -0,0 +1,75 @@
+package godub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrossCorrelate(t *testing.T) {
+	// A chirp-like signal that doesn't repeat.
+	var parts []*AudioSegment
+	for _, freq := range []float64{300, 500, 700, 1100, 1300, 1700} {
+		parts = append(parts, newSineSegment(freq, 0.5, 100, 8000, 1))
+	}
+	a, err := Concat(parts...)
+	require.NoError(t, err)
+
+	b, err := a.Slice(250, 450)
+	require.NoError(t, err)
+	lag, score, err := CrossCorrelate(a, b, 500)
+	require.NoError(t, err)
+	assert.Equal(t, int64(250), lag)
+	assert.InDelta(t, 1, score, 1e-6)
+
+	// b starts before a, in stereo and another frame rate
+	a2, _ := a.Slice(100, 600)
+	b2, _ := a.ForkWithChannels(2)
+	b2, _ = b2.ForkWithFrameRate(16000)
+	lag, score, err = CrossCorrelate(a2, b2, 300)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-100), lag)
+	assert.Greater(t, score, 0.9)
+
+	// The true lag is beyond maxLag, the search is limited
+	lag, _, err = CrossCorrelate(a, b, 100)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, lag, int64(100))
+
+	_, _, err = CrossCorrelate(a, b, -1)
+	assert.Error(t, err)
+	empty, _ := NewEmptyAudioSegment()
+	_, _, err = CrossCorrelate(a, empty, 100)
+	assert.Error(t, err)
+}
+
+func TestCorrelateLags(t *testing.T) {
+	// Long enough for several blocks
+	x := make([]float64, 3*correlationBlockSize+123)
+	y := make([]float64, 2*correlationBlockSize+45)
+	seed := uint32(1)
+	next := func() float64 {
+		seed = seed*1664525 + 1013904223
+		return float64(int32(seed)) / (1 << 31)
+	}
+	for i := range x {
+		x[i] = next()
+	}
+	for i := range y {
+		y[i] = next()
+	}
+
+	minK, maxK := -40, 60
+	r := correlateLags(x, y, minK, maxK)
+	require.Len(t, r, maxK-minK+1)
+	for k := minK; k <= maxK; k++ {
+		var expected float64
+		for i := range y {
+			if i+k >= 0 && i+k < len(x) {
+				expected += x[i+k] * y[i]
+			}
+		}
+		assert.InDelta(t, expected, r[k-minK], 1e-6)
+	}
+}
@@ -0,0 +1,264 @@
+package godub
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// ClipCount 返回达到或超过满刻度的采样数
+//
+// 说明:
+//   - 满刻度按采样宽度计算,例如16位音频中|采样| >= 32767即视为削波
+//   - 8位音频会先去掉无符号偏移
+//   - 用于在导出前检查Overlay/ApplyGain等处理是否引入了削波
+func (seg *AudioSegment) ClipCount() int {
+	samples, err := seg.signedSamples()
+	if err != nil {
+		return 0
+	}
+
+	limit := int64(seg.MaxPossibleAmplitude()) - 1
+	count := 0
+	for _, sample := range samples {
+		if v := int64(sample); v >= limit || -v >= limit {
+			count++
+		}
+	}
+	return count
+}
+
+// IsClipping 判断音频中是否存在削波的采样,即ClipCount() > 0
+func (seg *AudioSegment) IsClipping() bool {
+	return seg.ClipCount() > 0
+}
+
+// AmplitudeHistogram 统计采样幅度的分布
+//
+// 参数:
+//   - bins: 区间数,把[0, 满刻度]的幅度等分为bins个区间
+//
+// 说明:
+//   - 返回每个区间内的采样数,所有声道的采样都参与统计
+//   - 幅度取绝对值,8位音频会先去掉无符号偏移,满刻度(负向最大值)计入最后一个区间
+//   - 只遍历一次数据,不解码出完整的采样数组
+//   - 过度压缩或砖墙限幅的音频,采样会集中在最后几个区间,可以与ClipCount配合使用
+func (seg *AudioSegment) AmplitudeHistogram(bins int) ([]int, error) {
+	if bins <= 0 {
+		return nil, NewAudioSegmentError("bins should be positive, got %d", bins)
+	}
+
+	width := int(seg.sampleWidth)
+	if width != 1 && width != 2 && width != 4 {
+		return nil, NewAudioSegmentError("unsupported sample width %d", width)
+	}
+
+	fullScale := int64(seg.MaxPossibleAmplitude())
+	histogram := make([]int, bins)
+	for i := 0; i+width <= len(seg.data); i += width {
+		sample, _ := seg.signedSample(seg.data, i/width)
+		v := int64(sample)
+		if v < 0 {
+			v = -v
+		}
+		histogram[min(int(v*int64(bins)/fullScale), bins-1)]++
+	}
+	return histogram, nil
+}
+
+// MovingRMS 计算重叠窗口的RMS,类似VU表的读数
+//
+// 参数:
+//   - windowLen: 窗口长度(毫秒)
+//   - hop: 相邻窗口起点的间隔(毫秒),小于windowLen时窗口相互重叠
+//
+// 返回:
+//   - 每个窗口的RMS,刻度与RMS相同,第i个值对应从i*hop毫秒开始的窗口
+//
+// 说明:
+//   - 直接在原始数据上计算每个窗口,不需要先截取片段
+//   - 与不重叠的分块相比,重叠窗口的读数变化更平滑,适合电平显示和门限器的侧链检测
+//   - 只返回完整的窗口,音频比一个窗口短时返回整个音频的RMS
+//   - windowLen或hop不是正数时返回nil
+func (seg *AudioSegment) MovingRMS(windowLen, hop int64) []float64 {
+	if windowLen <= 0 || hop <= 0 {
+		return nil
+	}
+
+	frameRate := int64(seg.frameRate)
+	windowFrames := max64(windowLen*frameRate/1000, 1)
+	hopFrames := max64(hop*frameRate/1000, 1)
+	frames := int64(seg.FrameCount())
+	if frames == 0 {
+		return nil
+	}
+	if frames <= windowFrames {
+		return []float64{seg.RMS()}
+	}
+
+	values := make([]float64, 0, (frames-windowFrames)/hopFrames+1)
+	for start := int64(0); start+windowFrames <= frames; start += hopFrames {
+		values = append(values, calculateRMSForFrames(seg, start, start+windowFrames))
+	}
+	return values
+}
+
+// Limit 砖墙限幅器,把采样幅度限制在ceiling(dBFS)以内
+//
+// 参数:
+//   - ceiling: 上限,例如-0.1表示-0.1 dBFS
+//
+// 说明:
+//   - 超过上限的采样被截断到上限,而不是回绕,没有压缩比和起音/释放时间
+//   - 每个声道的每个采样独立处理,采样宽度保持不变
+//   - 没有采样超过上限时原样返回
+//   - 适合作为混音之后的安全环节
+func (seg *AudioSegment) Limit(ceiling Volume) (*AudioSegment, error) {
+	if math.IsNaN(float64(ceiling)) {
+		return nil, NewAudioSegmentError("invalid ceiling %v", ceiling)
+	}
+
+	samples, err := seg.signedSamples()
+	if err != nil {
+		return nil, err
+	}
+
+	maxAmplitude := seg.MaxPossibleAmplitude()
+	threshold := math.Min(math.Floor(ceiling.ToRatio(true)*maxAmplitude), maxAmplitude-1)
+	limit := int32(threshold)
+
+	limited := false
+	for i, sample := range samples {
+		if sample > limit {
+			samples[i] = limit
+			limited = true
+		} else if sample < -limit {
+			samples[i] = -limit
+			limited = true
+		}
+	}
+
+	if !limited {
+		return seg, nil
+	}
+
+	return seg.derive(seg.packSignedSamples(samples))
+}
+
+// noiseGateDetectorRelease is the release time (ms) of the level detector of NoiseGate,
+// it keeps the detected level up between the peaks of low frequency waveforms.
+const noiseGateDetectorRelease = 20
+
+// NoiseGate 噪声门,衰减低于阈值的部分(如说话间隙的底噪、串音),并平滑过渡而不是硬切
+//
+// 参数:
+//   - threshold: 阈值(dBFS),电平低于该值时关闭噪声门
+//   - attack: 打开噪声门的过渡时间(毫秒)
+//   - hold: 电平低于阈值后保持打开的时间(毫秒),避免在词语之间频繁开关
+//   - release: 关闭噪声门的过渡时间(毫秒)
+//
+// 说明:
+//   - 电平由包络跟随器检测(瞬时起音,20ms释放),增益在0和1之间按attack/release线性变化
+//   - 每个声道独立处理,采样宽度、采样率和时长保持不变
+//   - 与SplitOnSilence不同,不会切分音频,只是把静音部分变得更安静
+func (seg *AudioSegment) NoiseGate(threshold Volume, attack, hold, release int64) (*AudioSegment, error) {
+	if math.IsNaN(float64(threshold)) {
+		return nil, NewAudioSegmentError("invalid threshold %v", threshold)
+	}
+	if attack < 0 || hold < 0 || release < 0 {
+		return nil, NewAudioSegmentError("attack, hold and release should not be negative")
+	}
+
+	channels, err := seg.ToFloat64()
+	if err != nil {
+		return nil, err
+	}
+
+	frameRate := int64(seg.frameRate)
+	thresh := threshold.ToRatio(true)
+	holdFrames := int(hold * frameRate / 1000)
+	// The gain changes by these steps every frame, a step of 1 is instant.
+	attackStep := 1 / math.Max(float64(attack*frameRate)/1000, 1)
+	releaseStep := 1 / math.Max(float64(release*frameRate)/1000, 1)
+
+	for _, samples := range channels {
+		detector := newEnvelopeFollower(0, noiseGateDetectorRelease, float64(frameRate))
+		gain := 0.0
+		holding := 0
+		for i, v := range samples {
+			open := detector.process(math.Abs(v)) >= thresh
+			if open {
+				holding = holdFrames
+			} else if holding > 0 {
+				holding--
+				open = true
+			}
+
+			if open {
+				gain = math.Min(gain+attackStep, 1)
+			} else {
+				gain = math.Max(gain-releaseStep, 0)
+			}
+			samples[i] = v * gain
+		}
+	}
+
+	return seg.deriveFromFloat64(channels)
+}
+
+// envelopeFollower tracks the level of a rectified signal with separate attack and
+// release time constants, a time of 0 follows the signal instantly.
+type envelopeFollower struct {
+	attackCoef  float64
+	releaseCoef float64
+	envelope    float64
+}
+
+// newEnvelopeFollower creates an envelopeFollower, attack and release are in milliseconds.
+func newEnvelopeFollower(attack, release, frameRate float64) *envelopeFollower {
+	coef := func(ms float64) float64 {
+		if ms <= 0 {
+			return 0
+		}
+		return math.Exp(-1000 / (ms * frameRate))
+	}
+	return &envelopeFollower{attackCoef: coef(attack), releaseCoef: coef(release)}
+}
+
+func (f *envelopeFollower) process(level float64) float64 {
+	coef := f.releaseCoef
+	if level > f.envelope {
+		coef = f.attackCoef
+	}
+	f.envelope = coef*f.envelope + (1-coef)*level
+	return f.envelope
+}
+
+// signedSamples decodes the samples of all channels, 8-bit audio is biased to signed.
+func (seg *AudioSegment) signedSamples() ([]int32, error) {
+	samples := make([]int32, len(seg.data)/int(seg.sampleWidth))
+	for i := range samples {
+		sample, err := seg.signedSample(seg.data, i)
+		if err != nil {
+			return nil, err
+		}
+		samples[i] = sample
+	}
+	return samples, nil
+}
+
+// packSignedSamples is the reverse of signedSamples, the samples must be in range.
+func (seg *AudioSegment) packSignedSamples(samples []int32) []byte {
+	width := int(seg.sampleWidth)
+	data := make([]byte, len(samples)*width)
+	for i, sample := range samples {
+		switch width {
+		case 1:
+			data[i] = byte(sample + 128)
+		case 2:
+			binary.LittleEndian.PutUint16(data[i*2:], uint16(sample))
+		case 4:
+			binary.LittleEndian.PutUint32(data[i*4:], uint32(sample))
+		}
+	}
+	return data
+}
@@ -0,0 +1,133 @@
+package godub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClipCount(t *testing.T) {
+	seg := newSegment16(0, 32767, -32768, -32767, 32766, 1000)
+	assert.Equal(t, 3, seg.ClipCount())
+	assert.True(t, seg.IsClipping())
+
+	assert.False(t, newSegment16(0, 32766, -32766).IsClipping())
+
+	seg = newSegment32(2147483647, 0, -2147483648)
+	assert.Equal(t, 2, seg.ClipCount())
+
+	// 8-bit audio is unsigned, 0x00 and 0xff are full scale
+	seg, _ = NewAudioSegment([]byte{0x00, 0x80, 0xff, 0xfe}, SampleWidth(1), FrameRate(8000), Channels(1), FrameWidth(1))
+	assert.Equal(t, 2, seg.ClipCount())
+
+	// Overlay introduces clipping
+	loud := newSineSegment(440, 0.9, 100, 8000, 1)
+	assert.False(t, loud.IsClipping())
+	overlaid, _ := loud.Overlay(loud, &OverlayConfig{})
+	assert.True(t, overlaid.IsClipping())
+}
+
+func TestAmplitudeHistogram(t *testing.T) {
+	seg := newSegment16(0, 100, -100, 16383, -16384, 32767, -32768)
+	histogram, err := seg.AmplitudeHistogram(4)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 1, 1, 2}, histogram)
+
+	// 8-bit audio is unsigned
+	seg, _ = NewAudioSegment([]byte{0x00, 0x80, 0xff, 0x7f}, SampleWidth(1), FrameRate(8000), Channels(1), FrameWidth(1))
+	histogram, err = seg.AmplitudeHistogram(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 2}, histogram)
+
+	// Limiting piles the samples up in the top bins
+	loud := newSineSegment(440, 0.9, 100, 8000, 1)
+	limited, _ := loud.ApplyGain(12)
+	limited, _ = limited.Limit(-1)
+	before, _ := loud.AmplitudeHistogram(10)
+	after, _ := limited.AmplitudeHistogram(10)
+	assert.Greater(t, after[8], before[8]+before[9])
+
+	_, err = seg.AmplitudeHistogram(0)
+	assert.Error(t, err)
+}
+
+func TestMovingRMS(t *testing.T) {
+	quiet := newSineSegment(440, 0.1, 100, 8000, 1)
+	loud := newSineSegment(440, 0.8, 100, 8000, 1)
+	seg, _ := quiet.Append(loud)
+
+	// 200ms in 50ms windows stepped by 25ms
+	values := seg.MovingRMS(50, 25)
+	assert.Len(t, values, 7)
+	assert.InDelta(t, quiet.RMS(), values[0], quiet.RMS()*0.05)
+	assert.InDelta(t, loud.RMS(), values[6], loud.RMS()*0.05)
+	// Windows overlapping both halves read in between
+	assert.Greater(t, values[3], values[0])
+	assert.Less(t, values[3], values[6])
+
+	window, _ := seg.Slice(25, 75)
+	assert.Equal(t, window.RMS(), values[1])
+
+	// Shorter than a window
+	assert.Equal(t, []float64{quiet.RMS()}, quiet.MovingRMS(500, 10))
+	assert.Nil(t, seg.MovingRMS(0, 10))
+	assert.Nil(t, seg.MovingRMS(10, 0))
+}
+
+func TestLimit(t *testing.T) {
+	// -6.0206dB is half of full scale
+	seg := newSegment16(0, 20000, -20000, 16000, -32768)
+	limited, err := seg.Limit(-6.0206)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(0, 16383, -16383, 16000, -16383).RawData(), limited.RawData())
+	assert.Equal(t, seg.SampleWidth(), limited.SampleWidth())
+	assert.False(t, limited.IsClipping())
+
+	// Nothing exceeds the ceiling
+	quiet := newSegment16(0, 100, -100)
+	limited, err = quiet.Limit(-0.1)
+	assert.NoError(t, err)
+	assert.Same(t, quiet, limited)
+
+	// 8-bit audio keeps the unsigned bias
+	seg, _ = NewAudioSegment([]byte{0x00, 0x80, 0xff}, SampleWidth(1), FrameRate(8000), Channels(1), FrameWidth(1))
+	limited, err = seg.Limit(-6.0206)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x80 - 63, 0x80, 0x80 + 63}, limited.RawData())
+}
+
+func TestNoiseGate(t *testing.T) {
+	speech := newSineSegment(300, 0.5, 300, 8000, 1)
+	noise := newSineSegment(300, 0.001, 300, 8000, 1)
+	seg, _ := Concat(speech, noise, speech)
+
+	gated, err := seg.NoiseGate(-40, 5, 50, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, seg.FrameCount(), gated.FrameCount())
+	assert.Equal(t, seg.SampleWidth(), gated.SampleWidth())
+
+	// The noise is removed once the detected level, hold and release are over, the speech is kept.
+	gap, _ := gated.Slice(500, 600)
+	assert.Equal(t, 0.0, gap.RMS())
+	kept, _ := gated.Slice(100, 300)
+	original, _ := seg.Slice(100, 300)
+	assert.InDelta(t, original.RMS(), kept.RMS(), 1)
+
+	// It's faded out during the release instead of cut.
+	fading, _ := gated.Slice(440, 470)
+	assert.Greater(t, fading.RMS(), 0.0)
+	assert.Less(t, fading.RMS(), speech.RMS())
+
+	// Every channel is gated on its own
+	noiseBed, _ := noise.TileTo(seg.Duration())
+	stereo, _ := NewAudioSegment(interleave([]*AudioSegment{seg, noiseBed}),
+		SampleWidth(2), FrameRate(8000), Channels(2), FrameWidth(4))
+	gated, err = stereo.NoiseGate(-40, 5, 50, 50)
+	assert.NoError(t, err)
+	channels, _ := gated.SplitToMono()
+	assert.Equal(t, 0.0, channels[1].RMS())
+	assert.Greater(t, channels[0].RMS(), 0.0)
+
+	_, err = seg.NoiseGate(-40, -1, 0, 0)
+	assert.Error(t, err)
+}
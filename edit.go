@@ -0,0 +1,579 @@
+package godub
+
+import (
+	"math"
+
+	"github.com/wonglyxng/godub/audioop"
+	"github.com/wonglyxng/godub/utils"
+)
+
+// PadPosition decides where PadTo puts the silence.
+type PadPosition int
+
+const (
+	// PadEnd appends silence to the end
+	PadEnd PadPosition = iota
+	// PadStart prepends silence to the start
+	PadStart
+	// PadCenter splits silence between both ends, the end gets the extra frame if any
+	PadCenter
+)
+
+// PadTo 用静音将音频片段补齐到指定时长
+//
+// 参数:
+//   - duration: 目标时长(毫秒)
+//   - where: 静音的位置,末尾(PadEnd)、开头(PadStart)或两端(PadCenter)
+//   - strict: 音频已经比目标时长更长时,为true则返回错误,否则原样返回
+//
+// 说明:
+//   - 静音与当前音频格式一致,不需要额外同步
+//   - 按帧补齐,结果帧数为 duration * 帧率 / 1000
+func (seg *AudioSegment) PadTo(duration int64, where PadPosition, strict bool) (*AudioSegment, error) {
+	if duration < 0 {
+		return nil, NewAudioSegmentError("duration should be positive")
+	}
+
+	targetFrames := int(duration * int64(seg.frameRate) / 1000)
+	missingFrames := targetFrames - int(seg.FrameCount())
+	if missingFrames < 0 {
+		if strict {
+			return nil, NewAudioSegmentError(
+				"segment is already longer (%dms) than %dms", seg.Duration(), duration)
+		}
+		return seg, nil
+	}
+
+	if missingFrames == 0 {
+		return seg, nil
+	}
+
+	var data []byte
+	switch where {
+	case PadEnd:
+		data = utils.ConcatenateByteSlice(seg.data, seg.silentData(missingFrames))
+	case PadStart:
+		data = utils.ConcatenateByteSlice(seg.silentData(missingFrames), seg.data)
+	case PadCenter:
+		left := missingFrames / 2
+		data = utils.ConcatenateByteSlice(seg.silentData(left), seg.data, seg.silentData(missingFrames-left))
+	default:
+		return nil, NewAudioSegmentError("invalid pad position %d", where)
+	}
+
+	return seg.derive(data)
+}
+
+// Truncate 将音频片段截断到最多maxDuration毫秒
+//
+// 说明:
+//   - 音频已经不超过maxDuration时原样返回
+//   - maxDuration不能为负数
+func (seg *AudioSegment) Truncate(maxDuration int64) (*AudioSegment, error) {
+	if maxDuration < 0 {
+		return nil, NewAudioSegmentError("max duration should be positive")
+	}
+
+	maxFrames := int(maxDuration * int64(seg.frameRate) / 1000)
+	if maxFrames >= int(seg.FrameCount()) {
+		return seg, nil
+	}
+
+	return seg.SliceFrames(0, maxFrames)
+}
+
+// TileTo 循环重复音频片段,并截断到正好duration毫秒,例如用12秒的循环填满90秒的背景音
+//
+// 说明:
+//   - 与Repeat只能重复整数次不同,最后一次重复会在中间被截断
+//   - 结果帧数为 duration * 帧率 / 1000
+//   - duration必须大于0,音频片段不能为空
+//   - 循环点是硬切,需要交叉淡化时使用TileToWithCrossfade
+func (seg *AudioSegment) TileTo(duration int64) (*AudioSegment, error) {
+	if duration <= 0 {
+		return nil, NewAudioSegmentError("duration should be greater than 0")
+	}
+
+	if len(seg.data) == 0 {
+		return nil, NewAudioSegmentError("%w: can't tile an empty segment", ErrEmptyAudio)
+	}
+
+	size := int(duration*int64(seg.frameRate)/1000) * int(seg.frameWidth)
+	data := make([]byte, size)
+	for pos := 0; pos < size; pos += len(seg.data) {
+		copy(data[pos:], seg.data)
+	}
+
+	return seg.derive(data)
+}
+
+// TileToWithCrossfade 与TileTo相同,但每次重复之间以crossfade毫秒的交叉淡化衔接
+//
+// 说明:
+//   - 循环点的硬切在大多数环境音上会产生咔哒声,交叉淡化可以掩盖它
+//   - 上一次重复的最后crossfade毫秒与下一次重复的最前crossfade毫秒以FadeEqualPower叠加,
+//     接合部分只计算一次,每次重复因此只贡献 音频长度 - crossfade 毫秒
+//   - crossfade为0(或不足一帧)时与TileTo相同,不能超过音频长度的一半
+func (seg *AudioSegment) TileToWithCrossfade(duration, crossfade int64) (*AudioSegment, error) {
+	if crossfade < 0 {
+		return nil, NewAudioSegmentError("crossfade duration should not be negative")
+	}
+
+	frameCount := int(seg.FrameCount())
+	fadeFrames := int(crossfade * int64(seg.frameRate) / 1000)
+	if fadeFrames == 0 || len(seg.data) == 0 {
+		return seg.TileTo(duration)
+	}
+
+	if duration <= 0 {
+		return nil, NewAudioSegmentError("duration should be greater than 0")
+	}
+
+	if 2*fadeFrames > frameCount {
+		return nil, NewAudioSegmentError(
+			"crossfade duration %dms is longer than half of the segment (%dms)", crossfade, seg.Duration())
+	}
+
+	tail, err := seg.SliceFrames(frameCount-fadeFrames, frameCount)
+	if err != nil {
+		return nil, err
+	}
+	head, err := seg.SliceFrames(0, fadeFrames)
+	if err != nil {
+		return nil, err
+	}
+	joint, err := Crossfade(tail, head, crossfade)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first repeat is followed by the joint and the middle of every other repeat.
+	frameWidth := int(seg.frameWidth)
+	first := seg.data[:(frameCount-fadeFrames)*frameWidth]
+	loop := utils.ConcatenateByteSlice(joint.data, seg.data[fadeFrames*frameWidth:(frameCount-fadeFrames)*frameWidth])
+
+	size := int(duration*int64(seg.frameRate)/1000) * frameWidth
+	data := make([]byte, size)
+	pos := copy(data, first)
+	for pos < size {
+		pos += copy(data[pos:], loop)
+	}
+
+	return seg.derive(data)
+}
+
+// Chunk 将音频片段切分为连续的固定长度片段(即pydub的make_chunks)
+//
+// 参数:
+//   - chunkLen: 每个片段的长度(毫秒),必须大于0
+//   - overlap: 相邻片段的重叠长度(毫秒),用于滑动窗口,必须小于chunkLen
+//
+// 说明:
+//   - 直接按帧切分原始数据,而不是重复调用Slice
+//   - 最后一个片段可能比chunkLen短
+func (seg *AudioSegment) Chunk(chunkLen int64, overlap int64) ([]*AudioSegment, error) {
+	if chunkLen <= 0 {
+		return nil, NewAudioSegmentError("chunk length should be greater than 0")
+	}
+
+	if overlap < 0 || overlap >= chunkLen {
+		return nil, NewAudioSegmentError("overlap should be in [0, chunk length)")
+	}
+
+	chunkFrames := int(chunkLen * int64(seg.frameRate) / 1000)
+	stepFrames := chunkFrames - int(overlap*int64(seg.frameRate)/1000)
+	if chunkFrames == 0 || stepFrames <= 0 {
+		return nil, NewAudioSegmentError("chunk length is too short for frame rate %d", seg.frameRate)
+	}
+
+	frameCount := int(seg.FrameCount())
+	frameWidth := int(seg.frameWidth)
+
+	var chunks []*AudioSegment
+	for start := 0; start < frameCount; start += stepFrames {
+		end := start + chunkFrames
+		if end > frameCount {
+			end = frameCount
+		}
+
+		chunk, err := seg.derive(seg.data[start*frameWidth : end*frameWidth])
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+
+		if end == frameCount {
+			break
+		}
+	}
+
+	return chunks, nil
+}
+
+// GainRamp 在[start, end)范围内将增益从from线性过渡到to(自动化音量,用于闪避/渐强)
+//
+// 参数:
+//   - from: 起始增益(dB)
+//   - to: 结束增益(dB)
+//   - start: 起始时间(毫秒)
+//   - end: 结束时间(毫秒),超过音频长度时截断到末尾
+//
+// 说明:
+//   - 增益按dB插值,每1ms为一块调用audioop.Mul,与淡入淡出的做法相同
+//   - 范围之外的音频保持不变
+func (seg *AudioSegment) GainRamp(from, to Volume, start, end int64) (*AudioSegment, error) {
+	if start < 0 || end < 0 {
+		return nil, NewAudioSegmentError("%w: start or end should be positive", ErrInvalidRange)
+	}
+
+	if start > end {
+		return nil, NewAudioSegmentError("%w: start should be smaller than end", ErrInvalidRange)
+	}
+
+	frameCount := int(seg.FrameCount())
+	startFrame := min(int(start*int64(seg.frameRate)/1000), frameCount)
+	endFrame := min(int(end*int64(seg.frameRate)/1000), frameCount)
+
+	data := make([]byte, len(seg.data))
+	copy(data, seg.data)
+	if err := seg.rampGain(data, from, to, startFrame, endFrame); err != nil {
+		return nil, err
+	}
+
+	return seg.derive(data)
+}
+
+// rampGain ramps the gain of frames [startFrame, endFrame) of data in place, 1ms at a time.
+// The first chunk gets `from` and the last one gets `to`.
+func (seg *AudioSegment) rampGain(data []byte, from, to Volume, startFrame, endFrame int) error {
+	return seg.applyChunkGain(data, startFrame, endFrame, func(t float64) float64 {
+		return (from + (to-from)*Volume(t)).ToRatio(true)
+	})
+}
+
+// applyChunkGain multiplies frames [startFrame, endFrame) of data in place by ratio(t), 1ms at
+// a time. t is the position of the chunk in [0, 1], the first chunk gets 0 and the last one 1.
+func (seg *AudioSegment) applyChunkGain(data []byte, startFrame, endFrame int, ratio func(t float64) float64) error {
+	if startFrame >= endFrame {
+		return nil
+	}
+
+	frameWidth := int(seg.frameWidth)
+	chunkFrames := max(1, int(seg.frameRate/1000))
+	numChunks := (endFrame - startFrame + chunkFrames - 1) / chunkFrames
+
+	for i := 0; i < numChunks; i++ {
+		t := 0.0
+		if numChunks > 1 {
+			t = float64(i) / float64(numChunks-1)
+		}
+
+		chunkStart := startFrame + i*chunkFrames
+		chunkEnd := min(chunkStart+chunkFrames, endFrame)
+		chunk := data[chunkStart*frameWidth : chunkEnd*frameWidth]
+		if seg.sampleWidth == 1 {
+			flipSignBit(chunk)
+		}
+		if err := audioop.MulInto(chunk, chunk, int(seg.sampleWidth), ratio(t)); err != nil {
+			return err
+		}
+		if seg.sampleWidth == 1 {
+			flipSignBit(chunk)
+		}
+	}
+
+	return nil
+}
+
+// Crossfade 把a的最后duration毫秒与b的最前duration毫秒叠加,做等功率的交叉淡化
+//
+// 参数:
+//   - a: 前一个音频片段,在重叠部分淡出
+//   - b: 后一个音频片段,在重叠部分淡入
+//   - duration: 重叠的时长(毫秒),不能超过a和b的长度
+//
+// 返回:
+//   - *AudioSegment: 拼接后的音频,长度为a和b之和减去duration
+//
+// 说明:
+//   - 即CrossfadeWithCurve(a, b, duration, FadeEqualPower)
+//   - 淡出增益为cos(t·π/2),淡入增益为sin(t·π/2),两者的平方和恒为1,
+//     不相关的两段音频在重叠部分的响度保持不变,不会像线性淡化那样在中间凹陷
+//   - 增益每1ms计算一次,与GainRamp相同;叠加时超出满刻度的采样被截断
+//   - a和b先同步为相同的格式,duration为0时与Concat相同
+//   - 可用于DJ式的过渡
+func Crossfade(a, b *AudioSegment, duration int64) (*AudioSegment, error) {
+	return CrossfadeWithCurve(a, b, duration, FadeEqualPower)
+}
+
+// CrossfadeWithCurve 与Crossfade相同,但可以选择淡化曲线
+//
+// 说明:
+//   - b按curve淡入,a按curve的镜像淡出,参见FadeCurve
+//   - 交叉淡化通常应该使用FadeEqualPower,其他曲线在重叠部分的中间响度会下降
+func CrossfadeWithCurve(a, b *AudioSegment, duration int64, curve FadeCurve) (*AudioSegment, error) {
+	if a == nil || b == nil {
+		return nil, NewAudioSegmentError("segments should not be nil")
+	}
+
+	if err := curve.validate(); err != nil {
+		return nil, err
+	}
+
+	if duration < 0 {
+		return nil, NewAudioSegmentError("crossfade duration should not be negative")
+	}
+
+	if duration > a.Duration() || duration > b.Duration() {
+		return nil, NewAudioSegmentError(
+			"crossfade duration %dms is longer than the segments (%dms and %dms)",
+			duration, a.Duration(), b.Duration())
+	}
+
+	if duration == 0 {
+		return Concat(a, b)
+	}
+
+	results, err := syncSegments(a, b)
+	if err != nil {
+		return nil, err
+	}
+	a, b = results[0], results[1]
+
+	frameWidth := int(a.frameWidth)
+	aFrames, bFrames := int(a.FrameCount()), int(b.FrameCount())
+	frames := min(int(duration*int64(a.frameRate)/1000), min(aFrames, bFrames))
+
+	tail := make([]byte, frames*frameWidth)
+	copy(tail, a.data[(aFrames-frames)*frameWidth:])
+	head := make([]byte, frames*frameWidth)
+	copy(head, b.data[:frames*frameWidth])
+
+	if err = a.applyChunkGain(tail, 0, frames, curve.fadeOut); err != nil {
+		return nil, err
+	}
+	if err = b.applyChunkGain(head, 0, frames, curve.fadeIn); err != nil {
+		return nil, err
+	}
+
+	// Mix the signed samples.
+	if a.sampleWidth == 1 {
+		flipSignBit(tail)
+		flipSignBit(head)
+	}
+	if err = audioop.AddInto(tail, head, int(a.sampleWidth)); err != nil {
+		return nil, err
+	}
+	if a.sampleWidth == 1 {
+		flipSignBit(tail)
+	}
+
+	return a.derive(utils.ConcatenateByteSlice(
+		a.data[:(aFrames-frames)*frameWidth], tail, b.data[frames*frameWidth:]))
+}
+
+const (
+	// duckMinSilenceLen is the shortest pause (ms) in the voice that releases the ducking.
+	duckMinSilenceLen = 300
+	// duckSilenceThresh is the silence threshold relative to the loudness of the voice.
+	duckSilenceThresh = -16
+)
+
+// Duck 在voice有声音的区域降低music的音量(闪避),然后把voice叠加到music上
+//
+// 参数:
+//   - music: 背景音乐
+//   - voice: 人声(旁白)
+//   - amount: 衰减量(dB),正负均可,如12或-12都表示降低12dB
+//   - attack: 人声开始前音量降低的过渡时长(毫秒)
+//   - release: 人声结束后音量恢复的过渡时长(毫秒)
+//
+// 返回:
+//   - *AudioSegment: 混合后的音频,长度与music相同
+//   - error: 错误信息,如果有的话
+//
+// 说明:
+//   - 有声区域由DetectNonsilent检测,阈值为voice的dBFS-16,短于300ms的停顿不会恢复音量
+//   - 相邻区域的过渡重叠时会合并为一个区域
+//   - attack/release超出音频范围时会被截断
+func Duck(music, voice *AudioSegment, amount Volume, attack, release int64) (*AudioSegment, error) {
+	if music == nil || voice == nil {
+		return nil, NewAudioSegmentError("music and voice should not be nil")
+	}
+
+	if attack < 0 || release < 0 {
+		return nil, NewAudioSegmentError("attack and release should be positive")
+	}
+
+	syncedSegments, err := syncSegments(music, voice)
+	if err != nil {
+		return nil, err
+	}
+	music, voice = syncedSegments[0], syncedSegments[1]
+
+	reduced := -Volume(math.Abs(float64(amount)))
+	ranges := DetectNonsilent(voice, duckMinSilenceLen, voice.DBFS()+duckSilenceThresh, 1)
+
+	// Merge the ranges whose transitions overlap, so the gain never jumps back up in between.
+	var merged [][]int64
+	for _, r := range ranges {
+		if r[0] >= r[1] {
+			continue
+		}
+
+		if n := len(merged); n > 0 && r[0]-attack <= merged[n-1][1]+release {
+			merged[n-1][1] = r[1]
+			continue
+		}
+		merged = append(merged, []int64{r[0], r[1]})
+	}
+
+	frameCount := int64(music.FrameCount())
+	toFrame := func(ms int64) int {
+		frame := ms * int64(music.frameRate) / 1000
+		if frame < 0 {
+			return 0
+		}
+		if frame > frameCount {
+			return int(frameCount)
+		}
+		return int(frame)
+	}
+
+	data := make([]byte, len(music.data))
+	copy(data, music.data)
+	for _, r := range merged {
+		start, end := r[0], r[1]
+		steps := []struct {
+			from, to   Volume
+			start, end int64
+		}{
+			{0, reduced, start - attack, start},
+			{reduced, reduced, start, end},
+			{reduced, 0, end, end + release},
+		}
+
+		for _, step := range steps {
+			if err := music.rampGain(data, step.from, step.to, toFrame(step.start), toFrame(step.end)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ducked, err := music.derive(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return ducked.Overlay(voice, &OverlayConfig{})
+}
+
+// ReplaceSection 用replacement替换[start, end)范围内的音频,即 seg[:start] + replacement + seg[end:]
+//
+// 说明:
+//   - replacement的长度不需要与被替换的部分相同
+//   - 拼接前会同步replacement与当前音频的采样参数
+//   - end不能超过音频长度
+func (seg *AudioSegment) ReplaceSection(start, end int64, replacement *AudioSegment) (*AudioSegment, error) {
+	if replacement == nil {
+		return nil, NewAudioSegmentError("replacement should not be nil")
+	}
+
+	left, right, err := seg.splitAround(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return Concat(left, replacement, right)
+}
+
+// splitAround returns the parts before `start` and after `end` (milliseconds).
+func (seg *AudioSegment) splitAround(start, end int64) (*AudioSegment, *AudioSegment, error) {
+	if start < 0 || end < 0 {
+		return nil, nil, NewAudioSegmentError("%w: start or end should be positive", ErrInvalidRange)
+	}
+
+	if start > end {
+		return nil, nil, NewAudioSegmentError("%w: start should be smaller than end", ErrInvalidRange)
+	}
+
+	if end > seg.Duration() {
+		return nil, nil, NewAudioSegmentError("%w: end %dms is beyond the duration %dms", ErrInvalidRange, end, seg.Duration())
+	}
+
+	frameCount := int(seg.FrameCount())
+	left, err := seg.SliceFrames(0, int(start*int64(seg.frameRate)/1000))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	right, err := seg.SliceFrames(int(end*int64(seg.frameRate)/1000), frameCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return left, right, nil
+}
+
+// Insert 在at位置插入other,不覆盖原有音频,总时长随之增加
+//
+// 说明:
+//   - 插入前会同步other与当前音频的采样参数
+//   - at为0时相当于在开头添加,at超过音频长度时先用静音补齐再追加
+func (seg *AudioSegment) Insert(at int64, other *AudioSegment) (*AudioSegment, error) {
+	if other == nil {
+		return nil, NewAudioSegmentError("the segment to insert should not be nil")
+	}
+
+	if at < 0 {
+		return nil, NewAudioSegmentError("position should be positive")
+	}
+
+	if at > seg.Duration() {
+		padded, err := seg.PadTo(at, PadEnd, false)
+		if err != nil {
+			return nil, err
+		}
+		return Concat(padded, other)
+	}
+
+	return seg.ReplaceSection(at, at, other)
+}
+
+// Delete 删除[start, end)范围内的音频,并拼接前后两部分(Insert的逆操作)
+//
+// 说明:
+//   - start等于end时原样返回
+//   - end不能超过音频长度
+//   - 接缝处的波形可能跳变产生咔哒声,需要掩盖接缝时使用DeleteWithCrossfade
+func (seg *AudioSegment) Delete(start, end int64) (*AudioSegment, error) {
+	left, right, err := seg.splitAround(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if start == end {
+		return seg, nil
+	}
+
+	return seg.derive(utils.ConcatenateByteSlice(left.data, right.data))
+}
+
+// DeleteWithCrossfade 与Delete相同,但前后两部分以crossfade毫秒的交叉淡化拼接,以掩盖接缝
+//
+// 说明:
+//   - 即Crossfade(前一部分, 后一部分, crossfade),使用FadeEqualPower
+//   - 结果比Delete再短crossfade毫秒,crossfade不能超过前后任意一部分的长度
+//   - crossfade为0时与Delete相同,start等于end时原样返回
+func (seg *AudioSegment) DeleteWithCrossfade(start, end, crossfade int64) (*AudioSegment, error) {
+	left, right, err := seg.splitAround(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if start == end {
+		return seg, nil
+	}
+
+	return Crossfade(left, right, crossfade)
+}
@@ -0,0 +1,314 @@
+package godub
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wonglyxng/godub/audioop"
+)
+
+func TestPadTo(t *testing.T) {
+	seg := newSegment16(1, 2, 3)
+
+	padded, err := seg.PadTo(6, PadEnd, false)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 2, 3, 0, 0, 0).RawData(), padded.RawData())
+
+	padded, err = seg.PadTo(5, PadStart, false)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(0, 0, 1, 2, 3).RawData(), padded.RawData())
+
+	padded, err = seg.PadTo(6, PadCenter, false)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(0, 1, 2, 3, 0, 0).RawData(), padded.RawData())
+
+	padded, err = seg.PadTo(2, PadEnd, false)
+	assert.NoError(t, err)
+	assert.Same(t, seg, padded)
+
+	_, err = seg.PadTo(2, PadEnd, true)
+	assert.Error(t, err)
+}
+
+func TestTruncate(t *testing.T) {
+	seg := newSegment16(1, 2, 3)
+
+	truncated, err := seg.Truncate(2)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 2).RawData(), truncated.RawData())
+
+	truncated, err = seg.Truncate(10)
+	assert.NoError(t, err)
+	assert.Same(t, seg, truncated)
+
+	_, err = seg.Truncate(-1)
+	assert.Error(t, err)
+}
+
+func TestTileTo(t *testing.T) {
+	seg := newSegment16(1, 2, 3)
+
+	tiled, err := seg.TileTo(8)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 2, 3, 1, 2, 3, 1, 2).RawData(), tiled.RawData())
+
+	tiled, err = seg.TileTo(2)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 2).RawData(), tiled.RawData())
+
+	// 90s of a 12s loop
+	loop := newSineSegment(440, 0.5, 12000, 8000, 1)
+	tiled, err = loop.TileTo(90000)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(90000), tiled.Duration())
+
+	_, err = seg.TileTo(0)
+	assert.Error(t, err)
+	_, err = newSegment16().TileTo(10)
+	assert.Error(t, err)
+}
+
+func TestTileToWithCrossfade(t *testing.T) {
+	loop := newSineSegment(440, 0.5, 1000, 8000, 1)
+	tiled, err := loop.TileToWithCrossfade(3500, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3500), tiled.Duration())
+
+	// Every repeat after the first starts with the joint, 900ms apart
+	tail, _ := loop.Slice(900, 1000)
+	head, _ := loop.Slice(0, 100)
+	joint, _ := Crossfade(tail, head, 100)
+	first, _ := loop.Slice(0, 900)
+	middle, _ := loop.Slice(100, 900)
+	for _, start := range []int64{900, 1800, 2700} {
+		chunk, _ := tiled.Slice(start, start+100)
+		assert.Equal(t, joint.RawData(), chunk.RawData())
+		if start+900 <= 3500 {
+			chunk, _ = tiled.Slice(start+100, start+900)
+			assert.Equal(t, middle.RawData(), chunk.RawData())
+		}
+	}
+	chunk, _ := tiled.Slice(0, 900)
+	assert.Equal(t, first.RawData(), chunk.RawData())
+
+	plain, _ := loop.TileTo(3500)
+	tiled, err = loop.TileToWithCrossfade(3500, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, plain.RawData(), tiled.RawData())
+
+	_, err = loop.TileToWithCrossfade(3500, 600)
+	assert.Error(t, err)
+	_, err = loop.TileToWithCrossfade(3500, -1)
+	assert.Error(t, err)
+	_, err = loop.TileToWithCrossfade(0, 100)
+	assert.Error(t, err)
+	_, err = newSegment16().TileToWithCrossfade(10, 5)
+	assert.Error(t, err)
+}
+
+func TestChunk(t *testing.T) {
+	seg := newSegment16(1, 2, 3, 4, 5)
+
+	chunks, err := seg.Chunk(2, 0)
+	assert.NoError(t, err)
+	assert.Len(t, chunks, 3)
+	assert.Equal(t, newSegment16(5).RawData(), chunks[2].RawData())
+
+	chunks, err = seg.Chunk(3, 1)
+	assert.NoError(t, err)
+	assert.Len(t, chunks, 2)
+	assert.Equal(t, newSegment16(3, 4, 5).RawData(), chunks[1].RawData())
+
+	_, err = seg.Chunk(0, 0)
+	assert.Error(t, err)
+
+	_, err = seg.Chunk(2, 2)
+	assert.Error(t, err)
+}
+
+func TestGainRamp(t *testing.T) {
+	seg := newSegment16(1000, 1000, 1000, 1000, 1000)
+
+	// -6.0206dB halves the amplitude
+	ramped, err := seg.GainRamp(0, -6.0206, 1, 4)
+	assert.NoError(t, err)
+	samples, _ := audioop.GetSamples(ramped.RawData(), 2)
+	assert.Equal(t, int32(1000), samples[0])
+	assert.Equal(t, int32(1000), samples[1])
+	assert.InDelta(t, 707, samples[2], 1)
+	assert.InDelta(t, 500, samples[3], 1)
+	assert.Equal(t, int32(1000), samples[4])
+
+	ramped, err = seg.GainRamp(0, -6, 3, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, seg.Len(), ramped.Len())
+
+	_, err = seg.GainRamp(0, -6, 3, 1)
+	assert.Error(t, err)
+}
+
+func TestCrossfade(t *testing.T) {
+	a := newSineSegment(440, 0.5, 500, 8000, 1)
+	silence, _ := NewSilentAudioSegmentWith(500, 8000, 2, 1)
+
+	faded, err := Crossfade(a, silence, 200)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(800), faded.Duration())
+
+	// Untouched before the overlap
+	head, _ := faded.Slice(0, 300)
+	expected, _ := a.Slice(0, 300)
+	assert.Equal(t, expected.RawData(), head.RawData())
+
+	// a is at cos(π/4) in the middle of the overlap, and silent at the end
+	middle, _ := faded.Slice(395, 405)
+	original, _ := a.Slice(395, 405)
+	assert.InDelta(t, original.RMS()*math.Sqrt(0.5), middle.RMS(), original.RMS()*0.05)
+	tail, _ := faded.Slice(499, 800)
+	assert.InDelta(t, 0, tail.RMS(), 1)
+
+	// Equal power: the overlap of two uncorrelated signals is as loud as each of them
+	tone1 := newSineSegment(440, 0.5, 500, 8000, 1)
+	tone2 := newSineSegment(1230, 0.5, 500, 8000, 1)
+	faded, err = Crossfade(tone1, tone2, 500)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), faded.Duration())
+	overlap, _ := faded.Slice(200, 300)
+	assert.InDelta(t, tone1.RMS(), overlap.RMS(), tone1.RMS()*0.05)
+
+	// Synced to the same format
+	stereo := newSineSegment(440, 0.5, 500, 16000, 2)
+	faded, err = Crossfade(a, stereo, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(2), faded.Channels())
+	assert.Equal(t, int64(900), faded.Duration())
+
+	same, err := Crossfade(a, silence, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), same.Duration())
+
+	_, err = Crossfade(a, silence, 600)
+	assert.Error(t, err)
+	_, err = Crossfade(a, silence, -1)
+	assert.Error(t, err)
+	_, err = Crossfade(nil, silence, 100)
+	assert.Error(t, err)
+}
+
+func TestDuck(t *testing.T) {
+	music := newSineSegment(200, 0.5, 3000, 8000, 1)
+	silence, _ := NewSilentAudioSegmentWith(1000, 8000, 2, 1)
+	voice, _ := Concat(silence, newSineSegment(1000, 0.5, 1000, 8000, 1), silence)
+
+	ducked, err := Duck(music, voice, 20, 100, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, music.Duration(), ducked.Duration())
+
+	// Music is untouched outside the voice and its transitions
+	head, _ := ducked.Slice(0, 800)
+	expected, _ := music.Slice(0, 800)
+	assert.True(t, expected.Equal(head))
+
+	// Music is 20dB lower under the voice
+	under, _ := ducked.Slice(1000, 2000)
+	voiceOnly, _ := voice.Slice(1000, 2000)
+	musicOnly, _ := music.Slice(1000, 2000)
+	mixed, _ := audioop.GetSamples(under.RawData(), 2)
+	voiceSamples, _ := audioop.GetSamples(voiceOnly.RawData(), 2)
+	residual := make([]int16, len(mixed))
+	for i := range mixed {
+		residual[i] = int16(mixed[i] - voiceSamples[i])
+	}
+	residualSeg := newSegment16(residual...)
+	assert.InDelta(t, float64(musicOnly.DBFS()-20), float64(residualSeg.DBFS()), 0.5)
+
+	_, err = Duck(music, nil, 20, 100, 100)
+	assert.Error(t, err)
+}
+
+func TestReplaceSection(t *testing.T) {
+	seg := newSegment16(1, 2, 3, 4, 5)
+
+	replaced, err := seg.ReplaceSection(1, 3, newSegment16(7, 8, 9))
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 7, 8, 9, 4, 5).RawData(), replaced.RawData())
+
+	replaced, err = seg.ReplaceSection(0, 5, newSegment16(7))
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(7).RawData(), replaced.RawData())
+
+	_, err = seg.ReplaceSection(1, 6, newSegment16(7))
+	assert.Error(t, err)
+
+	_, err = seg.ReplaceSection(3, 1, newSegment16(7))
+	assert.Error(t, err)
+
+	_, err = seg.ReplaceSection(1, 3, nil)
+	assert.Error(t, err)
+}
+
+func TestInsert(t *testing.T) {
+	seg := newSegment16(1, 2, 3)
+
+	inserted, err := seg.Insert(0, newSegment16(7, 8))
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(7, 8, 1, 2, 3).RawData(), inserted.RawData())
+
+	inserted, err = seg.Insert(2, newSegment16(7, 8))
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 2, 7, 8, 3).RawData(), inserted.RawData())
+
+	inserted, err = seg.Insert(5, newSegment16(7))
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 2, 3, 0, 0, 7).RawData(), inserted.RawData())
+
+	_, err = seg.Insert(-1, newSegment16(7))
+	assert.Error(t, err)
+}
+
+func TestDelete(t *testing.T) {
+	seg := newSegment16(1, 2, 3, 4, 5)
+
+	deleted, err := seg.Delete(1, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 4, 5).RawData(), deleted.RawData())
+	assert.Equal(t, seg.Duration()-2, deleted.Duration())
+
+	long := newSineSegment(440, 0.5, 2000, 8000, 2)
+	deleted, err = long.Delete(500, 1250)
+	assert.NoError(t, err)
+	assert.Equal(t, long.Duration()-750, deleted.Duration())
+
+	deleted, err = seg.Delete(2, 2)
+	assert.NoError(t, err)
+	assert.Same(t, seg, deleted)
+
+	_, err = seg.Delete(1, 6)
+	assert.Error(t, err)
+}
+
+func TestDeleteWithCrossfade(t *testing.T) {
+	long := newSineSegment(440, 0.5, 2000, 8000, 1)
+	deleted, err := long.DeleteWithCrossfade(500, 1250, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, long.Duration()-750-20, deleted.Duration())
+
+	left, _ := long.Slice(0, 500)
+	right, _ := long.Slice(1250, 2000)
+	expected, _ := Crossfade(left, right, 20)
+	assert.Equal(t, expected.RawData(), deleted.RawData())
+
+	deleted, err = long.DeleteWithCrossfade(500, 1250, 0)
+	assert.NoError(t, err)
+	plain, _ := long.Delete(500, 1250)
+	assert.Equal(t, plain.RawData(), deleted.RawData())
+
+	deleted, err = long.DeleteWithCrossfade(500, 500, 20)
+	assert.NoError(t, err)
+	assert.Same(t, long, deleted)
+
+	// Longer than the part before
+	_, err = long.DeleteWithCrossfade(10, 1250, 20)
+	assert.Error(t, err)
+}
@@ -0,0 +1,196 @@
+package godub
+
+import "math"
+
+// MapSamples 对每个采样调用f,用于实现自定义效果(如失真、比特压缩)
+//
+// 参数:
+//   - f: 采样处理函数,参数为有符号采样值和声道索引(从0开始),返回新的采样值
+//
+// 说明:
+//   - 采样值为有符号整数,范围由采样宽度决定,8位音频会先去掉无符号偏移
+//   - 返回值超出范围时被截断到满刻度,采样宽度保持不变
+//   - 所有采样在一次遍历中按顺序处理
+//
+// 注意:
+//   - 每个采样都要调用一次f,比内置的处理(ApplyGain等)慢得多,适合原型验证
+func (seg *AudioSegment) MapSamples(f func(sample int32, channel int) int32) (*AudioSegment, error) {
+	if f == nil {
+		return nil, NewAudioSegmentError("sample function should not be nil")
+	}
+
+	samples, err := seg.signedSamples()
+	if err != nil {
+		return nil, err
+	}
+
+	maxValue := int32(seg.MaxPossibleAmplitude() - 1)
+	minValue := -maxValue - 1
+	channels := int(seg.channels)
+	for i, sample := range samples {
+		v := f(sample, i%channels)
+		if v > maxValue {
+			v = maxValue
+		} else if v < minValue {
+			v = minValue
+		}
+		samples[i] = v
+	}
+
+	return seg.derive(seg.packSignedSamples(samples))
+}
+
+// Bitcrush 比特压缩效果,降低采样精度和有效采样率,用于制作lo-fi音色
+//
+// 参数:
+//   - bits: 保留的采样精度(位),范围[1, 采样宽度*8]
+//   - downsampleFactor: 降采样倍数,每downsampleFactor帧只保留第一帧(采样保持),1表示不降采样
+//
+// 说明:
+//   - 采样值的低位被清零,量化为bits位精度
+//   - 降采样不改变实际的采样率和时长,只是重复保持的帧
+//   - 每个声道独立处理
+func (seg *AudioSegment) Bitcrush(bits int, downsampleFactor int) (*AudioSegment, error) {
+	sampleBits := int(seg.sampleWidth) * 8
+	if bits < 1 || bits > sampleBits {
+		return nil, NewAudioSegmentError("bits should be in [1, %d], got %d", sampleBits, bits)
+	}
+	if downsampleFactor < 1 {
+		return nil, NewAudioSegmentError("downsample factor should be at least 1, got %d", downsampleFactor)
+	}
+
+	samples, err := seg.signedSamples()
+	if err != nil {
+		return nil, err
+	}
+
+	shift := uint(sampleBits - bits)
+	channels := int(seg.channels)
+	for i := range samples {
+		frame := i / channels
+		if held := frame % downsampleFactor; held != 0 {
+			// Hold the (already crushed) sample of the first frame in the block.
+			samples[i] = samples[i-held*channels]
+			continue
+		}
+		samples[i] = samples[i] >> shift << shift
+	}
+
+	return seg.derive(seg.packSignedSamples(samples))
+}
+
+// RemoveClicks 去除短促的咔哒声(如黑胶翻录中的爆音)
+//
+// 参数:
+//   - sensitivity: 灵敏度,范围(0, 1],越大检测越激进,0.5是比较稳妥的起点
+//
+// 说明:
+//   - 每个声道独立处理:采样与相邻采样平均值的偏差远大于附近(约5ms内)的平均偏差时视为咔哒声
+//   - 检测到的采样连同两侧各一个采样,用前后未受影响的采样线性插值替换
+//   - 超过2ms的突变视为正常的瞬态,不会被修改;未检测到咔哒声的采样保持不变
+//
+// 注意:
+//   - 灵敏度过高时,打击乐等正常的瞬态也会被当作咔哒声,声音会变得发闷
+func (seg *AudioSegment) RemoveClicks(sensitivity float64) (*AudioSegment, error) {
+	if !(sensitivity > 0 && sensitivity <= 1) {
+		return nil, NewAudioSegmentError("sensitivity should be in (0, 1], got %v", sensitivity)
+	}
+
+	samples, err := seg.signedSamples()
+	if err != nil {
+		return nil, err
+	}
+
+	channels := int(seg.channels)
+	frames := len(samples) / channels
+	opts := clickOptions{
+		factor:    4 + 16*(1-sensitivity),
+		floor:     seg.MaxPossibleAmplitude() / 1000,
+		window:    max(int(seg.FrameCountIn(5)), 4),
+		maxLength: max(int(seg.FrameCountIn(2)), 3),
+	}
+
+	channel := make([]int32, frames)
+	for c := 0; c < channels; c++ {
+		for i := range channel {
+			channel[i] = samples[i*channels+c]
+		}
+		if !removeClicks(channel, opts) {
+			continue
+		}
+		for i, v := range channel {
+			samples[i*channels+c] = v
+		}
+	}
+
+	return seg.derive(seg.packSignedSamples(samples))
+}
+
+type clickOptions struct {
+	// factor times the local mean deviation is the threshold of clicks.
+	factor float64
+	// floor is the minimum deviation of clicks, so that noise in silence is left alone.
+	floor float64
+	// window is the half size (frames) of the local mean.
+	window int
+	// maxLength is the longest run (frames) to be treated as a click.
+	maxLength int
+}
+
+// removeClicks interpolates across the clicks of one channel in place, and tells if any is removed.
+func removeClicks(s []int32, opts clickOptions) bool {
+	n := len(s)
+	if n < 3 {
+		return false
+	}
+
+	// The deviation from the average of the neighbors, and its prefix sums.
+	dev := make([]float64, n)
+	prefix := make([]float64, n+1)
+	for i := 1; i < n-1; i++ {
+		dev[i] = math.Abs(2*float64(s[i]) - float64(s[i-1]) - float64(s[i+1]))
+	}
+	for i, d := range dev {
+		prefix[i+1] = prefix[i] + d
+	}
+
+	// The click itself is excluded from the local mean, a spike raises the
+	// deviation of its neighbors too.
+	isClick := func(i int) bool {
+		lo, hi := max(i-opts.window, 0), min(i+opts.window+1, n)
+		excludedLo, excludedHi := max(i-2, 0), min(i+3, n)
+		count := (hi - lo) - (excludedHi - excludedLo)
+		if count <= 0 {
+			return false
+		}
+		sum := (prefix[hi] - prefix[lo]) - (prefix[excludedHi] - prefix[excludedLo])
+		return dev[i] > opts.floor && dev[i] > opts.factor*sum/float64(count)
+	}
+
+	removed := false
+	for i := 1; i < n-1; i++ {
+		if !isClick(i) {
+			continue
+		}
+
+		// The run of clicks, with one more sample on both sides.
+		start, end := i-1, i+1
+		for end < n-1 && isClick(end) {
+			end++
+		}
+		end++
+		i = end
+
+		if start < 1 || end >= n || end-start > opts.maxLength {
+			continue
+		}
+		a, b := float64(s[start-1]), float64(s[end])
+		length := float64(end - start + 1)
+		for j := start; j < end; j++ {
+			t := float64(j-start+1) / length
+			s[j] = int32(math.Round(a + (b-a)*t))
+		}
+		removed = true
+	}
+	return removed
+}
@@ -0,0 +1,97 @@
+package godub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapSamples(t *testing.T) {
+	seg, _ := NewAudioSegment(newSegment16(100, -100, 200, -200, 30000, -30000).RawData(),
+		SampleWidth(2), FrameRate(1000), Channels(2), FrameWidth(4))
+
+	// Double the left channel and silence the right one, the result is clamped.
+	mapped, err := seg.MapSamples(func(sample int32, channel int) int32 {
+		if channel == 0 {
+			return sample * 2
+		}
+		return 0
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(200, 0, 400, 0, 32767, 0).RawData(), mapped.RawData())
+	assert.Equal(t, seg.Channels(), mapped.Channels())
+
+	// 8-bit samples are signed in the function
+	seg, _ = NewAudioSegment([]byte{0x80, 0x90, 0x00, 0xff}, SampleWidth(1), FrameRate(8000), Channels(1), FrameWidth(1))
+	var samples []int32
+	mapped, err = seg.MapSamples(func(sample int32, channel int) int32 {
+		samples = append(samples, sample)
+		return -sample
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{0, 16, -128, 127}, samples)
+	assert.Equal(t, []byte{0x80, 0x70, 0xff, 0x01}, mapped.RawData())
+
+	_, err = seg.MapSamples(nil)
+	assert.Error(t, err)
+}
+
+func TestBitcrush(t *testing.T) {
+	seg := newSegment16(0x1234, -0x1234, 0x7fff, 100)
+
+	crushed, err := seg.Bitcrush(8, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(0x1200, -0x1300, 0x7f00, 0).RawData(), crushed.RawData())
+
+	same, err := seg.Bitcrush(16, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, seg.RawData(), same.RawData())
+
+	// Stereo sample-and-hold keeps the channels apart
+	stereo, _ := NewAudioSegment(newSegment16(1, -1, 2, -2, 3, -3, 4, -4, 5, -5).RawData(),
+		SampleWidth(2), FrameRate(1000), Channels(2), FrameWidth(4))
+	held, err := stereo.Bitcrush(16, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, -1, 1, -1, 3, -3, 3, -3, 5, -5).RawData(), held.RawData())
+	assert.Equal(t, stereo.FrameRate(), held.FrameRate())
+	assert.Equal(t, stereo.Duration(), held.Duration())
+
+	for _, args := range [][2]int{{0, 1}, {17, 1}, {8, 0}} {
+		_, err = seg.Bitcrush(args[0], args[1])
+		assert.Error(t, err, args)
+	}
+}
+
+func TestRemoveClicks(t *testing.T) {
+	clean := newSineSegment(440, 0.5, 200, 8000, 2)
+
+	// Nothing is changed without clicks
+	declicked, err := clean.RemoveClicks(0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, clean.RawData(), declicked.RawData())
+
+	// Spikes of one and two samples in the left channel
+	samples, _ := clean.signedSamples()
+	clicked := append([]int32(nil), samples...)
+	for _, frame := range []int{300, 301, 900} {
+		clicked[frame*2] = 32000
+	}
+	seg, _ := clean.derive(clean.packSignedSamples(clicked))
+
+	declicked, err = seg.RemoveClicks(0.5)
+	assert.NoError(t, err)
+	restored, _ := declicked.signedSamples()
+	// Linear interpolation is close to, but not exactly the sine
+	for _, frame := range []int{300, 301, 900} {
+		assert.InDelta(t, samples[frame*2], restored[frame*2], 3000)
+	}
+	// The right channel is untouched
+	for i := 1; i < len(samples); i += 2 {
+		assert.Equal(t, samples[i], restored[i])
+	}
+
+	_, err = seg.RemoveClicks(0)
+	assert.Error(t, err)
+	_, err = seg.RemoveClicks(1.5)
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package godub
+
+import "math"
+
+// EQBandType is the filter type of an EQBand.
+type EQBandType int
+
+const (
+	// EQPeak boosts or cuts a bell shaped band around the frequency, Q decides the
+	// bandwidth, the higher the narrower.
+	EQPeak EQBandType = iota
+	// EQLowShelf boosts or cuts the frequencies below the frequency, Q decides the slope.
+	EQLowShelf
+	// EQHighShelf boosts or cuts the frequencies above the frequency, Q decides the slope.
+	EQHighShelf
+)
+
+// EQBand is a band of the parametric equalizer, see Equalize.
+type EQBand struct {
+	Type EQBandType
+	// Frequency is the center frequency of EQPeak, or the corner of shelf filters (Hz).
+	Frequency float64
+	// Gain is the boost (positive) or cut (negative) in dB.
+	Gain Volume
+	// Q is the quality factor, 0.7071 (1/sqrt(2)) is a usual choice for shelf filters.
+	Q float64
+}
+
+// Equalize 参数均衡器,按顺序对每个声道应用所有频段的二阶(biquad)滤波器
+//
+// 参数:
+//   - bands: 均衡频段,每个频段有各自的类型、频率、增益和Q值
+//
+// 说明:
+//   - EQPeak: 钟形峰值滤波器,提升/衰减Frequency附近的频率,Q越大频带越窄
+//   - EQLowShelf: 低架滤波器,提升/衰减Frequency以下的频率
+//   - EQHighShelf: 高架滤波器,提升/衰减Frequency以上的频率
+//   - 基于浮点采样处理,结果保持原有的采样宽度和采样率,超出满刻度的部分被截断
+//   - 例如提升3kHz附近可以改善人声清晰度,衰减200Hz以下可以减少低频浑浊
+//
+// 注意:
+//   - Frequency必须在(0, 采样率/2)范围内,Q必须大于0
+func (seg *AudioSegment) Equalize(bands []EQBand) (*AudioSegment, error) {
+	nyquist := float64(seg.frameRate) / 2
+	for i, band := range bands {
+		if !(band.Frequency > 0 && band.Frequency < nyquist) {
+			return nil, NewAudioSegmentError("frequency of band %d should be in (0, %v), got %v", i, nyquist, band.Frequency)
+		}
+		if !(band.Q > 0) {
+			return nil, NewAudioSegmentError("Q of band %d should be greater than 0, got %v", i, band.Q)
+		}
+		if math.IsNaN(float64(band.Gain)) || math.IsInf(float64(band.Gain), 0) {
+			return nil, NewAudioSegmentError("invalid gain %v of band %d", band.Gain, i)
+		}
+		if band.Type != EQPeak && band.Type != EQLowShelf && band.Type != EQHighShelf {
+			return nil, NewAudioSegmentError("invalid type %d of band %d", band.Type, i)
+		}
+	}
+
+	if len(bands) == 0 {
+		return seg, nil
+	}
+
+	channels, err := seg.ToFloat64()
+	if err != nil {
+		return nil, err
+	}
+
+	frameRate := float64(seg.frameRate)
+	for _, samples := range channels {
+		// Filters have state, every channel gets its own.
+		filters := make([]*biquad, len(bands))
+		for i, band := range bands {
+			filters[i] = newEQFilter(band, frameRate)
+		}
+
+		for j, v := range samples {
+			for _, f := range filters {
+				v = f.process(v)
+			}
+			samples[j] = v
+		}
+	}
+
+	return seg.deriveFromFloat64(channels)
+}
+
+func newEQFilter(band EQBand, frameRate float64) *biquad {
+	gain := float64(band.Gain)
+	switch band.Type {
+	case EQLowShelf:
+		return newLowShelf(band.Frequency, gain, band.Q, frameRate)
+	case EQHighShelf:
+		return newHighShelf(band.Frequency, gain, band.Q, frameRate)
+	default:
+		return newPeaking(band.Frequency, gain, band.Q, frameRate)
+	}
+}
@@ -0,0 +1,63 @@
+package godub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// eqGain returns the change of dBFS by the EQ, the filter transient at the start is skipped.
+func eqGain(t *testing.T, seg *AudioSegment, bands []EQBand) float64 {
+	equalized, err := seg.Equalize(bands)
+	assert.NoError(t, err)
+
+	before, _ := seg.Slice(100, seg.Duration())
+	after, _ := equalized.Slice(100, seg.Duration())
+	return float64(after.DBFS() - before.DBFS())
+}
+
+func TestEqualize(t *testing.T) {
+	low := newSineSegment(50, 0.2, 500, 44100, 1)
+	mid := newSineSegment(1000, 0.2, 500, 44100, 1)
+	high := newSineSegment(10000, 0.2, 500, 44100, 1)
+
+	peak := []EQBand{{Type: EQPeak, Frequency: 1000, Gain: 6, Q: 1}}
+	assert.InDelta(t, 6, eqGain(t, mid, peak), 0.2)
+	assert.InDelta(t, 0, eqGain(t, low, peak), 0.2)
+	assert.InDelta(t, 0, eqGain(t, high, peak), 0.2)
+
+	lowShelf := []EQBand{{Type: EQLowShelf, Frequency: 200, Gain: -12, Q: 0.7071}}
+	assert.InDelta(t, -12, eqGain(t, low, lowShelf), 0.5)
+	assert.InDelta(t, 0, eqGain(t, high, lowShelf), 0.2)
+
+	highShelf := []EQBand{{Type: EQHighShelf, Frequency: 4000, Gain: 6, Q: 0.7071}}
+	assert.InDelta(t, 6, eqGain(t, high, highShelf), 0.5)
+	assert.InDelta(t, 0, eqGain(t, low, highShelf), 0.2)
+
+	// Bands are chained
+	assert.InDelta(t, -12, eqGain(t, low, append(lowShelf, highShelf...)), 0.5)
+
+	// Every channel is filtered on its own
+	stereo := newSineSegment(1000, 0.2, 500, 44100, 2)
+	equalized, err := stereo.Equalize(peak)
+	assert.NoError(t, err)
+	assert.Equal(t, stereo.Channels(), equalized.Channels())
+	channels, _ := equalized.SplitToMono()
+	monoEqualized, _ := mid.Equalize(peak)
+	assert.Equal(t, monoEqualized.RawData(), channels[0].RawData())
+	assert.Equal(t, monoEqualized.RawData(), channels[1].RawData())
+
+	same, err := mid.Equalize(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, mid, same)
+
+	for _, band := range []EQBand{
+		{Frequency: 0, Gain: 3, Q: 1},
+		{Frequency: 30000, Gain: 3, Q: 1},
+		{Frequency: 1000, Gain: 3, Q: 0},
+		{Type: 5, Frequency: 1000, Gain: 3, Q: 1},
+	} {
+		_, err = mid.Equalize([]EQBand{band})
+		assert.Error(t, err, band)
+	}
+}
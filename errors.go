@@ -1,15 +1,46 @@
 package godub
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Sentinel errors wrapped by the errors of AudioSegment, check them with errors.Is.
+var (
+	// ErrEmptyAudio is returned when the audio has no data to work on.
+	ErrEmptyAudio = errors.New("empty audio")
+	// ErrInvalidChannels is returned when the number of channels or a channel index is invalid.
+	ErrInvalidChannels = errors.New("invalid channels")
+	// ErrInvalidRange is returned when a start/end range is reversed, negative or out of the audio.
+	ErrInvalidRange = errors.New("invalid range")
+)
 
 type AudioSegmentError struct {
-	inner string
+	inner error
 }
 
+// NewAudioSegmentError formats the error like fmt.Errorf, so a sentinel error can be
+// wrapped with %w.
 func NewAudioSegmentError(format string, args ...interface{}) AudioSegmentError {
-	return AudioSegmentError{inner: fmt.Sprintf(format, args...)}
+	return AudioSegmentError{inner: fmt.Errorf(format, args...)}
 }
 
 func (e AudioSegmentError) Error() string {
-	return e.inner
+	return e.inner.Error()
+}
+
+// Unwrap returns the error wrapped with %w, if any.
+func (e AudioSegmentError) Unwrap() error {
+	return errors.Unwrap(e.inner)
+}
+
+// DurationLimitError is returned by Loader when the audio is longer than the limit
+// set by WithMaxDuration.
+type DurationLimitError struct {
+	Limit time.Duration
+}
+
+func (e DurationLimitError) Error() string {
+	return fmt.Sprintf("audio is longer than the limit %v", e.Limit)
 }
@@ -0,0 +1,36 @@
+package godub
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 100, 8000, 1)
+
+	_, err := seg.Slice(50, 10)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+	_, err = seg.SliceFrames(0, 10000)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+	assert.False(t, errors.Is(err, ErrInvalidChannels))
+
+	var segErr AudioSegmentError
+	assert.ErrorAs(t, err, &segErr)
+
+	_, err = seg.ForkWithChannels(7)
+	assert.ErrorIs(t, err, ErrInvalidChannels)
+	_, err = seg.ApplyGainToChannel(1, -3)
+	assert.ErrorIs(t, err, ErrInvalidChannels)
+	assert.Equal(t, "invalid channels: channel 1 is out of range, the segment has 1 channels", err.Error())
+
+	empty, _ := seg.derive(nil)
+	_, _, err = SplitOnSilence(empty, 100, -40, 0, 10)
+	assert.ErrorIs(t, err, ErrEmptyAudio)
+	var invalid *InvalidFile
+	assert.ErrorAs(t, err, &invalid)
+
+	// Errors without a sentinel don't unwrap
+	assert.Nil(t, errors.Unwrap(NewAudioSegmentError("plain %d", 1)))
+}
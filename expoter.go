@@ -1,8 +1,13 @@
 package godub
 
 import (
+	"fmt"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"bytes"
 
@@ -13,6 +18,10 @@ import (
 type Exporter struct {
 	converter *converter.Converter
 	dst       interface{}
+	// tagsSet tells if WithTags is called, otherwise the metadata of the segment is used.
+	tagsSet bool
+	// keepSourceSampleWidth exports 24-bit audio as 24-bit instead of 32-bit.
+	keepSourceSampleWidth bool
 }
 
 func NewExporter(dst interface{}) *Exporter {
@@ -20,8 +29,13 @@ func NewExporter(dst interface{}) *Exporter {
 }
 
 func (e *Exporter) Export(segment *AudioSegment) error {
+	waveAudio := segment.AsWaveAudio()
+	if e.keepSourceSampleWidth {
+		waveAudio = segment.asSourceWaveAudio()
+	}
+
 	wavBuf := bytes.Buffer{}
-	err := wav.Encode(&wavBuf, segment.AsWaveAudio())
+	err := wav.Encode(&wavBuf, waveAudio)
 	if err != nil {
 		return err
 	}
@@ -44,6 +58,9 @@ func (e *Exporter) Export(segment *AudioSegment) error {
 		return err
 	} else {
 		// Otherwise, convert it to the dst format using ffmpeg.
+		if !e.tagsSet {
+			e.converter.WithTags(segment.metadata)
+		}
 		return e.converter.WithWriter(w).Convert(&wavBuf)
 	}
 }
@@ -63,6 +80,11 @@ func (e *Exporter) WithBitRate(rate int) *Exporter {
 	return e
 }
 
+func (e *Exporter) WithVBRQuality(q int) *Exporter {
+	e.converter.WithVBRQuality(q)
+	return e
+}
+
 func (e *Exporter) WithSampleRate(rate int) *Exporter {
 	e.converter.WithSampleRate(rate)
 	return e
@@ -73,11 +95,22 @@ func (e *Exporter) WithChannels(channels int) *Exporter {
 	return e
 }
 
+// WithTags sets the tags of the exported audio, the metadata of the segment is used
+// by default.
 func (e *Exporter) WithTags(tags map[string]string) *Exporter {
+	e.tagsSet = true
 	e.converter.WithTags(tags)
 	return e
 }
 
+// WithSourceSampleWidth exports the audio in its source sample width if keep is true, see
+// AudioSegment.SourceSampleWidth. 24-bit audio is 32-bit internally and exported as 32-bit
+// by default, with it the output is true 24-bit, e.g. for 24-bit deliverables.
+func (e *Exporter) WithSourceSampleWidth(keep bool) *Exporter {
+	e.keepSourceSampleWidth = keep
+	return e
+}
+
 func (e *Exporter) WithDstFormat(f string) *Exporter {
 	e.converter.WithDstFormat(f)
 	return e
@@ -92,3 +125,321 @@ func (e *Exporter) WithParams(p ...string) *Exporter {
 	e.converter.WithParams(p...)
 	return e
 }
+
+// ExportOpus 把音频片段导出为适合语音的Ogg/Opus(单声道、16kHz、`-application voip`)
+//
+// 参数:
+//   - segment: 需要导出的音频片段
+//   - w: 输出
+//   - bitRate: 码率(bps),范围[converter.OpusBitRateMin, converter.OpusBitRateMax],
+//     语音通常使用converter.OpusBitRateVoice
+//
+// 说明:
+//   - 通过ffmpeg的libopus编码,参见converter.ExportOpus
+func ExportOpus(segment *AudioSegment, w io.Writer, bitRate int) error {
+	wavBuf := bytes.Buffer{}
+	if err := wav.Encode(&wavBuf, segment.AsWaveAudio()); err != nil {
+		return err
+	}
+	return converter.ExportOpus(&wavBuf, w, bitRate)
+}
+
+// ExportChunksOnSilence 在静音处切分音频,并把每个片段导出为编号的文件
+//
+// 参数:
+//   - seg: 待切分的音频片段
+//   - dir: 输出目录,必须已经存在
+//   - prefix: 文件名前缀,文件名为prefix_001.format、prefix_002.format……
+//   - format: 输出格式,例如"wav"、"mp3",wav以外的格式通过ffmpeg转换
+//   - opts: 切分配置,见SplitOnSilenceWithOptions
+//
+// 返回:
+//   - []string: 按顺序写出的文件路径
+//
+// 说明:
+//   - 编号至少3位,片段超过999个时自动加宽
+//   - 任意一个片段导出失败时,删除本次已经写出的所有文件(包括写了一半的文件)再返回错误
+func ExportChunksOnSilence(seg *AudioSegment, dir, prefix, format string, opts SplitOnSilenceOptions) ([]string, error) {
+	if format == "" {
+		return nil, NewAudioSegmentError("format should not be empty")
+	}
+
+	chunks, _, err := SplitOnSilenceWithOptions(seg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	digits := max(len(strconv.Itoa(len(chunks))), 3)
+	paths := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		p := filepath.Join(dir, fmt.Sprintf("%s_%0*d.%s", prefix, digits, i+1, format))
+		if err := exportChunk(chunk, p, format); err != nil {
+			for _, written := range paths {
+				os.Remove(written)
+			}
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// exportChunk exports the segment to a new file, which is removed if the export fails.
+func exportChunk(segment *AudioSegment, path, format string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = NewExporter(f).WithDstFormat(format).Export(segment)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// StreamExporter writes WAV audio incrementally, segment by segment, so that long
+// recordings can be exported with constant memory instead of being assembled into
+// one giant AudioSegment first.
+type StreamExporter struct {
+	w         io.Writer
+	format    *AudioSegment
+	dataSize  uint32
+	sizeKnown bool
+	// duration is converted to dataSize once the format is known.
+	duration time.Duration
+	seekable bool
+	// useTempFile buffers the data in a temp file in tempDir if w can't seek.
+	useTempFile bool
+	tempDir     string
+	temp        *os.File
+	headerPos   int64
+	written     int64
+	// keepSourceSampleWidth writes 24-bit audio as 24-bit instead of 32-bit.
+	keepSourceSampleWidth bool
+	// to24Bit is set once the header tells that the data is converted to 24-bit.
+	to24Bit bool
+}
+
+// NewStreamExporter 创建流式WAV导出器
+//
+// 说明:
+//   - WAV头在第一次WriteSegment时写入,格式(声道数、采样率、采样宽度)由第一个音频片段决定
+//   - 之后的音频片段会先转换为相同的格式再写入
+//   - Close不会关闭w
+//
+// 头部的大小字段按以下方式确定:
+//   - w实现了io.WriteSeeker并且可以定位(如*os.File):单次写入,Close时回到头部修正大小字段
+//   - 通过WithDataSize或WithDuration预先声明了大小:单次写入,头部一开始就是正确的,
+//     适合管道、HTTP分块响应等不可定位的w
+//   - 通过WithTempFile启用了两遍模式:数据先写入临时文件,Close时写出正确的头部再复制数据,
+//     需要额外的磁盘空间,数据在Close时才到达w
+//   - 以上都不满足时,大小字段为0xFFFFFFFF(流式WAV的惯例写法),部分播放器不支持
+func NewStreamExporter(w io.Writer) *StreamExporter {
+	return &StreamExporter{w: w}
+}
+
+// WithDataSize declares the total size (bytes) of the PCM data in advance,
+// which is required to produce a valid header on non-seekable writers.
+func (e *StreamExporter) WithDataSize(size uint32) *StreamExporter {
+	e.dataSize = size
+	e.sizeKnown = true
+	return e
+}
+
+// WithDuration is like WithDataSize, but declares the total duration. The size is
+// calculated in the format of the first segment, so the frames written must add up to
+// exactly duration * frame rate. 0 leaves the size undeclared, and a negative duration
+// is an error on the first WriteSegment.
+func (e *StreamExporter) WithDuration(duration time.Duration) *StreamExporter {
+	e.duration = duration
+	if duration > 0 {
+		e.sizeKnown = true
+	}
+	return e
+}
+
+// WithTempFile enables the two-pass mode for non-seekable writers whose size isn't
+// declared: the data is buffered in a temp file in dir (os.TempDir() if empty), and
+// copied to the writer after a correct header on Close. The temp file is removed on Close.
+func (e *StreamExporter) WithTempFile(dir string) *StreamExporter {
+	e.useTempFile = true
+	e.tempDir = dir
+	return e
+}
+
+// WithSourceSampleWidth is like Exporter.WithSourceSampleWidth: if keep is true and the
+// first segment is 24-bit audio, the stream is written as 24-bit instead of 32-bit.
+func (e *StreamExporter) WithSourceSampleWidth(keep bool) *StreamExporter {
+	e.keepSourceSampleWidth = keep
+	return e
+}
+
+// WriteSegment converts the segment to the format of the stream and writes its PCM data.
+func (e *StreamExporter) WriteSegment(segment *AudioSegment) error {
+	if segment == nil {
+		return NewAudioSegmentError("segment should not be nil")
+	}
+
+	if e.format == nil {
+		if err := e.writeHeader(segment); err != nil {
+			return err
+		}
+	}
+
+	seg, err := segment.ForkWithChannels(e.format.channels)
+	if err != nil {
+		return err
+	}
+	if seg, err = seg.ForkWithFrameRate(int(e.format.frameRate)); err != nil {
+		return err
+	}
+	if seg, err = seg.ForkWithSampleWidth(int(e.format.sampleWidth)); err != nil {
+		return err
+	}
+
+	data := seg.data
+	if e.to24Bit {
+		data = to24Bit(data)
+	}
+
+	var w io.Writer = e.w
+	if e.temp != nil {
+		w = e.temp
+	}
+	n, err := w.Write(data)
+	e.written += int64(n)
+	return err
+}
+
+// Close patches the sizes in the header if the writer is seekable, copies the buffered
+// data in the two-pass mode, otherwise it verifies that exactly the declared size has
+// been written.
+func (e *StreamExporter) Close() error {
+	if e.format == nil {
+		return nil
+	}
+
+	if e.temp != nil {
+		defer func() {
+			e.temp.Close()
+			os.Remove(e.temp.Name())
+			e.temp = nil
+		}()
+	}
+
+	if e.written > math.MaxUint32 {
+		return NewAudioSegmentError("%d bytes of data exceed the size limit of WAV", e.written)
+	}
+
+	if e.temp != nil {
+		if err := wav.EncodeHeader(e.w, e.waveAudio(e.format), uint32(e.written)); err != nil {
+			return err
+		}
+		if _, err := e.temp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.Copy(e.w, e.temp); err != nil {
+			return err
+		}
+	}
+
+	// RIFF chunks are word aligned.
+	if e.written%2 == 1 {
+		if _, err := e.w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+
+	if e.temp != nil {
+		return nil
+	}
+
+	if !e.seekable {
+		if e.sizeKnown && int64(e.dataSize) != e.written {
+			return NewAudioSegmentError(
+				"declared data size is %d bytes, but %d bytes are written", e.dataSize, e.written)
+		}
+		return nil
+	}
+
+	ws := e.w.(io.WriteSeeker)
+	if _, err := ws.Seek(e.headerPos, io.SeekStart); err != nil {
+		return err
+	}
+	if err := wav.EncodeHeader(ws, e.waveAudio(e.format), uint32(e.written)); err != nil {
+		return err
+	}
+	_, err := ws.Seek(0, io.SeekEnd)
+	return err
+}
+
+func (e *StreamExporter) writeHeader(segment *AudioSegment) error {
+	if e.duration < 0 {
+		return NewAudioSegmentError("duration should not be negative, got %v", e.duration)
+	}
+
+	if ws, ok := e.w.(io.WriteSeeker); ok {
+		// Some writers implement Seek but can't actually seek, e.g. os.Stdout on a pipe.
+		if pos, err := ws.Seek(0, io.SeekCurrent); err == nil {
+			e.seekable = true
+			e.headerPos = pos
+		}
+	}
+
+	// Keep the format only, not the data.
+	format, err := segment.derive(nil)
+	if err != nil {
+		return err
+	}
+	e.to24Bit = e.keepSourceSampleWidth && format.sourceSampleWidth == 3
+
+	if e.duration > 0 {
+		frameWidth := int64(format.frameWidth)
+		if e.to24Bit {
+			frameWidth = int64(format.channels) * 3
+		}
+		frames := int64(e.duration) * int64(format.frameRate) / int64(time.Second)
+		size := frames * frameWidth
+		if size > math.MaxUint32 {
+			return NewAudioSegmentError("duration %v exceeds the size limit of WAV", e.duration)
+		}
+		e.dataSize = uint32(size)
+	}
+
+	if !e.seekable && !e.sizeKnown && e.useTempFile {
+		// The header is written on Close, once the size is known.
+		temp, err := os.CreateTemp(e.tempDir, "godub-*.pcm")
+		if err != nil {
+			return err
+		}
+		e.temp = temp
+		e.format = format
+		return nil
+	}
+
+	placeholder := uint32(math.MaxUint32)
+	if e.sizeKnown {
+		placeholder = e.dataSize
+	} else if e.seekable {
+		placeholder = 0
+	}
+
+	if err := wav.EncodeHeader(e.w, e.waveAudio(format), placeholder); err != nil {
+		return err
+	}
+
+	e.format = format
+	return nil
+}
+
+// waveAudio returns the format of the header, which is 24-bit if the data is converted.
+func (e *StreamExporter) waveAudio(format *AudioSegment) *wav.WaveAudio {
+	if e.to24Bit {
+		return format.asSourceWaveAudio()
+	}
+	return format.AsWaveAudio()
+}
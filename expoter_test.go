@@ -0,0 +1,167 @@
+package godub
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamExporterSeekable(t *testing.T) {
+	part1 := newSineSegment(440, 0.5, 300, 8000, 1)
+	part2 := newSineSegment(880, 0.5, 200, 8000, 1)
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "stream.wav"))
+	assert.NoError(t, err)
+	defer f.Close()
+
+	e := NewStreamExporter(f)
+	assert.NoError(t, e.WriteSegment(part1))
+	assert.NoError(t, e.WriteSegment(part2))
+	assert.NoError(t, e.Close())
+
+	// The sizes in the header are patched
+	expected, _ := Concat(part1, part2)
+	out, err := os.ReadFile(f.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, encodeWav(t, expected), out)
+}
+
+func TestStreamExporterNonSeekable(t *testing.T) {
+	part1 := newSineSegment(440, 0.5, 300, 8000, 1)
+	// Converted to the format of the first segment
+	part2 := newSineSegment(880, 0.5, 200, 16000, 2)
+	converted, _ := part2.ForkWithChannels(1)
+	converted, _ = converted.ForkWithFrameRate(8000)
+	expected, _ := Concat(part1, converted)
+
+	var buf bytes.Buffer
+	e := NewStreamExporter(&buf).WithDataSize(uint32(expected.Len()))
+	assert.NoError(t, e.WriteSegment(part1))
+	assert.NoError(t, e.WriteSegment(part2))
+	assert.NoError(t, e.Close())
+
+	assert.Equal(t, expected.Len()+44, buf.Len())
+	assert.Equal(t, encodeWav(t, expected), buf.Bytes())
+
+	// The declared size doesn't match
+	buf.Reset()
+	e = NewStreamExporter(&buf).WithDataSize(10)
+	assert.NoError(t, e.WriteSegment(part1))
+	assert.Error(t, e.Close())
+}
+
+func TestStreamExporterTwoPass(t *testing.T) {
+	part1 := newSineSegment(440, 0.5, 300, 8000, 1)
+	part2 := newSineSegment(880, 0.5, 201, 8000, 1)
+	expected, _ := Concat(part1, part2)
+
+	// Nothing reaches the writer before Close
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	e := NewStreamExporter(&buf).WithTempFile(dir)
+	assert.NoError(t, e.WriteSegment(part1))
+	assert.NoError(t, e.WriteSegment(part2))
+	assert.Equal(t, 0, buf.Len())
+	assert.NoError(t, e.Close())
+	assert.Equal(t, encodeWav(t, expected), buf.Bytes())
+
+	// The temp file is removed
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestStreamExporterWithDuration(t *testing.T) {
+	part1 := newSineSegment(440, 0.5, 300, 8000, 1)
+	part2 := newSineSegment(880, 0.5, 200, 8000, 1)
+	expected, _ := Concat(part1, part2)
+
+	var buf bytes.Buffer
+	e := NewStreamExporter(&buf).WithDuration(500 * time.Millisecond)
+	assert.NoError(t, e.WriteSegment(part1))
+	// The header is correct from the start
+	assert.Equal(t, encodeWav(t, expected)[:44], buf.Bytes()[:44])
+	assert.NoError(t, e.WriteSegment(part2))
+	assert.NoError(t, e.Close())
+	assert.Equal(t, encodeWav(t, expected), buf.Bytes())
+
+	buf.Reset()
+	e = NewStreamExporter(&buf).WithDuration(time.Second)
+	assert.NoError(t, e.WriteSegment(part1))
+	assert.Error(t, e.Close())
+
+	// 0 leaves the size undeclared, as streamed WAV
+	buf.Reset()
+	e = NewStreamExporter(&buf).WithDuration(0)
+	assert.NoError(t, e.WriteSegment(part1))
+	assert.NoError(t, e.Close())
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff}, buf.Bytes()[40:44])
+
+	buf.Reset()
+	e = NewStreamExporter(&buf).WithDuration(-time.Second)
+	assert.Error(t, e.WriteSegment(part1))
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestStreamExporterSourceSampleWidth(t *testing.T) {
+	data := []byte{0xFF, 0xFF, 0x7F, 0x00, 0x00, 0x80, 0x01, 0x00, 0x00, 0xFF, 0xFF, 0xFF}
+	seg, _ := NewAudioSegment(data, Channels(1), SampleWidth(3), FrameWidth(3), FrameRate(4))
+	var expected bytes.Buffer
+	assert.NoError(t, NewExporter(&expected).WithDstFormat("wav").WithSourceSampleWidth(true).Export(seg))
+
+	for _, duration := range []time.Duration{0, 2 * time.Second} {
+		var buf bytes.Buffer
+		e := NewStreamExporter(&buf).WithSourceSampleWidth(true)
+		if duration > 0 {
+			e.WithDuration(duration)
+		}
+		assert.NoError(t, e.WriteSegment(seg))
+		// Segments that aren't 24-bit are converted as well
+		plain, _ := NewAudioSegment(seg.RawData(), Channels(1), SampleWidth(4), FrameWidth(4), FrameRate(4))
+		assert.NoError(t, e.WriteSegment(plain))
+		assert.NoError(t, e.Close())
+
+		loaded, err := NewLoader().Load(buf.Bytes())
+		assert.NoError(t, err)
+		assert.Equal(t, uint16(3), loaded.SourceSampleWidth())
+		joined, _ := seg.Append(seg)
+		assert.Equal(t, joined.RawData(), loaded.RawData())
+		assert.Equal(t, expected.Bytes()[20:36], buf.Bytes()[20:36])
+	}
+}
+
+func TestExportChunksOnSilence(t *testing.T) {
+	gap, _ := NewSilentAudioSegmentWith(600, 8000, 2, 1)
+	tone := newSineSegment(440, 0.5, 300, 8000, 1)
+	seg, _ := Concat(tone, gap, tone, gap, tone)
+	opts := SplitOnSilenceOptions{MinSilenceLen: 300, SilenceThresh: -40, KeepSilenceMode: KeepSilenceHalf, SeekStep: 1}
+
+	dir := t.TempDir()
+	paths, err := ExportChunksOnSilence(seg, dir, "lecture", "wav", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "lecture_001.wav"),
+		filepath.Join(dir, "lecture_002.wav"),
+		filepath.Join(dir, "lecture_003.wav"),
+	}, paths)
+
+	chunks, _, _ := SplitOnSilenceWithOptions(seg, opts)
+	for i, p := range paths {
+		out, err := os.ReadFile(p)
+		assert.NoError(t, err)
+		assert.Equal(t, encodeWav(t, chunks[i]), out)
+	}
+
+	// The second file can't be created, the first one is removed
+	dir = t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "lecture_002.wav"), 0755))
+	_, err = ExportChunksOnSilence(seg, dir, "lecture", "wav", opts)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "lecture_001.wav"))
+	assert.True(t, os.IsNotExist(err))
+	assert.DirExists(t, filepath.Join(dir, "lecture_002.wav"))
+}
@@ -0,0 +1,97 @@
+package godub
+
+import "math"
+
+// FadeCurve is the shape of the gain of fades, from silence at t = 0 to full volume at t = 1.
+type FadeCurve int
+
+const (
+	// FadeLinear ramps the amplitude linearly, it's the default. It sounds abrupt at the
+	// quiet end, since loudness is perceived logarithmically.
+	FadeLinear FadeCurve = iota
+	// FadeLogarithmic ramps the gain linearly in dB, from fadeFloor to 0dB, which sounds
+	// like an even change of loudness.
+	FadeLogarithmic
+	// FadeEqualPower ramps the amplitude by sin(t·π/2). The fade in and its mirrored fade
+	// out always sum to constant power, so it's the curve for crossfades.
+	FadeEqualPower
+	// FadeSCurve ramps the amplitude by (1-cos(t·π))/2, slow at both ends and fast in the
+	// middle.
+	FadeSCurve
+)
+
+// fadeFloor is the gain (dB) of FadeLogarithmic at the silent end.
+const fadeFloor Volume = -60
+
+func (c FadeCurve) validate() error {
+	if c < FadeLinear || c > FadeSCurve {
+		return NewAudioSegmentError("invalid fade curve %d", c)
+	}
+	return nil
+}
+
+// fadeIn returns the amplitude ratio at t in [0, 1].
+func (c FadeCurve) fadeIn(t float64) float64 {
+	switch c {
+	case FadeLogarithmic:
+		if t <= 0 {
+			return 0
+		}
+		return (fadeFloor * Volume(1-t)).ToRatio(true)
+	case FadeEqualPower:
+		return math.Sin(t * math.Pi / 2)
+	case FadeSCurve:
+		return (1 - math.Cos(t*math.Pi)) / 2
+	default:
+		return t
+	}
+}
+
+// fadeOut is the mirror of fadeIn.
+func (c FadeCurve) fadeOut(t float64) float64 {
+	return c.fadeIn(1 - t)
+}
+
+// FadeIn 在音频开头duration毫秒内从静音淡入
+//
+// 参数:
+//   - duration: 淡入时长(毫秒),超过音频长度时整段淡入
+//   - curve: 淡化曲线,默认(零值)为FadeLinear
+//
+// 说明:
+//   - 增益每1ms计算一次,与GainRamp相同
+//   - 线性淡化在安静的一端听起来比较突兀,FadeLogarithmic或FadeEqualPower更符合听感,
+//     交叉淡化应使用FadeEqualPower,参见CrossfadeWithCurve
+func (seg *AudioSegment) FadeIn(duration int64, curve FadeCurve) (*AudioSegment, error) {
+	return seg.fade(0, duration, curve.fadeIn, curve)
+}
+
+// FadeOut 在音频结尾duration毫秒内淡出到静音,参见FadeIn
+func (seg *AudioSegment) FadeOut(duration int64, curve FadeCurve) (*AudioSegment, error) {
+	return seg.fade(max64(seg.Duration()-duration, 0), seg.Duration(), curve.fadeOut, curve)
+}
+
+// fade applies the gain to [start, end) milliseconds.
+func (seg *AudioSegment) fade(start, end int64, ratio func(float64) float64, curve FadeCurve) (*AudioSegment, error) {
+	if err := curve.validate(); err != nil {
+		return nil, err
+	}
+
+	if start < 0 || end < start {
+		return nil, NewAudioSegmentError("fade duration should not be negative")
+	}
+
+	frameCount := int(seg.FrameCount())
+	startFrame := min(int(start*int64(seg.frameRate)/1000), frameCount)
+	endFrame := min(int(end*int64(seg.frameRate)/1000), frameCount)
+	if seg.Duration() <= end {
+		endFrame = frameCount
+	}
+
+	data := make([]byte, len(seg.data))
+	copy(data, seg.data)
+	if err := seg.applyChunkGain(data, startFrame, endFrame, ratio); err != nil {
+		return nil, err
+	}
+	return seg.derive(data)
+}
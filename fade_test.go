@@ -0,0 +1,72 @@
+package godub
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wonglyxng/godub/audioop"
+)
+
+func TestFadeCurve(t *testing.T) {
+	for _, c := range []FadeCurve{FadeLinear, FadeLogarithmic, FadeEqualPower, FadeSCurve} {
+		assert.InDelta(t, 0, c.fadeIn(0), 1e-9)
+		assert.InDelta(t, 1, c.fadeIn(1), 1e-9)
+		assert.InDelta(t, c.fadeIn(0.3), c.fadeOut(0.7), 1e-9)
+	}
+
+	assert.InDelta(t, 0.5, FadeLinear.fadeIn(0.5), 1e-9)
+	assert.InDelta(t, math.Sqrt(0.5), FadeEqualPower.fadeIn(0.5), 1e-9)
+	assert.InDelta(t, 0.5, FadeSCurve.fadeIn(0.5), 1e-9)
+	// -30dB in the middle
+	assert.InDelta(t, 0.0316, FadeLogarithmic.fadeIn(0.5), 1e-4)
+
+	// Equal power sums to constant power
+	for _, x := range []float64{0.1, 0.5, 0.9} {
+		in, out := FadeEqualPower.fadeIn(x), FadeEqualPower.fadeOut(x)
+		assert.InDelta(t, 1, in*in+out*out, 1e-9)
+	}
+}
+
+func TestFadeInOut(t *testing.T) {
+	seg := newSegment16(1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000)
+
+	// The first ms gets 0 and the last one of the fade gets full volume
+	faded, err := seg.FadeIn(5, FadeLinear)
+	assert.NoError(t, err)
+	samples, _ := audioop.GetSamples(faded.RawData(), 2)
+	assert.Equal(t, []int32{0, 250, 500, 750, 1000, 1000, 1000, 1000, 1000, 1000}, samples)
+
+	faded, err = seg.FadeOut(5, FadeSCurve)
+	assert.NoError(t, err)
+	samples, _ = audioop.GetSamples(faded.RawData(), 2)
+	assert.Equal(t, []int32{1000, 1000, 1000, 1000, 1000, 1000, 853, 499, 146, 0}, samples)
+
+	// Longer than the segment
+	faded, err = seg.FadeIn(100, FadeEqualPower)
+	assert.NoError(t, err)
+	assert.Equal(t, seg.Len(), faded.Len())
+
+	_, err = seg.FadeIn(-1, FadeLinear)
+	assert.Error(t, err)
+	_, err = seg.FadeOut(5, FadeCurve(10))
+	assert.Error(t, err)
+}
+
+func TestCrossfadeWithCurve(t *testing.T) {
+	tone1 := newSineSegment(440, 0.5, 500, 8000, 1)
+	tone2 := newSineSegment(1230, 0.5, 500, 8000, 1)
+
+	// Linear dips in the middle of the overlap, equal power doesn't
+	linear, err := CrossfadeWithCurve(tone1, tone2, 500, FadeLinear)
+	assert.NoError(t, err)
+	equalPower, err := Crossfade(tone1, tone2, 500)
+	assert.NoError(t, err)
+	linearMiddle, _ := linear.Slice(200, 300)
+	equalPowerMiddle, _ := equalPower.Slice(200, 300)
+	assert.InDelta(t, tone1.RMS()*math.Sqrt(0.5), linearMiddle.RMS(), tone1.RMS()*0.05)
+	assert.InDelta(t, tone1.RMS(), equalPowerMiddle.RMS(), tone1.RMS()*0.05)
+
+	_, err = CrossfadeWithCurve(tone1, tone2, 100, FadeCurve(-1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package flac
+
+import "math/bits"
+
+// bitReader reads big-endian bit fields from a byte slice. Reading beyond the end sets
+// err and returns zeros, so that a sequence of fields can be checked once.
+type bitReader struct {
+	buf []byte
+	pos int // in bits
+	err error
+}
+
+func (r *bitReader) eof() bool {
+	return r.pos >= len(r.buf)*8
+}
+
+// bytePos returns the position in bytes, the reader must be byte aligned.
+func (r *bitReader) bytePos() int {
+	return r.pos / 8
+}
+
+// align skips to the next byte boundary.
+func (r *bitReader) align() {
+	r.pos = (r.pos + 7) &^ 7
+}
+
+// readBits reads an unsigned field of n (<= 64) bits.
+func (r *bitReader) readBits(n uint) uint64 {
+	if n == 0 || r.err != nil {
+		return 0
+	}
+	if r.pos+int(n) > len(r.buf)*8 {
+		r.pos = len(r.buf) * 8
+		r.err = DecodeError("unexpected end of flac data")
+		return 0
+	}
+
+	var v uint64
+	for n > 0 {
+		offset := uint(r.pos & 7)
+		available := 8 - offset
+		take := min(available, n)
+		b := uint64(r.buf[r.pos>>3]>>(available-take)) & (1<<take - 1)
+		v = v<<take | b
+		n -= take
+		r.pos += int(take)
+	}
+	return v
+}
+
+// readSigned reads a two's complement field of n (<= 64) bits.
+func (r *bitReader) readSigned(n uint) int64 {
+	if n == 0 {
+		return 0
+	}
+	return int64(r.readBits(n)<<(64-n)) >> (64 - n)
+}
+
+// readUnary counts the zero bits before the next one bit, and skips them all.
+func (r *bitReader) readUnary() uint64 {
+	var n uint64
+	for r.err == nil {
+		if r.eof() {
+			r.err = DecodeError("unexpected end of flac data")
+			break
+		}
+
+		offset := r.pos & 7
+		b := r.buf[r.pos>>3] << offset
+		if b == 0 {
+			n += uint64(8 - offset)
+			r.pos += 8 - offset
+			continue
+		}
+
+		zeros := bits.LeadingZeros8(b)
+		n += uint64(zeros)
+		r.pos += zeros + 1
+		return n
+	}
+	return 0
+}
+
+// skipUTF8 skips the frame or sample number, which is coded like UTF-8 in 1-7 bytes.
+func (r *bitReader) skipUTF8() {
+	first := r.readBits(8)
+	for mask := uint64(0x80); first&mask != 0 && mask != 0x01; mask >>= 1 {
+		if mask != 0x80 {
+			r.readBits(8)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package flac
+
+// crc8 is the CRC of frame headers, polynomial x^8 + x^2 + x + 1, initialized with 0.
+func crc8(data []byte) uint8 {
+	var crc uint8
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16 is the CRC of frames, polynomial x^16 + x^15 + x^2 + 1, initialized with 0.
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
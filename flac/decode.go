@@ -0,0 +1,447 @@
+package flac
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/wonglyxng/godub/wav"
+)
+
+// Decode decodes the whole FLAC stream into little-endian PCM WAV audio. Samples of
+// 8-bit audio are unsigned like in WAV, samples of other depths that aren't a multiple
+// of 8 bits (e.g. 12 or 20) are left-justified in the next wider container.
+func Decode(r io.Reader) (*wav.WaveAudio, error) {
+	d, err := NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Decode()
+}
+
+type Decoder struct {
+	buffer     []byte
+	streamInfo StreamInfo
+	// framesPos is the position of the first audio frame.
+	framesPos int
+}
+
+// NewDecoder reads the whole stream and decodes the metadata blocks.
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Decoder{buffer: buf}
+	if err = d.decodeMetadata(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// StreamInfo returns the STREAMINFO metadata of the stream.
+func (d *Decoder) StreamInfo() StreamInfo {
+	return d.streamInfo
+}
+
+func (d *Decoder) Decode() (*wav.WaveAudio, error) {
+	return d.DecodeFrames(0)
+}
+
+// DecodeFrames is like Decode but stops once maxFrames sample frames (samples per
+// channel) are decoded, the result may exceed it by less than a block. There's no
+// limit if maxFrames <= 0.
+func (d *Decoder) DecodeFrames(maxFrames int64) (*wav.WaveAudio, error) {
+	info := d.streamInfo
+	bps := uint(info.BitsPerSample)
+	width := int(bps+7) / 8
+
+	capacity := int64(info.TotalSamples)
+	if maxFrames > 0 && (capacity == 0 || capacity > maxFrames) {
+		capacity = maxFrames
+	}
+	// Don't trust the header too much, the compression ratio of FLAC is small.
+	capacity = min(capacity, int64(len(d.buffer))*4/int64(width*int(info.Channels)))
+	data := make([]byte, 0, capacity*int64(width*int(info.Channels)))
+
+	r := &bitReader{buf: d.buffer, pos: d.framesPos * 8}
+	var decoded uint64
+	for !r.eof() {
+		// Trailing data such as an ID3v1 tag follows the last frame.
+		if info.TotalSamples > 0 && decoded >= info.TotalSamples {
+			break
+		}
+		if maxFrames > 0 && decoded >= uint64(maxFrames) {
+			break
+		}
+
+		channels, err := d.decodeFrame(r)
+		if err != nil {
+			return nil, err
+		}
+		data = appendSamples(data, channels, bps)
+		decoded += uint64(len(channels[0]))
+	}
+
+	audio := &wav.WaveAudio{
+		Format:        wav.AudioFormatPCM,
+		Channels:      info.Channels,
+		SampleRate:    info.SampleRate,
+		BitsPerSample: uint16(width * 8),
+		RawData:       data,
+	}
+	if int(bps) != width*8 {
+		audio.ValidBitsPerSample = uint16(bps)
+	}
+	return audio, nil
+}
+
+func (d *Decoder) decodeMetadata() error {
+	if len(d.buffer) < 4 || !bytes.Equal(d.buffer[0:4], FlacHeader) {
+		return DecodeError("Could not find fLaC header in flac data")
+	}
+
+	pos := 4
+	found := false
+	for {
+		if pos+4 > len(d.buffer) {
+			return DecodeError("Could not find audio frames in flac data")
+		}
+		last := d.buffer[pos]&0x80 != 0
+		blockType := d.buffer[pos] & 0x7F
+		size := int(d.buffer[pos+1])<<16 | int(d.buffer[pos+2])<<8 | int(d.buffer[pos+3])
+		pos += 4
+		if pos+size > len(d.buffer) {
+			return DecodeError("Truncated metadata block in flac data")
+		}
+
+		if blockType == metadataStreamInfo {
+			if size < streamInfoSize {
+				return DecodeError("Invalid STREAMINFO block in flac data")
+			}
+			d.decodeStreamInfo(d.buffer[pos : pos+size])
+			found = true
+		}
+
+		pos += size
+		if last {
+			break
+		}
+	}
+
+	if !found {
+		return DecodeError("Could not find STREAMINFO block in flac data")
+	}
+
+	info := d.streamInfo
+	if info.SampleRate == 0 || info.BitsPerSample < 4 || info.BitsPerSample > 32 {
+		return DecodeError(fmt.Sprintf(
+			"unsupported flac stream of %dHz, %d bits per sample", info.SampleRate, info.BitsPerSample))
+	}
+
+	d.framesPos = pos
+	return nil
+}
+
+func (d *Decoder) decodeStreamInfo(block []byte) {
+	r := &bitReader{buf: block}
+	d.streamInfo = StreamInfo{
+		MinBlockSize:  uint16(r.readBits(16)),
+		MaxBlockSize:  uint16(r.readBits(16)),
+		MinFrameSize:  uint32(r.readBits(24)),
+		MaxFrameSize:  uint32(r.readBits(24)),
+		SampleRate:    uint32(r.readBits(20)),
+		Channels:      uint16(r.readBits(3)) + 1,
+		BitsPerSample: uint16(r.readBits(5)) + 1,
+		TotalSamples:  r.readBits(36),
+	}
+	copy(d.streamInfo.MD5[:], block[18:34])
+}
+
+// decodeFrame decodes a frame, and returns the samples of each channel.
+func (d *Decoder) decodeFrame(r *bitReader) ([][]int64, error) {
+	start := r.bytePos()
+
+	if r.readBits(14) != frameSyncCode {
+		return nil, DecodeError(fmt.Sprintf("invalid frame sync code at %d in flac data", start))
+	}
+	r.readBits(2) // reserved, blocking strategy
+	blockSizeCode := r.readBits(4)
+	sampleRateCode := r.readBits(4)
+	channelAssignment := r.readBits(4)
+	sampleSizeCode := r.readBits(3)
+	r.readBits(1) // reserved
+	r.skipUTF8()
+
+	blockSize := 0
+	switch {
+	case blockSizeCode == 1:
+		blockSize = 192
+	case blockSizeCode >= 2 && blockSizeCode <= 5:
+		blockSize = 576 << (blockSizeCode - 2)
+	case blockSizeCode == 6:
+		blockSize = int(r.readBits(8)) + 1
+	case blockSizeCode == 7:
+		blockSize = int(r.readBits(16)) + 1
+	case blockSizeCode >= 8:
+		blockSize = 256 << (blockSizeCode - 8)
+	default:
+		return nil, DecodeError("reserved block size in flac frame")
+	}
+
+	switch sampleRateCode {
+	case 12:
+		r.readBits(8)
+	case 13, 14:
+		r.readBits(16)
+	case 15:
+		return nil, DecodeError("invalid sample rate in flac frame")
+	}
+
+	bps := uint(d.streamInfo.BitsPerSample)
+	if sampleSizeCode != 0 {
+		bps = [8]uint{0, 8, 12, 0, 16, 20, 24, 32}[sampleSizeCode]
+		if bps == 0 {
+			return nil, DecodeError("reserved sample size in flac frame")
+		}
+	}
+
+	channels := int(channelAssignment) + 1
+	if channelAssignment >= 8 && channelAssignment <= 10 {
+		channels = 2
+	} else if channelAssignment > 10 {
+		return nil, DecodeError("reserved channel assignment in flac frame")
+	}
+	if channels != int(d.streamInfo.Channels) || bps != uint(d.streamInfo.BitsPerSample) {
+		return nil, DecodeError("the format of the flac frame differs from STREAMINFO")
+	}
+
+	headerEnd := r.bytePos()
+	if crc := uint8(r.readBits(8)); r.err == nil && crc != crc8(d.buffer[start:headerEnd]) {
+		return nil, DecodeError("flac frame header CRC mismatch")
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	samples := make([][]int64, channels)
+	for c := range samples {
+		// The side channel has one more bit.
+		sideChannel := (channelAssignment == 8 || channelAssignment == 10) && c == 1 ||
+			channelAssignment == 9 && c == 0
+		subframeBps := bps
+		if sideChannel {
+			subframeBps++
+		}
+
+		s, err := decodeSubframe(r, blockSize, subframeBps)
+		if err != nil {
+			return nil, err
+		}
+		samples[c] = s
+	}
+
+	r.align()
+	frameEnd := r.bytePos()
+	if crc := uint16(r.readBits(16)); r.err == nil && crc != crc16(d.buffer[start:frameEnd]) {
+		return nil, DecodeError("flac frame CRC mismatch")
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	decorrelate(samples, channelAssignment)
+	return samples, nil
+}
+
+func decodeSubframe(r *bitReader, blockSize int, bps uint) ([]int64, error) {
+	if r.readBits(1) != 0 {
+		return nil, DecodeError("invalid subframe padding in flac frame")
+	}
+	subframeType := int(r.readBits(6))
+
+	var wasted uint
+	if r.readBits(1) == 1 {
+		wasted = uint(r.readUnary()) + 1
+		if wasted >= bps {
+			return nil, DecodeError("invalid wasted bits in flac subframe")
+		}
+		bps -= wasted
+	}
+
+	samples := make([]int64, blockSize)
+	switch {
+	case subframeType == 0: // CONSTANT
+		v := r.readSigned(bps)
+		for i := range samples {
+			samples[i] = v
+		}
+	case subframeType == 1: // VERBATIM
+		for i := range samples {
+			samples[i] = r.readSigned(bps)
+		}
+	case subframeType >= 8 && subframeType <= 12: // FIXED
+		order := subframeType - 8
+		if order > blockSize {
+			return nil, DecodeError("invalid predictor order in flac subframe")
+		}
+		for i := 0; i < order; i++ {
+			samples[i] = r.readSigned(bps)
+		}
+		if err := decodeResidual(r, samples, order); err != nil {
+			return nil, err
+		}
+		predictFixed(samples, order)
+	case subframeType >= 32: // LPC
+		order := subframeType - 31
+		if order > blockSize {
+			return nil, DecodeError("invalid predictor order in flac subframe")
+		}
+		for i := 0; i < order; i++ {
+			samples[i] = r.readSigned(bps)
+		}
+		precision := uint(r.readBits(4)) + 1
+		if precision == 16 {
+			return nil, DecodeError("invalid coefficient precision in flac subframe")
+		}
+		shift := r.readSigned(5)
+		if shift < 0 {
+			return nil, DecodeError("negative coefficient shift in flac subframe")
+		}
+		coefficients := make([]int64, order)
+		for i := range coefficients {
+			coefficients[i] = r.readSigned(precision)
+		}
+		if err := decodeResidual(r, samples, order); err != nil {
+			return nil, err
+		}
+		predictLPC(samples, coefficients, uint(shift))
+	default:
+		return nil, DecodeError(fmt.Sprintf("reserved subframe type %d in flac frame", subframeType))
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if wasted > 0 {
+		for i := range samples {
+			samples[i] <<= wasted
+		}
+	}
+	return samples, nil
+}
+
+// decodeResidual decodes the Rice coded residual into samples[order:].
+func decodeResidual(r *bitReader, samples []int64, order int) error {
+	method := r.readBits(2)
+	if method > 1 {
+		return DecodeError("reserved residual coding method in flac subframe")
+	}
+	paramBits := uint(4 + method)
+	escape := uint64(1)<<paramBits - 1
+
+	partitionOrder := uint(r.readBits(4))
+	partitionSize := len(samples) >> partitionOrder
+	if partitionSize<<partitionOrder != len(samples) || partitionSize < order {
+		return DecodeError("invalid residual partition order in flac subframe")
+	}
+
+	i := order
+	for p := 0; p < 1<<partitionOrder; p++ {
+		n := partitionSize
+		if p == 0 {
+			n -= order
+		}
+
+		param := r.readBits(paramBits)
+		if param == escape {
+			// The partition is stored unencoded.
+			rawBits := uint(r.readBits(5))
+			for j := 0; j < n; j++ {
+				samples[i] = r.readSigned(rawBits)
+				i++
+			}
+		} else {
+			for j := 0; j < n; j++ {
+				u := r.readUnary()<<param | r.readBits(uint(param))
+				samples[i] = int64(u>>1) ^ -int64(u&1)
+				i++
+			}
+		}
+
+		if r.err != nil {
+			return r.err
+		}
+	}
+	return nil
+}
+
+// predictFixed restores the samples from the residual of the fixed polynomial predictor.
+func predictFixed(s []int64, order int) {
+	for i := order; i < len(s); i++ {
+		switch order {
+		case 1:
+			s[i] += s[i-1]
+		case 2:
+			s[i] += 2*s[i-1] - s[i-2]
+		case 3:
+			s[i] += 3*s[i-1] - 3*s[i-2] + s[i-3]
+		case 4:
+			s[i] += 4*s[i-1] - 6*s[i-2] + 4*s[i-3] - s[i-4]
+		}
+	}
+}
+
+// predictLPC restores the samples from the residual of the linear predictor.
+func predictLPC(s []int64, coefficients []int64, shift uint) {
+	for i := len(coefficients); i < len(s); i++ {
+		var sum int64
+		for j, c := range coefficients {
+			sum += c * s[i-1-j]
+		}
+		s[i] += sum >> shift
+	}
+}
+
+// decorrelate restores left and right from the stereo coding of the channel assignment.
+func decorrelate(samples [][]int64, channelAssignment uint64) {
+	switch channelAssignment {
+	case 8: // left/side
+		for i, side := range samples[1] {
+			samples[1][i] = samples[0][i] - side
+		}
+	case 9: // side/right
+		for i, side := range samples[0] {
+			samples[0][i] = side + samples[1][i]
+		}
+	case 10: // mid/side
+		for i, side := range samples[1] {
+			mid := samples[0][i]<<1 | side&1
+			samples[0][i] = (mid + side) >> 1
+			samples[1][i] = (mid - side) >> 1
+		}
+	}
+}
+
+// appendSamples interleaves the samples into little-endian PCM of the container width.
+func appendSamples(data []byte, channels [][]int64, bps uint) []byte {
+	width := int(bps+7) / 8
+	shift := uint(width*8) - bps
+	for i := range channels[0] {
+		for _, samples := range channels {
+			v := samples[i] << shift
+			if width == 1 {
+				// 8-bit WAV is unsigned
+				v += 128
+			}
+			for b := 0; b < width; b++ {
+				data = append(data, byte(v>>(8*b)))
+			}
+		}
+	}
+	return data
+}
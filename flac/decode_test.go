@@ -0,0 +1,361 @@
+package flac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bitWriter is the counterpart of bitReader to build test streams.
+type bitWriter struct {
+	buf   []byte
+	nbits uint
+}
+
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	for i := int(n) - 1; i >= 0; i-- {
+		if w.nbits%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if v>>uint(i)&1 == 1 {
+			w.buf[len(w.buf)-1] |= 0x80 >> (w.nbits % 8)
+		}
+		w.nbits++
+	}
+}
+
+func (w *bitWriter) writeSigned(v int64, n uint) {
+	w.writeBits(uint64(v)&(1<<n-1), n)
+}
+
+func (w *bitWriter) writeUnary(n uint64) {
+	for i := uint64(0); i < n; i++ {
+		w.writeBits(0, 1)
+	}
+	w.writeBits(1, 1)
+}
+
+func (w *bitWriter) align() {
+	w.nbits = (w.nbits + 7) &^ 7
+}
+
+// writeResidual Rice codes the residual in 2 partitions, the second one is escaped.
+func (w *bitWriter) writeResidual(residual []int64, order int, param uint, bps uint) {
+	w.writeBits(0, 2) // 4-bit parameters
+	w.writeBits(1, 4) // partition order
+	half := (len(residual) + order) / 2
+	w.writeBits(uint64(param), 4)
+	for _, v := range residual[:half-order] {
+		u := uint64(v<<1) ^ uint64(v>>63)
+		w.writeUnary(u >> param)
+		w.writeBits(u&(1<<param-1), param)
+	}
+	w.writeBits(0xF, 4)
+	w.writeBits(uint64(bps), 5)
+	for _, v := range residual[half-order:] {
+		w.writeSigned(v, bps)
+	}
+}
+
+const (
+	subframeConstant = iota
+	subframeVerbatim
+	subframeFixed
+	subframeLPC
+)
+
+// writeSubframe codes the samples with the type, the LPC coefficients are those of
+// the fixed predictor of order 2.
+func (w *bitWriter) writeSubframe(samples []int64, bps uint, subframeType int, wasted uint) {
+	if wasted > 0 {
+		shifted := make([]int64, len(samples))
+		for i, v := range samples {
+			shifted[i] = v >> wasted
+		}
+		samples = shifted
+	}
+
+	header := func(t uint64) {
+		w.writeBits(0, 1)
+		w.writeBits(t, 6)
+		if wasted > 0 {
+			w.writeBits(1, 1)
+			w.writeUnary(uint64(wasted - 1))
+		} else {
+			w.writeBits(0, 1)
+		}
+	}
+	bps -= wasted
+
+	switch subframeType {
+	case subframeConstant:
+		header(0)
+		w.writeSigned(samples[0], bps)
+	case subframeVerbatim:
+		header(1)
+		for _, v := range samples {
+			w.writeSigned(v, bps)
+		}
+	case subframeFixed, subframeLPC:
+		order := 2
+		if subframeType == subframeFixed {
+			header(8 + uint64(order))
+		} else {
+			header(32 + uint64(order-1))
+		}
+		for _, v := range samples[:order] {
+			w.writeSigned(v, bps)
+		}
+		if subframeType == subframeLPC {
+			w.writeBits(3, 4)   // precision 4
+			w.writeSigned(0, 5) // shift
+			w.writeSigned(2, 4)
+			w.writeSigned(-1, 4)
+		}
+		residual := make([]int64, 0, len(samples)-order)
+		for i := order; i < len(samples); i++ {
+			residual = append(residual, samples[i]-(2*samples[i-1]-samples[i-2]))
+		}
+		w.writeResidual(residual, order, 3, bps+2)
+	}
+}
+
+// encodeFLAC builds a FLAC stream, the frames cycle through the stereo channel
+// assignments and subframe types.
+func encodeFLAC(channels [][]int64, bps uint, sampleRate uint32, blockSize int, wasted uint) []byte {
+	w := &bitWriter{}
+	w.buf = append(w.buf, FlacHeader...)
+	w.nbits = 32
+
+	// An unknown metadata block before STREAMINFO is skipped
+	w.writeBits(4, 8)
+	w.writeBits(3, 24)
+	w.writeBits(0xABCDEF, 24)
+
+	frameCount := len(channels[0])
+	w.writeBits(0x80|metadataStreamInfo, 8)
+	w.writeBits(streamInfoSize, 24)
+	w.writeBits(uint64(blockSize), 16)
+	w.writeBits(uint64(blockSize), 16)
+	w.writeBits(0, 24)
+	w.writeBits(0, 24)
+	w.writeBits(uint64(sampleRate), 20)
+	w.writeBits(uint64(len(channels)-1), 3)
+	w.writeBits(uint64(bps-1), 5)
+	w.writeBits(uint64(frameCount), 36)
+	w.writeBits(0, 64)
+	w.writeBits(0, 64)
+
+	sampleSizeCodes := map[uint]uint64{8: 1, 12: 2, 16: 4, 20: 5, 24: 6, 32: 7}
+	for frame, pos := 0, 0; pos < frameCount; frame, pos = frame+1, pos+blockSize {
+		n := min(blockSize, frameCount-pos)
+		block := make([][]int64, len(channels))
+		for c := range channels {
+			block[c] = channels[c][pos : pos+n]
+		}
+
+		assignment := uint64(len(channels) - 1)
+		if len(channels) == 2 {
+			assignment = []uint64{1, 8, 9, 10}[frame%4]
+		}
+
+		start := len(w.buf)
+		w.writeBits(frameSyncCode, 14)
+		w.writeBits(0, 2)
+		if n == 256 {
+			w.writeBits(8, 4)
+		} else {
+			w.writeBits(7, 4)
+		}
+		w.writeBits(0, 4)
+		w.writeBits(assignment, 4)
+		w.writeBits(sampleSizeCodes[bps], 3)
+		w.writeBits(0, 1)
+		w.writeBits(uint64(frame), 8)
+		if n != 256 {
+			w.writeBits(uint64(n-1), 16)
+		}
+		w.writeBits(uint64(crc8(w.buf[start:])), 8)
+
+		subframes := block
+		if len(channels) == 2 {
+			left, right := block[0], block[1]
+			side := make([]int64, n)
+			mid := make([]int64, n)
+			for i := range side {
+				side[i] = left[i] - right[i]
+				mid[i] = (left[i] + right[i]) >> 1
+			}
+			switch assignment {
+			case 8:
+				subframes = [][]int64{left, side}
+			case 9:
+				subframes = [][]int64{side, right}
+			case 10:
+				subframes = [][]int64{mid, side}
+			}
+		}
+
+		for c, samples := range subframes {
+			subframeBps := bps
+			if assignment == 8 && c == 1 || assignment == 9 && c == 0 || assignment == 10 && c == 1 {
+				subframeBps++
+			}
+			subframeType := (frame + c) % 4
+			subframeWasted := uint(0)
+			if subframeBps == bps {
+				subframeWasted = wasted
+			}
+
+			constant := true
+			for _, v := range samples {
+				constant = constant && v == samples[0]
+			}
+			if subframeType == subframeConstant && !constant {
+				subframeType = subframeVerbatim
+			}
+			w.writeSubframe(samples, subframeBps, subframeType, subframeWasted)
+		}
+
+		w.align()
+		crc := crc16(w.buf[start:])
+		w.writeBits(uint64(crc), 16)
+	}
+	return w.buf
+}
+
+// sineSamples generates a sine at the amplitude (ratio to full scale) of bps-bit samples.
+func sineSamples(freq, amplitude float64, count int, sampleRate uint32, bps uint) []int64 {
+	samples := make([]int64, count)
+	full := math.Pow(2, float64(bps-1))
+	for i := range samples {
+		samples[i] = int64(amplitude * full * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+	}
+	return samples
+}
+
+// pcm packs interleaved little-endian samples of the width in bytes.
+func pcm(channels [][]int64, width int) []byte {
+	var buf bytes.Buffer
+	for i := range channels[0] {
+		for _, samples := range channels {
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], uint64(samples[i]))
+			buf.Write(b[:width])
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestCRC(t *testing.T) {
+	assert.Equal(t, uint8(0xF4), crc8([]byte("123456789")))
+	assert.Equal(t, uint16(0xFEE8), crc16([]byte("123456789")))
+}
+
+func TestBitReader(t *testing.T) {
+	r := &bitReader{buf: []byte{0xC3, 0xA9, 0x42, 0x0F, 0x00, 0x01}}
+	r.skipUTF8()
+	assert.Equal(t, uint64(0x4), r.readBits(4))
+	assert.Equal(t, int64(2), r.readSigned(4))
+	assert.Equal(t, int64(15), r.readSigned(8))
+	assert.NoError(t, r.err)
+
+	r = &bitReader{buf: []byte{0x00, 0x01, 0x80}}
+	assert.Equal(t, uint64(15), r.readUnary())
+	assert.Equal(t, uint64(1), r.readBits(1))
+	assert.False(t, r.eof())
+	r.readBits(16)
+	assert.Error(t, r.err)
+}
+
+func TestDecodeStereo16(t *testing.T) {
+	left := sineSamples(440, 0.5, 1300, 44100, 16)
+	right := sineSamples(1000, 0.25, 1300, 44100, 16)
+	for i := 1024; i < 1280; i++ {
+		// The silent block is coded as a CONSTANT subframe
+		left[i], right[i] = 0, 0
+	}
+	stream := encodeFLAC([][]int64{left, right}, 16, 44100, 256, 0)
+
+	audio, err := Decode(bytes.NewReader(stream))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(2), audio.Channels)
+	assert.Equal(t, uint32(44100), audio.SampleRate)
+	assert.Equal(t, uint16(16), audio.BitsPerSample)
+	assert.Equal(t, uint16(0), audio.ValidBitsPerSample)
+	assert.Equal(t, pcm([][]int64{left, right}, 2), audio.RawData)
+
+	d, err := NewDecoder(bytes.NewReader(stream))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1300), d.StreamInfo().TotalSamples)
+	assert.Equal(t, uint16(256), d.StreamInfo().MaxBlockSize)
+
+	// Decoding stops at the block containing the limit
+	limited, err := d.DecodeFrames(300)
+	assert.NoError(t, err)
+	assert.Equal(t, pcm([][]int64{left[:512], right[:512]}, 2), limited.RawData)
+
+	// Trailing data is ignored
+	audio, err = Decode(bytes.NewReader(append(stream, []byte("TAG")...)))
+	assert.NoError(t, err)
+	assert.Equal(t, pcm([][]int64{left, right}, 2), audio.RawData)
+}
+
+func TestDecodeStereo24(t *testing.T) {
+	// Samples are multiples of 4, the subframes have 2 wasted bits
+	left := sineSamples(440, 0.9, 700, 48000, 24)
+	right := sineSamples(220, -0.9, 700, 48000, 24)
+	for i := range left {
+		left[i] &^= 3
+		right[i] &^= 3
+	}
+
+	audio, err := Decode(bytes.NewReader(encodeFLAC([][]int64{left, right}, 24, 48000, 256, 2)))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(24), audio.BitsPerSample)
+	assert.Equal(t, pcm([][]int64{left, right}, 3), audio.RawData)
+}
+
+func TestDecodeLeftJustified(t *testing.T) {
+	samples := sineSamples(440, 0.5, 300, 8000, 12)
+	audio, err := Decode(bytes.NewReader(encodeFLAC([][]int64{samples}, 12, 8000, 256, 0)))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(16), audio.BitsPerSample)
+	assert.Equal(t, uint16(12), audio.ValidBitsPerSample)
+
+	shifted := make([]int64, len(samples))
+	for i, v := range samples {
+		shifted[i] = v << 4
+	}
+	assert.Equal(t, pcm([][]int64{shifted}, 2), audio.RawData)
+
+	// 8-bit is unsigned
+	samples = sineSamples(440, 0.5, 300, 8000, 8)
+	audio, err = Decode(bytes.NewReader(encodeFLAC([][]int64{samples}, 8, 8000, 256, 0)))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(8), audio.BitsPerSample)
+	for i, v := range samples {
+		assert.Equal(t, byte(v+128), audio.RawData[i])
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	samples := sineSamples(440, 0.5, 300, 8000, 16)
+	stream := encodeFLAC([][]int64{samples}, 16, 8000, 256, 0)
+
+	_, err := Decode(bytes.NewReader([]byte("RIFF")))
+	assert.Error(t, err)
+
+	// Truncated
+	_, err = Decode(bytes.NewReader(stream[:len(stream)-10]))
+	assert.Error(t, err)
+
+	// Corrupted audio data fails the CRC
+	corrupted := append([]byte(nil), stream...)
+	corrupted[len(corrupted)-20] ^= 0xFF
+	_, err = Decode(bytes.NewReader(corrupted))
+	assert.Error(t, err)
+}
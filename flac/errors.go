@@ -0,0 +1,7 @@
+package flac
+
+type DecodeError string
+
+func (e DecodeError) Error() string {
+	return string(e)
+}
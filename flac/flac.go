@@ -0,0 +1,45 @@
+// Package flac decodes FLAC audio natively into WAV audio, so that FLAC can be loaded
+// without ffmpeg.
+//
+// FLAC format reference: https://xiph.org/flac/format.html.
+//
+// A FLAC stream is the "fLaC" marker, followed by metadata blocks (the first one is
+// always STREAMINFO) and audio frames. Each frame holds one block of samples of all the
+// channels, every channel is coded as a subframe:
+//
+// | Subframe type | Description                                                  |
+// |---------------|--------------------------------------------------------------|
+// | CONSTANT      | All the samples have the same value                          |
+// | VERBATIM      | Samples are stored unencoded                                 |
+// | FIXED         | Fixed polynomial predictor of order 0-4, Rice coded residual |
+// | LPC           | Linear predictor of order 1-32, Rice coded residual          |
+//
+// Stereo frames may be coded as left/side, right/side or mid/side.
+package flac
+
+const (
+	metadataStreamInfo = 0
+	streamInfoSize     = 34
+
+	// frameSyncCode is the 14-bit sync code at the start of each frame.
+	frameSyncCode = 0x3FFE
+)
+
+var (
+	FlacHeader = []byte{'f', 'L', 'a', 'C'}
+)
+
+// StreamInfo is the STREAMINFO metadata block.
+type StreamInfo struct {
+	MinBlockSize  uint16
+	MaxBlockSize  uint16
+	MinFrameSize  uint32
+	MaxFrameSize  uint32
+	SampleRate    uint32
+	Channels      uint16
+	BitsPerSample uint16
+	// TotalSamples is the number of samples per channel, 0 if unknown.
+	TotalSamples uint64
+	// MD5 is the MD5 signature of the unencoded audio data.
+	MD5 [16]byte
+}
@@ -0,0 +1,109 @@
+package godub
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// ToFloat64 将音频数据解码为按声道分开、归一化到[-1, 1]的浮点采样
+//
+// 返回:
+//   - [][]float64: 每个声道一个切片,长度均为帧数
+//   - error: 错误信息,如果有的话
+//
+// 说明:
+//   - 采样除以MaxPossibleAmplitude归一化,8位音频会先去掉无符号偏移
+//   - 是各种DSP效果(滤波、响度、频谱等)的公共基础,逆操作为NewAudioSegmentFromFloat64
+func (seg *AudioSegment) ToFloat64() ([][]float64, error) {
+	samples, err := seg.signedSamples()
+	if err != nil {
+		return nil, err
+	}
+
+	channels := int(seg.channels)
+	frameCount := len(samples) / channels
+	maxAmplitude := seg.MaxPossibleAmplitude()
+
+	result := make([][]float64, channels)
+	for c := range result {
+		result[c] = make([]float64, frameCount)
+	}
+	for i := 0; i < frameCount*channels; i++ {
+		result[i%channels][i/channels] = float64(samples[i]) / maxAmplitude
+	}
+	return result, nil
+}
+
+// NewAudioSegmentFromFloat64 从按声道分开、归一化到[-1, 1]的浮点采样创建音频片段
+//
+// 参数:
+//   - channels: 每个声道的采样,各声道长度必须相同
+//   - frameRate: 采样率
+//   - sampleWidth: 采样宽度(字节),1、2、3或4
+//
+// 说明:
+//   - 是ToFloat64的逆操作
+//   - 超出[-1, 1]的采样会被截断(削波)而不是回绕
+//   - 8位音频会加上无符号偏移
+func NewAudioSegmentFromFloat64(channels [][]float64, frameRate uint32, sampleWidth uint16) (*AudioSegment, error) {
+	if len(channels) == 0 {
+		return nil, NewAudioSegmentError("%w: at least one channel is required", ErrInvalidChannels)
+	}
+
+	if !ValidSampleWidths.Has(int(sampleWidth)) {
+		return nil, NewAudioSegmentError("invalid sample width %d", sampleWidth)
+	}
+
+	frameCount := len(channels[0])
+	for c, samples := range channels {
+		if len(samples) != frameCount {
+			return nil, NewAudioSegmentError(
+				"channel %d has %d samples, expected %d", c, len(samples), frameCount)
+		}
+	}
+
+	width := int(sampleWidth)
+	maxAmplitude := math.Pow(2, float64(width*8-1))
+	data := make([]byte, frameCount*len(channels)*width)
+	for i := 0; i < frameCount; i++ {
+		for c, samples := range channels {
+			v := math.Round(samples[i] * maxAmplitude)
+			v = math.Max(-maxAmplitude, math.Min(maxAmplitude-1, v))
+			sample := int32(v)
+
+			offset := (i*len(channels) + c) * width
+			switch width {
+			case 1:
+				data[offset] = byte(sample + 128)
+			case 2:
+				binary.LittleEndian.PutUint16(data[offset:], uint16(sample))
+			case 3:
+				data[offset] = byte(sample)
+				data[offset+1] = byte(sample >> 8)
+				data[offset+2] = byte(sample >> 16)
+			case 4:
+				binary.LittleEndian.PutUint32(data[offset:], uint32(sample))
+			}
+		}
+	}
+
+	return NewAudioSegment(
+		data,
+		SampleWidth(sampleWidth),
+		FrameRate(frameRate),
+		Channels(uint16(len(channels))),
+		FrameWidth(uint32(len(channels)*width)),
+	)
+}
+
+// deriveFromFloat64 is like NewAudioSegmentFromFloat64 in the format of seg, and the
+// result keeps the metadata and the source sample width of seg like derive.
+func (seg *AudioSegment) deriveFromFloat64(channels [][]float64) (*AudioSegment, error) {
+	derived, err := NewAudioSegmentFromFloat64(channels, seg.frameRate, seg.sampleWidth)
+	if err != nil {
+		return nil, err
+	}
+	derived.metadata = seg.metadata
+	derived.sourceSampleWidth = seg.sourceSampleWidth
+	return derived, nil
+}
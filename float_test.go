@@ -0,0 +1,49 @@
+package godub
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToFloat64(t *testing.T) {
+	seg := newSegment16(0, 16384, -32768)
+
+	channels, err := seg.ToFloat64()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{0, 0.5, -1}}, channels)
+
+	// 8-bit audio is unsigned
+	seg, _ = NewAudioSegment([]byte{0x80, 0xc0, 0x00, 0x40}, SampleWidth(1), FrameRate(8000), Channels(2), FrameWidth(2))
+	channels, err = seg.ToFloat64()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{0, -1}, {0.5, -0.5}}, channels)
+}
+
+func TestNewAudioSegmentFromFloat64(t *testing.T) {
+	channels := [][]float64{{0, 0.5, -1, 2}, {0.25, -0.5, 1, -2}}
+
+	for _, sampleWidth := range []uint16{1, 2, 3, 4} {
+		seg, err := NewAudioSegmentFromFloat64(channels, 8000, sampleWidth)
+		assert.NoError(t, err)
+		assert.Equal(t, uint16(2), seg.Channels())
+		assert.Equal(t, float64(4), seg.FrameCount())
+
+		decoded, err := seg.ToFloat64()
+		assert.NoError(t, err)
+		for c := range channels {
+			for i, v := range channels[c] {
+				// Out of range samples are clipped
+				v = math.Min(math.Max(v, -1), 1)
+				assert.InDelta(t, v, decoded[c][i], 1/math.Pow(2, float64(sampleWidth*8-1)), "width %d", sampleWidth)
+			}
+		}
+	}
+
+	_, err := NewAudioSegmentFromFloat64([][]float64{{0}, {0, 1}}, 8000, 2)
+	assert.Error(t, err)
+
+	_, err = NewAudioSegmentFromFloat64(nil, 8000, 2)
+	assert.Error(t, err)
+}
@@ -0,0 +1,150 @@
+package godub
+
+import "encoding/json"
+
+// FormatSpec names the format parameters of audio segments.
+type FormatSpec struct {
+	Channels    uint16
+	FrameRate   uint32
+	SampleWidth uint16
+}
+
+// FormatPreset is a named target format of ToPreset.
+type FormatPreset = FormatSpec
+
+var (
+	// CDQuality is 44.1kHz, 16-bit, stereo.
+	CDQuality = FormatPreset{Channels: 2, FrameRate: 44100, SampleWidth: 2}
+	// DATQuality is 48kHz, 16-bit, stereo.
+	DATQuality = FormatPreset{Channels: 2, FrameRate: 48000, SampleWidth: 2}
+	// Telephony is 8kHz, 16-bit, mono.
+	Telephony = FormatPreset{Channels: 1, FrameRate: 8000, SampleWidth: 2}
+)
+
+// Format 返回音频片段的格式(声道数、采样率、采样宽度)
+//
+// 说明:
+//   - FormatSpec可以直接用==比较,判断两个音频片段的格式是否相同
+//   - 可以作为SyncTo/ToPreset的目标格式,例如把其他片段转换为seg的格式
+func (seg *AudioSegment) Format() FormatSpec {
+	return FormatSpec{Channels: seg.channels, FrameRate: seg.frameRate, SampleWidth: seg.sampleWidth}
+}
+
+// IsMono 判断音频片段是否为单声道
+func (seg *AudioSegment) IsMono() bool {
+	return seg.channels == 1
+}
+
+// IsStereo 判断音频片段是否为双声道
+func (seg *AudioSegment) IsStereo() bool {
+	return seg.channels == 2
+}
+
+// ToPreset 将音频片段转换为预设的格式
+//
+// 参数:
+//   - p: 目标格式,例如CDQuality、DATQuality、Telephony
+//
+// 说明:
+//   - 依次调用ForkWithChannels、ForkWithFrameRate、ForkWithSampleWidth,与同步的顺序相同
+//   - 已经是目标格式的参数不会转换
+func (seg *AudioSegment) ToPreset(p FormatPreset) (*AudioSegment, error) {
+	converted, err := seg.ForkWithChannels(p.Channels)
+	if err != nil {
+		return nil, err
+	}
+
+	if converted, err = converted.ForkWithFrameRate(int(p.FrameRate)); err != nil {
+		return nil, err
+	}
+
+	return converted.ForkWithSampleWidth(int(p.SampleWidth))
+}
+
+// SyncTo 将所有音频片段转换为指定的目标格式
+//
+// 参数:
+//   - target: 目标格式(声道数、采样率、采样宽度)
+//   - segments: 需要转换的音频片段
+//
+// 说明:
+//   - 与同步不同,不会选取输入中的最大值,而是强制转换为target,例如避免升采样到最高的采样率
+//   - 每个音频片段的转换与ToPreset相同
+//   - 适合在Overlay/Append之前统一格式
+func SyncTo(target FormatSpec, segments ...*AudioSegment) ([]*AudioSegment, error) {
+	synced := make([]*AudioSegment, 0, len(segments))
+	for i, seg := range segments {
+		if seg == nil {
+			return nil, NewAudioSegmentError("segment %d should not be nil", i)
+		}
+
+		converted, err := seg.ToPreset(target)
+		if err != nil {
+			return nil, err
+		}
+		synced = append(synced, converted)
+	}
+	return synced, nil
+}
+
+// SegmentHeader is the JSON form of the format of an audio segment, see MarshalHeader.
+type SegmentHeader struct {
+	SampleWidth uint16 `json:"sampleWidth"`
+	FrameRate   uint32 `json:"frameRate"`
+	FrameWidth  uint32 `json:"frameWidth"`
+	Channels    uint16 `json:"channels"`
+	// Duration is in milliseconds.
+	Duration int64             `json:"duration"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// MarshalHeader 把音频片段的格式参数序列化为JSON,不包含音频数据
+//
+// 说明:
+//   - 输出{sampleWidth, frameRate, frameWidth, channels, duration},有元数据时还包括metadata
+//   - 用于缓存:格式参数存为JSON,音频数据(RawData)另外存放,
+//     之后通过NewAudioSegmentFromHeader重新创建音频片段
+func (seg *AudioSegment) MarshalHeader() ([]byte, error) {
+	return json.Marshal(SegmentHeader{
+		SampleWidth: seg.sampleWidth,
+		FrameRate:   seg.frameRate,
+		FrameWidth:  seg.frameWidth,
+		Channels:    seg.channels,
+		Duration:    seg.Duration(),
+		Metadata:    seg.metadata,
+	})
+}
+
+// NewAudioSegmentFromHeader 从MarshalHeader输出的JSON和原始PCM数据重新创建音频片段
+//
+// 参数:
+//   - header: MarshalHeader输出的JSON
+//   - data: 原始PCM数据,即原音频片段的RawData
+//
+// 说明:
+//   - 格式参数与NewAudioSegment一样会被校验
+//   - 数据的时长与header中的duration不一致时返回错误,避免把数据与其他音频的header搭配使用
+func NewAudioSegmentFromHeader(header []byte, data []byte) (*AudioSegment, error) {
+	var h SegmentHeader
+	if err := json.Unmarshal(header, &h); err != nil {
+		return nil, NewAudioSegmentError("invalid segment header: %s", err)
+	}
+
+	seg, err := NewAudioSegment(
+		data,
+		SampleWidth(h.SampleWidth),
+		FrameRate(h.FrameRate),
+		FrameWidth(h.FrameWidth),
+		Channels(h.Channels),
+		Metadata(h.Metadata),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if seg.Duration() != h.Duration {
+		return nil, NewAudioSegmentError(
+			"data is %dms long, but the header tells %dms", seg.Duration(), h.Duration)
+	}
+	return seg, nil
+}
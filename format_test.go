@@ -0,0 +1,91 @@
+package godub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToPreset(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 1000, 22050, 1)
+
+	for _, p := range []FormatPreset{CDQuality, DATQuality, Telephony} {
+		converted, err := seg.ToPreset(p)
+		assert.NoError(t, err)
+		assert.Equal(t, p.Channels, converted.Channels())
+		assert.Equal(t, p.FrameRate, converted.FrameRate())
+		assert.Equal(t, p.SampleWidth, converted.SampleWidth())
+		assert.Equal(t, uint32(p.Channels*p.SampleWidth), converted.FrameWidth())
+		assert.InDelta(t, seg.Duration(), converted.Duration(), 1)
+	}
+
+	telephony, _ := seg.ToPreset(Telephony)
+	same, err := telephony.ToPreset(Telephony)
+	assert.NoError(t, err)
+	assert.Same(t, telephony, same)
+
+	_, err = seg.ToPreset(FormatPreset{Channels: 6, FrameRate: 48000, SampleWidth: 2})
+	assert.Error(t, err)
+}
+
+func TestFormat(t *testing.T) {
+	mono := newSineSegment(440, 0.5, 100, 8000, 1)
+	stereo := newSineSegment(440, 0.5, 100, 8000, 2)
+
+	assert.Equal(t, FormatSpec{Channels: 1, FrameRate: 8000, SampleWidth: 2}, mono.Format())
+	assert.True(t, mono.IsMono())
+	assert.False(t, mono.IsStereo())
+	assert.True(t, stereo.IsStereo())
+	assert.False(t, stereo.IsMono())
+
+	assert.NotEqual(t, mono.Format(), stereo.Format())
+	assert.True(t, mono.Format() == newSineSegment(880, 0.1, 50, 8000, 1).Format())
+
+	// The format of one segment is the target of another
+	converted, err := stereo.ToPreset(mono.Format())
+	assert.NoError(t, err)
+	assert.Equal(t, mono.Format(), converted.Format())
+}
+
+func TestSyncTo(t *testing.T) {
+	target := FormatSpec{Channels: 1, FrameRate: 16000, SampleWidth: 2}
+	synced, err := SyncTo(target,
+		newSineSegment(440, 0.5, 500, 44100, 2),
+		newSineSegment(440, 0.5, 500, 8000, 1),
+		newSegment32(1, 2, 3),
+	)
+	assert.NoError(t, err)
+	assert.Len(t, synced, 3)
+	for _, seg := range synced {
+		assert.Equal(t, target.Channels, seg.Channels())
+		assert.Equal(t, target.FrameRate, seg.FrameRate())
+		assert.Equal(t, target.SampleWidth, seg.SampleWidth())
+	}
+
+	_, err = SyncTo(target, nil)
+	assert.Error(t, err)
+}
+
+func TestMarshalHeader(t *testing.T) {
+	seg, _ := newSineSegment(440, 0.5, 250, 8000, 2).WithMetadata(map[string]string{"title": "sine"})
+
+	header, err := seg.MarshalHeader()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"sampleWidth":2,"frameRate":8000,"frameWidth":4,"channels":2,"duration":250,`+
+		`"metadata":{"title":"sine"}}`, string(header))
+
+	restored, err := NewAudioSegmentFromHeader(header, seg.RawData())
+	assert.NoError(t, err)
+	assert.True(t, seg.Equal(restored))
+	assert.Equal(t, seg.String(), restored.String())
+	assert.Equal(t, seg.Metadata(), restored.Metadata())
+
+	// The data doesn't belong to the header
+	_, err = NewAudioSegmentFromHeader(header, seg.RawData()[:400])
+	assert.Error(t, err)
+
+	_, err = NewAudioSegmentFromHeader([]byte(`{"sampleWidth":5,"frameRate":8000,"frameWidth":5,"channels":1}`), nil)
+	assert.Error(t, err)
+	_, err = NewAudioSegmentFromHeader([]byte(`not json`), nil)
+	assert.Error(t, err)
+}
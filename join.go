@@ -0,0 +1,164 @@
+package godub
+
+import (
+	"github.com/wonglyxng/godub/utils"
+)
+
+// JoinMode is how the segments are joined by ConcatWithOptions.
+type JoinMode int
+
+const (
+	// JoinRaw joins the data as is, which is what Concat does. Joining two segments that
+	// don't end/start at a zero crossing makes an audible click.
+	JoinRaw JoinMode = iota
+	// JoinZeroCrossing trims each side of a join to the nearest zero crossing within
+	// JoinOptions.Window, so the waveform doesn't jump at the join.
+	JoinZeroCrossing
+)
+
+// DefaultJoinWindow is the default search window of JoinZeroCrossing, milliseconds.
+const DefaultJoinWindow int64 = 5
+
+type JoinOptions struct {
+	// Mode of the joins, default to JoinRaw.
+	Mode JoinMode
+	// Window to search for zero crossings on each side of a join, milliseconds,
+	// default to DefaultJoinWindow.
+	Window int64
+}
+
+// ConcatWithOptions 按指定的方式拼接多个音频片段
+//
+// 参数:
+//   - opts: 拼接选项,包含:
+//   - Mode: JoinRaw与Concat相同;JoinZeroCrossing把每个拼接点对齐到过零点
+//   - Window: 在拼接点两侧搜索过零点的范围(毫秒),0表示DefaultJoinWindow
+//   - segments: 需要拼接的音频片段
+//
+// 说明:
+//   - JoinZeroCrossing会把前一个片段的结尾裁剪到范围内最后一个过零点,把后一个片段的开头
+//     裁剪到范围内第一个过零点,以减少拼接处波形跳变造成的咔哒声
+//   - 多声道音频以各声道之和判断过零点
+//   - 范围内没有过零点时该侧不裁剪
+//
+// 注意:
+//   - JoinZeroCrossing的结果可能比原始片段之和更短,每个拼接点最多短2*Window毫秒
+//   - 第一个片段的开头和最后一个片段的结尾不会被裁剪
+func ConcatWithOptions(opts JoinOptions, segments ...*AudioSegment) (*AudioSegment, error) {
+	if opts.Window < 0 {
+		return nil, NewAudioSegmentError("join window should not be negative")
+	}
+
+	switch opts.Mode {
+	case JoinRaw:
+		return Concat(segments...)
+	case JoinZeroCrossing:
+	default:
+		return nil, NewAudioSegmentError("invalid join mode %d", opts.Mode)
+	}
+
+	if len(segments) < 2 {
+		return Concat(segments...)
+	}
+
+	window := opts.Window
+	if window == 0 {
+		window = DefaultJoinWindow
+	}
+
+	results, err := syncSegments(segments...)
+	if err != nil {
+		return nil, err
+	}
+
+	windowFrames := int(window * int64(results[0].frameRate) / 1000)
+	data := make([][]byte, 0, len(results))
+	for i, r := range results {
+		start, end := 0, int(r.FrameCount())
+		if i > 0 {
+			start = r.firstZeroCrossing(windowFrames)
+		}
+		if i < len(results)-1 {
+			end = r.lastZeroCrossing(windowFrames)
+		}
+		if start >= end {
+			// The segment is shorter than the windows, keep it as is.
+			start, end = 0, int(r.FrameCount())
+		}
+		fw := int(r.frameWidth)
+		data = append(data, r.data[start*fw:end*fw])
+	}
+	return results[0].derive(utils.ConcatenateByteSlice(data...))
+}
+
+// AppendWithOptions 按指定的方式把音频片段追加到当前片段之后,参见ConcatWithOptions
+func (seg *AudioSegment) AppendWithOptions(opts JoinOptions, segments ...*AudioSegment) (*AudioSegment, error) {
+	combined := []*AudioSegment{seg}
+	combined = append(combined, segments...)
+	return ConcatWithOptions(opts, combined...)
+}
+
+// firstZeroCrossing returns the frame to start from, which is the frame nearer to zero
+// of the first sign change within the first n frames, or 0 if there's none.
+func (seg *AudioSegment) firstZeroCrossing(n int) int {
+	frames := int(seg.FrameCount())
+	v := seg.downmixFrames(0, min(n+1, frames))
+	for k := 1; k < len(v); k++ {
+		if crossesZero(v[k-1], v[k]) {
+			if abs64(v[k-1]) <= abs64(v[k]) {
+				return k - 1
+			}
+			return k
+		}
+	}
+	return 0
+}
+
+// lastZeroCrossing returns the frame to end at (exclusive), which is after the frame
+// nearer to zero of the last sign change within the last n frames, or the frame count
+// if there's none.
+func (seg *AudioSegment) lastZeroCrossing(n int) int {
+	frames := int(seg.FrameCount())
+	from := max(frames-n-1, 0)
+	v := seg.downmixFrames(from, frames)
+	for k := len(v) - 1; k > 0; k-- {
+		if crossesZero(v[k-1], v[k]) {
+			if abs64(v[k-1]) < abs64(v[k]) {
+				return from + k
+			}
+			return from + k + 1
+		}
+	}
+	return frames
+}
+
+// downmixFrames returns the sum of the channels of each frame in [start, end) as signed values.
+func (seg *AudioSegment) downmixFrames(start, end int) []int64 {
+	if start >= end {
+		return nil
+	}
+
+	fw := int(seg.frameWidth)
+	chunk := seg.data[start*fw : end*fw]
+
+	channels := int(seg.channels)
+	v := make([]int64, end-start)
+	for i := range v {
+		for c := 0; c < channels; c++ {
+			s, _ := seg.signedSample(chunk, i*channels+c)
+			v[i] += int64(s)
+		}
+	}
+	return v
+}
+
+func crossesZero(a, b int64) bool {
+	return (a <= 0 && b >= 0) || (a >= 0 && b <= 0)
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,65 @@
+package godub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maxJump returns the largest difference of two consecutive samples of a mono segment.
+func maxJump(t *testing.T, seg *AudioSegment) int32 {
+	samples, err := seg.signedSamples()
+	require.NoError(t, err)
+
+	var jump int32
+	for i := 1; i < len(samples); i++ {
+		d := samples[i] - samples[i-1]
+		if d < 0 {
+			d = -d
+		}
+		if d > jump {
+			jump = d
+		}
+	}
+	return jump
+}
+
+func TestConcatWithOptions(t *testing.T) {
+	// 440Hz at 8kHz is in the middle of a positive half cycle at 101ms, and near the
+	// negative peak at 4ms.
+	sine := newSineSegment(440, 0.5, 200, 8000, 1)
+	a, err := sine.Slice(0, 101)
+	require.NoError(t, err)
+	b, err := sine.Slice(4, 200)
+	require.NoError(t, err)
+
+	raw, err := ConcatWithOptions(JoinOptions{}, a, b)
+	require.NoError(t, err)
+	concat, err := Concat(a, b)
+	require.NoError(t, err)
+	assert.True(t, raw.Equal(concat))
+
+	joined, err := a.AppendWithOptions(JoinOptions{Mode: JoinZeroCrossing}, b)
+	require.NoError(t, err)
+
+	// The slope of the sine is at most 0.5*32767*2π*440/8000, about 5662 per sample.
+	assert.Greater(t, maxJump(t, raw), int32(8000))
+	assert.LessOrEqual(t, maxJump(t, joined), int32(5700))
+
+	tolerance := 2 * DefaultJoinWindow * 8
+	assert.Less(t, joined.FrameCount(), raw.FrameCount())
+	assert.GreaterOrEqual(t, joined.FrameCount(), raw.FrameCount()-float64(tolerance))
+
+	// The outer ends are not trimmed.
+	head, err := joined.Slice(0, 50)
+	require.NoError(t, err)
+	expected, err := a.Slice(0, 50)
+	require.NoError(t, err)
+	assert.True(t, head.Equal(expected))
+
+	_, err = ConcatWithOptions(JoinOptions{Mode: JoinZeroCrossing, Window: -1}, a, b)
+	assert.Error(t, err)
+	_, err = ConcatWithOptions(JoinOptions{Mode: JoinMode(100)}, a, b)
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package godub
+
+import (
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/wonglyxng/godub/wav"
+)
+
+// LazySegment is WAV audio whose data is read from the underlying io.ReadSeeker on
+// demand, so that a huge file can be sliced at random without loading it entirely.
+// Audio that can't be read lazily is loaded eagerly, and LazySegment serves it from memory.
+type LazySegment struct {
+	mu sync.Mutex
+	rs io.ReadSeeker
+	// format is the WAV format without data.
+	format     *wav.WaveAudio
+	dataOffset int64
+	frameCount int64
+	// segment is set when the audio is loaded eagerly.
+	segment *AudioSegment
+	// empty is an empty segment with the format of the output.
+	empty *AudioSegment
+}
+
+// LoadLazy 加载WAV音频但不读取音频数据,数据在截取时才按偏移从rs中读取
+//
+// 参数:
+//   - rs: 可定位的输入,例如*os.File
+//
+// 说明:
+//   - 只读取WAV头部,格式和时长可以立即得到,适合对大文件做随机截取
+//   - rs不可定位(例如管道上的os.Stdin)、不是WAV或者设置了WithInputParams时,退回到
+//     Load一次性加载,LazySegment从内存中返回数据
+//   - WithMaxDuration同样生效:超过限制时截断,或者返回DurationLimitError
+//
+// 注意:
+//   - 在LazySegment使用期间rs不能被关闭或者在别处读写
+//   - LazySegment的方法可以并发调用,读取时会加锁
+func (l *Loader) LoadLazy(rs io.ReadSeeker) (*LazySegment, error) {
+	if rs == nil {
+		return nil, NewAudioSegmentError("reader should not be nil")
+	}
+
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil || len(l.inputParams) > 0 {
+		return l.loadEager(rs)
+	}
+
+	format, size, err := wav.DecodeHeader(rs)
+	if err != nil || format.BlockAlign() == 0 {
+		// Not WAV, or a WAV the header decoder can't handle, start over eagerly.
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return l.loadEager(rs)
+	}
+
+	offset, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	// The size is unset in streamed WAV, and a truncated file is shorter than declared.
+	dataSize := end - offset
+	if size != 0 && size != math.MaxUint32 && int64(size) < dataSize {
+		dataSize = int64(size)
+	}
+
+	empty, err := NewAudioSegmentFromWaveAudio(format)
+	if err != nil {
+		return nil, err
+	}
+
+	lazy := &LazySegment{
+		rs:         rs,
+		format:     format,
+		dataOffset: offset,
+		frameCount: dataSize / int64(format.BlockAlign()),
+		empty:      empty,
+	}
+
+	if l.maxDuration > 0 {
+		maxFrames := int64(format.SampleRate) * int64(l.maxDuration) / int64(time.Second)
+		if lazy.frameCount > maxFrames {
+			if !l.truncate {
+				return nil, DurationLimitError{Limit: l.maxDuration}
+			}
+			lazy.frameCount = maxFrames
+		}
+	}
+	return lazy, nil
+}
+
+func (l *Loader) loadEager(r io.Reader) (*LazySegment, error) {
+	seg, err := l.Load(r)
+	if err != nil {
+		return nil, err
+	}
+	empty, err := seg.derive(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &LazySegment{segment: seg, frameCount: int64(seg.FrameCount()), empty: empty}, nil
+}
+
+// IsLazy tells if the data is read on demand, false if the audio is loaded eagerly.
+func (s *LazySegment) IsLazy() bool {
+	return s.segment == nil
+}
+
+func (s *LazySegment) SampleWidth() uint16 {
+	return s.empty.sampleWidth
+}
+
+func (s *LazySegment) FrameRate() uint32 {
+	return s.empty.frameRate
+}
+
+func (s *LazySegment) FrameWidth() uint32 {
+	return s.empty.frameWidth
+}
+
+func (s *LazySegment) Channels() uint16 {
+	return s.empty.channels
+}
+
+// FrameCount returns the number of frames, which is known without reading the data.
+func (s *LazySegment) FrameCount() int64 {
+	return s.frameCount
+}
+
+// Duration returns the duration in milliseconds, like AudioSegment.Duration.
+func (s *LazySegment) Duration() int64 {
+	if s.empty.frameRate == 0 {
+		return 0
+	}
+	return int64(math.Round(1000.0 * float64(s.frameCount) / float64(s.empty.frameRate)))
+}
+
+// Slice reads the audio in [start, end) milliseconds, end beyond the audio is clipped.
+func (s *LazySegment) Slice(start, end int64) (*AudioSegment, error) {
+	if start > end {
+		return nil, NewAudioSegmentError("%w: start should be smaller than end", ErrInvalidRange)
+	}
+
+	if start < 0 || end < 0 {
+		return nil, NewAudioSegmentError("%w: start or end should be positive", ErrInvalidRange)
+	}
+
+	toFrame := func(ms int64) int64 {
+		frame := ms * int64(s.empty.frameRate) / 1000
+		if frame > s.frameCount {
+			return s.frameCount
+		}
+		return frame
+	}
+	return s.SliceFrames(toFrame(start), toFrame(end))
+}
+
+// SliceFrames reads the frames in [startFrame, endFrame), only this part of the data is read.
+func (s *LazySegment) SliceFrames(startFrame, endFrame int64) (*AudioSegment, error) {
+	if startFrame > endFrame {
+		return nil, NewAudioSegmentError("%w: start frame should be smaller than end frame", ErrInvalidRange)
+	}
+
+	if startFrame < 0 || endFrame < 0 {
+		return nil, NewAudioSegmentError("%w: start frame or end frame should be positive", ErrInvalidRange)
+	}
+
+	if endFrame > s.frameCount {
+		return nil, NewAudioSegmentError("%w: end frame %d is out of range, the segment has %d frames", ErrInvalidRange, endFrame, s.frameCount)
+	}
+
+	if s.segment != nil {
+		return s.segment.SliceFrames(int(startFrame), int(endFrame))
+	}
+
+	blockAlign := int64(s.format.BlockAlign())
+	data := make([]byte, (endFrame-startFrame)*blockAlign)
+
+	s.mu.Lock()
+	_, err := s.rs.Seek(s.dataOffset+startFrame*blockAlign, io.SeekStart)
+	if err == nil {
+		_, err = io.ReadFull(s.rs, data)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	waveAudio := *s.format
+	waveAudio.RawData = data
+	return NewAudioSegmentFromWaveAudio(&waveAudio)
+}
+
+// Load reads all the audio into an AudioSegment.
+func (s *LazySegment) Load() (*AudioSegment, error) {
+	return s.SliceFrames(0, s.frameCount)
+}
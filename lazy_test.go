@@ -0,0 +1,80 @@
+package godub
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingReadSeeker counts the bytes read from the underlying reader.
+type countingReadSeeker struct {
+	io.ReadSeeker
+	n int
+}
+
+func (r *countingReadSeeker) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestLoadLazy(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 10000, 8000, 2)
+	path := filepath.Join(t.TempDir(), "tone.wav")
+	require.NoError(t, os.WriteFile(path, encodeWav(t, seg), 0644))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	rs := &countingReadSeeker{ReadSeeker: f}
+	lazy, err := NewLoader().LoadLazy(rs)
+	require.NoError(t, err)
+	assert.True(t, lazy.IsLazy())
+	assert.Less(t, rs.n, 100)
+	assert.Equal(t, seg.Duration(), lazy.Duration())
+	assert.Equal(t, int64(seg.FrameCount()), lazy.FrameCount())
+	assert.Equal(t, seg.Channels(), lazy.Channels())
+	assert.Equal(t, seg.FrameRate(), lazy.FrameRate())
+
+	// Only the sliced part is read
+	rs.n = 0
+	sliced, err := lazy.Slice(5000, 5100)
+	require.NoError(t, err)
+	assert.Equal(t, 800*4, rs.n)
+	expected, _ := seg.Slice(5000, 5100)
+	assert.Equal(t, expected.RawData(), sliced.RawData())
+
+	all, err := lazy.Load()
+	require.NoError(t, err)
+	assert.Equal(t, seg.RawData(), all.RawData())
+
+	_, err = lazy.SliceFrames(0, lazy.FrameCount()+1)
+	assert.Error(t, err)
+
+	// The duration limit is applied without reading the data
+	_, err = NewLoader().WithMaxDuration(time.Second, false).LoadLazy(bytes.NewReader(encodeWav(t, seg)))
+	assert.ErrorAs(t, err, &DurationLimitError{})
+	lazy, err = NewLoader().WithMaxDuration(time.Second, true).LoadLazy(bytes.NewReader(encodeWav(t, seg)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), lazy.Duration())
+}
+
+func TestLoadLazyFallback(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 500, 8000, 1)
+
+	// FLAC is loaded eagerly
+	lazy, err := NewLoader().LoadLazy(bytes.NewReader(encodeFLAC(t, seg)))
+	require.NoError(t, err)
+	assert.False(t, lazy.IsLazy())
+	sliced, err := lazy.Slice(100, 200)
+	require.NoError(t, err)
+	expected, _ := seg.Slice(100, 200)
+	assert.Equal(t, expected.RawData(), sliced.RawData())
+}
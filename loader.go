@@ -1,70 +1,225 @@
 package godub
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/wonglyxng/godub/converter"
+	"github.com/wonglyxng/godub/flac"
 	"github.com/wonglyxng/godub/wav"
 )
 
+// Loader loads audio into AudioSegment. WAV and FLAC audio are decoded natively, other
+// formats (mp3/m4a/aac/ogg...) are transcoded to WAV by ffmpeg first.
 type Loader struct {
-	converter *converter.Converter
-	buf       io.Writer
+	params      []string
+	inputParams []string
+	maxDuration time.Duration
+	truncate    bool
 }
 
 func NewLoader() *Loader {
-	var buf bytes.Buffer
-	return &Loader{
-		converter: converter.NewConverter(&buf),
-		buf:       &buf,
-	}
+	return &Loader{}
 }
 
 func (l *Loader) WithParams(params ...string) *Loader {
-	l.converter.WithParams(params...)
+	l.params = params
+	return l
+}
+
+// WithInputParams sets ffmpeg params that describe the input, they're passed before `-i`.
+// Use it for raw/headerless data or unusual containers that ffmpeg can't sniff.
+// For example, to load a raw dump of 48kHz stereo 16-bit PCM:
+//
+//	segment, err := godub.NewLoader().
+//		WithInputParams("-f", "s16le", "-ar", "48000", "-ac", "2").
+//		Load("dump.pcm")
+func (l *Loader) WithInputParams(params ...string) *Loader {
+	l.inputParams = params
+	return l
+}
+
+// WithMaxDuration limits the duration of the decoded audio, to protect servers from
+// memory exhaustion on huge or adversarial inputs. Longer audio is truncated to
+// maxDuration if `truncate` is true, otherwise DurationLimitError is returned.
+// Decoding stops at the limit: ffmpeg gets `-t` so it never decodes the whole file,
+// and WAV data beyond the limit isn't read.
+func (l *Loader) WithMaxDuration(maxDuration time.Duration, truncate bool) *Loader {
+	l.maxDuration = maxDuration
+	l.truncate = truncate
 	return l
 }
 
 func (l *Loader) Load(src interface{}) (*AudioSegment, error) {
-	var buf []byte
+	var r io.Reader
 
-	switch r := src.(type) {
+	switch src := src.(type) {
 	case io.Reader:
-		result, err := io.ReadAll(r)
-		if err != nil {
-			return nil, err
-		}
-		buf = result
+		r = src
 	case string:
-		result, err := os.ReadFile(r)
+		f, err := os.Open(src)
 		if err != nil {
 			return nil, err
 		}
-		buf = result
+		defer f.Close()
+		r = f
 	case []byte:
-		buf = r
+		r = bytes.NewReader(src)
 	default:
 		return nil, fmt.Errorf("expected `io.Reader` or file path to original audio")
 	}
 
-	// Try to decode it as wave audio
+	// Sniff the format with Peek, so that the sniffed bytes aren't consumed and
+	// non-seekable readers (pipes, sockets) still reach the decoder as a whole.
+	br := bufio.NewReader(r)
+	if len(l.inputParams) == 0 && isFLACAudio(br) {
+		return l.loadFLAC(br)
+	}
+	if len(l.inputParams) > 0 || !isWaveAudio(br) {
+		waveAudio, err := l.decodeUsingFFmpeg(br)
+		if err != nil {
+			return nil, err
+		}
+		return l.newSegment(waveAudio)
+	}
+
+	buf, err := io.ReadAll(l.limitWaveReader(br))
+	if err != nil {
+		return nil, err
+	}
+
 	waveAudio, err := wav.Decode(bytes.NewReader(buf))
 	if err != nil {
-		// Try to convert to wave audio, and decode it again!
-		var tmpWavBuf bytes.Buffer
-		conv := converter.NewConverter(&tmpWavBuf).WithDstFormat("wav")
-		e := conv.Convert(bytes.NewReader(buf))
+		// Not supported by the native decoder (e.g. compressed WAV), let ffmpeg try.
+		var e error
+		waveAudio, e = l.decodeUsingFFmpeg(bytes.NewReader(buf))
 		if e != nil {
-			return nil, e
+			// Keep both, e.g. converter.EncoderNotFoundError if ffmpeg is missing.
+			return nil, fmt.Errorf("%w (ffmpeg fallback: %w)", err, e)
 		}
+	}
+	return l.newSegment(waveAudio)
+}
+
+// newSegment creates the segment and applies the duration limit.
+func (l *Loader) newSegment(waveAudio *wav.WaveAudio) (*AudioSegment, error) {
+	seg, err := NewAudioSegmentFromWaveAudio(waveAudio)
+	if err != nil || l.maxDuration <= 0 {
+		return seg, err
+	}
 
-		waveAudio, e = wav.Decode(&tmpWavBuf)
+	maxFrames := int(int64(seg.frameRate) * int64(l.maxDuration) / int64(time.Second))
+	if int(seg.FrameCount()) <= maxFrames {
+		return seg, nil
+	}
+
+	if !l.truncate {
+		return nil, DurationLimitError{Limit: l.maxDuration}
+	}
+	return seg.SliceFrames(0, maxFrames)
+}
+
+// limitWaveReader stops reading the WAV data a little after the duration limit, the
+// byte rate is taken from the header. The exceeding part is handled by newSegment.
+func (l *Loader) limitWaveReader(br *bufio.Reader) io.Reader {
+	if l.maxDuration <= 0 {
+		return br
+	}
+
+	// Peek returns what's buffered with an error if the input is shorter, that's fine.
+	header, _ := br.Peek(br.Size())
+	waveAudio, err := wav.Decode(bytes.NewReader(header))
+	if err != nil || waveAudio.ByteRate() == 0 {
+		return br
+	}
+
+	// The header is counted in, and one more second to make sure the limit is exceeded.
+	seconds := l.maxDuration.Seconds() + 1
+	limit := int64(len(header)) + int64(seconds*float64(waveAudio.ByteRate()))
+	return io.LimitReader(br, limit)
+}
+
+// isWaveAudio checks the RIFF/WAVE header without consuming the reader.
+func isWaveAudio(br *bufio.Reader) bool {
+	header, err := br.Peek(12)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(header[0:4], wav.RiffHeader) && bytes.Equal(header[8:12], wav.WaveHeader)
+}
+
+// isFLACAudio checks the fLaC marker without consuming the reader.
+func isFLACAudio(br *bufio.Reader) bool {
+	header, err := br.Peek(4)
+	return err == nil && bytes.Equal(header, flac.FlacHeader)
+}
+
+// loadFLAC decodes FLAC natively, ffmpeg gets a try if it fails. Decoding stops a little
+// after the duration limit like WAV.
+func (l *Loader) loadFLAC(br *bufio.Reader) (*AudioSegment, error) {
+	buf, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+
+	waveAudio, err := l.decodeFLAC(buf)
+	if err != nil {
+		var e error
+		waveAudio, e = l.decodeUsingFFmpeg(bytes.NewReader(buf))
 		if e != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w (ffmpeg fallback: %w)", err, e)
+		}
+	}
+	return l.newSegment(waveAudio)
+}
+
+func (l *Loader) decodeFLAC(buf []byte) (*wav.WaveAudio, error) {
+	d, err := flac.NewDecoder(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	var maxFrames int64
+	if l.maxDuration > 0 {
+		seconds := l.maxDuration.Seconds() + 1
+		maxFrames = int64(seconds * float64(d.StreamInfo().SampleRate))
+	}
+	return d.DecodeFrames(maxFrames)
+}
+
+// decodeUsingFFmpeg transcodes the audio to WAV on the stdout of ffmpeg and decodes it.
+func (l *Loader) decodeUsingFFmpeg(src interface{}) (*wav.WaveAudio, error) {
+	if !converter.IsCommandAvailable(converter.FFMPEGEncoder) {
+		return nil, converter.EncoderNotFoundError(fmt.Sprintf(
+			"audio is not WAV and command `%s` is not found to decode it", converter.FFMPEGEncoder))
+	}
+
+	params := l.params
+	if l.maxDuration > 0 {
+		// Decode a little more than the limit, so that exceeding it can be told.
+		seconds := l.maxDuration.Seconds()
+		if !l.truncate {
+			seconds += 1
 		}
+		params = append([]string{"-t", strconv.FormatFloat(seconds, 'f', -1, 64)}, params...)
 	}
-	return NewAudioSegmentFromWaveAudio(waveAudio)
+
+	var wavBuf bytes.Buffer
+	err := converter.NewConverter(&wavBuf).
+		WithDstFormat("wav").
+		WithPipeOutput(true).
+		WithParams(params...).
+		WithInputParams(l.inputParams...).
+		Convert(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sizes in the header are unset since stdout is not seekable, the decoder patches them.
+	return wav.Decode(&wavBuf)
 }
@@ -0,0 +1,280 @@
+package godub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wonglyxng/godub/audioop"
+	"github.com/wonglyxng/godub/converter"
+	"github.com/wonglyxng/godub/wav"
+)
+
+func encodeWav(t *testing.T, seg *AudioSegment) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, wav.Encode(&buf, seg.AsWaveAudio()))
+	return buf.Bytes()
+}
+
+// encodeFLAC encodes 16-bit audio as VERBATIM FLAC frames of up to 4096 samples.
+func encodeFLAC(t *testing.T, seg *AudioSegment) []byte {
+	assert.Equal(t, uint16(2), seg.SampleWidth())
+	crc := func(data []byte, poly uint16, bits uint) uint16 {
+		var c uint16
+		top := uint16(1) << (bits - 1)
+		for _, b := range data {
+			c ^= uint16(b) << (bits - 8)
+			for i := 0; i < 8; i++ {
+				if c&top != 0 {
+					c = c<<1 ^ poly
+				} else {
+					c <<= 1
+				}
+			}
+		}
+		return c & (top<<1 - 1)
+	}
+
+	channels := int(seg.Channels())
+	frames := int(seg.FrameCount())
+	var buf bytes.Buffer
+	buf.WriteString("fLaC")
+	buf.Write([]byte{0x80, 0, 0, 34, 0x10, 0, 0x10, 0, 0, 0, 0, 0, 0, 0})
+	binary.Write(&buf, binary.BigEndian,
+		uint64(seg.FrameRate())<<44|uint64(channels-1)<<41|15<<36|uint64(frames))
+	buf.Write(make([]byte, 16))
+
+	samples, _ := audioop.GetSamples(seg.RawData(), 2)
+	for frame, pos := 0, 0; pos < frames; frame, pos = frame+1, pos+4096 {
+		n := min(4096, frames-pos)
+		start := buf.Len()
+		buf.Write([]byte{0xFF, 0xF8, 0x70, byte(channels-1)<<4 | 0x08, byte(frame), byte((n - 1) >> 8), byte(n - 1)})
+		buf.WriteByte(byte(crc(buf.Bytes()[start:], 0x07, 8)))
+		for c := 0; c < channels; c++ {
+			buf.WriteByte(0x02)
+			for i := pos; i < pos+n; i++ {
+				binary.Write(&buf, binary.BigEndian, int16(samples[i*channels+c]))
+			}
+		}
+		binary.Write(&buf, binary.BigEndian, crc(buf.Bytes()[start:], 0x8005, 16))
+	}
+	return buf.Bytes()
+}
+
+func TestLoaderLoadWav(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 100, 8000, 2)
+
+	loaded, err := NewLoader().Load(bytes.NewReader(encodeWav(t, seg)))
+	assert.NoError(t, err)
+	assert.Equal(t, seg.String(), loaded.String())
+	assert.True(t, seg.Equal(loaded))
+}
+
+func TestLoaderLoadFLAC(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 1000, 8000, 2)
+	data := encodeFLAC(t, seg)
+
+	// Decoded natively, no ffmpeg needed
+	loaded, err := NewLoader().Load(data)
+	assert.NoError(t, err)
+	assert.Equal(t, seg.String(), loaded.String())
+	assert.True(t, seg.Equal(loaded))
+
+	loaded, err = NewLoader().WithMaxDuration(500*time.Millisecond, true).Load(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), loaded.Duration())
+
+	_, err = NewLoader().WithMaxDuration(500*time.Millisecond, false).Load(data)
+	assert.ErrorAs(t, err, &DurationLimitError{})
+}
+
+func TestLoaderWithoutFFmpeg(t *testing.T) {
+	if converter.IsCommandAvailable(converter.FFMPEGEncoder) {
+		t.Skip("ffmpeg is available")
+	}
+
+	_, err := NewLoader().Load([]byte("definitely not a wav file"))
+	assert.IsType(t, converter.EncoderNotFoundError(""), err)
+}
+
+func TestLoaderFallbackError(t *testing.T) {
+	// Neither ffmpeg nor `which` can be found.
+	t.Setenv("PATH", t.TempDir())
+
+	corrupt := append([]byte("RIFF\x00\x00\x00\x00WAVE"), []byte("garbage chunks")...)
+	_, err := NewLoader().Load(corrupt)
+	assert.ErrorAs(t, err, new(converter.EncoderNotFoundError))
+	assert.ErrorAs(t, err, new(wav.DecodeError))
+
+	_, err = NewLoader().Load([]byte("fLaC garbage"))
+	assert.ErrorAs(t, err, new(converter.EncoderNotFoundError))
+}
+
+// chunkedReader is a non-seekable reader serving a few bytes per Read, and it fails
+// the test if Read is called again after the stream is drained.
+type chunkedReader struct {
+	t       *testing.T
+	data    []byte
+	drained bool
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if r.drained {
+		r.t.Error("Read is called after EOF")
+		return 0, io.EOF
+	}
+
+	if len(r.data) == 0 {
+		r.drained = true
+		return 0, io.EOF
+	}
+
+	n := copy(p, r.data[:min(5, len(r.data))])
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestLoaderSniffDoesNotConsume(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 50, 8000, 1)
+
+	loaded, err := NewLoader().Load(&chunkedReader{t: t, data: encodeWav(t, seg)})
+	assert.NoError(t, err)
+	assert.True(t, seg.Equal(loaded))
+}
+
+func TestLoaderLoadFloatWav(t *testing.T) {
+	data := make([]byte, 12)
+	for i, v := range []float32{0, 0.5, -1} {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, wav.Encode(&buf, &wav.WaveAudio{
+		Format:        wav.AudioFormatIEEEFloat,
+		Channels:      1,
+		SampleRate:    8000,
+		BitsPerSample: 32,
+		RawData:       data,
+	}))
+
+	seg, err := NewLoader().Load(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(4), seg.SampleWidth())
+	assert.Equal(t, newSegment32(0, 1073741824, -2147483647).RawData(), seg.RawData())
+}
+
+func TestLoaderLoad8BitWav(t *testing.T) {
+	// A full-scale 8-bit square wave and a full-scale 8-bit sine wave, unsigned around 128.
+	square := make([]byte, 800)
+	sine := make([]byte, 800)
+	for i := range square {
+		square[i] = 0x01
+		if i/10%2 == 0 {
+			square[i] = 0xff
+		}
+		sine[i] = byte(math.Round(128 + 127*math.Sin(2*math.Pi*float64(i)/40)))
+	}
+
+	load := func(data []byte) *AudioSegment {
+		var buf bytes.Buffer
+		assert.NoError(t, wav.Encode(&buf, &wav.WaveAudio{
+			Format:        wav.AudioFormatPCM,
+			Channels:      1,
+			SampleRate:    8000,
+			BitsPerSample: 8,
+			RawData:       data,
+		}))
+
+		seg, err := NewLoader().Load(buf.Bytes())
+		assert.NoError(t, err)
+		assert.Equal(t, uint16(1), seg.SampleWidth())
+		return seg
+	}
+
+	seg := load(square)
+	assert.InDelta(t, 0, float64(seg.DBFS()), 0.1)
+	assert.InDelta(t, 0, float64(seg.MaxDBFS()), 0.1)
+	assert.Equal(t, 127.0, seg.Max())
+
+	seg = load(sine)
+	assert.InDelta(t, -3.01, float64(seg.DBFS()), 0.1)
+	assert.InDelta(t, 0, float64(seg.MaxDBFS()), 0.1)
+
+	// The level is kept through the processing
+	forked, err := seg.ForkWithSampleWidth(2)
+	assert.NoError(t, err)
+	assert.InDelta(t, float64(seg.DBFS()), float64(forked.DBFS()), 0.1)
+
+	quieter, err := seg.ApplyGain(-6)
+	assert.NoError(t, err)
+	assert.InDelta(t, -9.01, float64(quieter.DBFS()), 0.2)
+
+	stereo, err := seg.ForkWithChannels(2)
+	assert.NoError(t, err)
+	mono, err := stereo.ForkWithChannels(1)
+	assert.NoError(t, err)
+	assert.Equal(t, seg.RawData(), mono.RawData())
+
+	resampled, err := seg.ForkWithFrameRate(16000)
+	assert.NoError(t, err)
+	assert.InDelta(t, float64(seg.DBFS()), float64(resampled.DBFS()), 0.1)
+
+	silence, _ := NewSilentAudioSegmentWith(100, 8000, 1, 1)
+	overlaid, err := silence.Overlay(seg, &OverlayConfig{})
+	assert.NoError(t, err)
+	head, _ := seg.Slice(0, 100)
+	assert.Equal(t, head.RawData(), overlaid.RawData())
+
+	mixed, err := Mix(seg, seg)
+	assert.NoError(t, err)
+	assert.True(t, seg.EqualApprox(mixed, 1))
+
+	assert.Empty(t, DetectSilence(seg, 50, -40, 10))
+	assert.Equal(t, [][]int64{{0, 100}}, DetectSilence(silence, 50, -40, 10))
+	assert.Empty(t, DetectSilenceConcurrent(seg, 50, -40, 10))
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += n
+	return n, err
+}
+
+func TestLoaderWithMaxDuration(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 2000, 8000, 1)
+	data := encodeWav(t, seg)
+
+	loaded, err := NewLoader().WithMaxDuration(time.Second, true).Load(data)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), loaded.Duration())
+	expected, _ := seg.Slice(0, 1000)
+	assert.True(t, expected.Equal(loaded))
+
+	_, err = NewLoader().WithMaxDuration(time.Second, false).Load(data)
+	assert.ErrorAs(t, err, &DurationLimitError{})
+
+	// Within the limit
+	loaded, err = NewLoader().WithMaxDuration(3*time.Second, false).Load(data)
+	assert.NoError(t, err)
+	assert.True(t, seg.Equal(loaded))
+}
+
+func TestLoaderWithMaxDurationStopsReading(t *testing.T) {
+	data := encodeWav(t, newSineSegment(440, 0.5, 10000, 8000, 1))
+
+	cr := &countingReader{r: bytes.NewReader(data)}
+	loaded, err := NewLoader().WithMaxDuration(time.Second, true).Load(cr)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), loaded.Duration())
+	assert.Less(t, cr.n, len(data)/2)
+}
@@ -0,0 +1,268 @@
+package godub
+
+import (
+	"math"
+)
+
+const (
+	// LUFS gating parameters defined by ITU-R BS.1770-4.
+	loudnessBlockMs          = 400
+	loudnessBlockStepMs      = 100
+	loudnessAbsoluteGate     = -70.0
+	loudnessRelativeGate     = -10.0
+	loudnessOffset           = -0.691
+	loudnessMinimumFrameRate = 1000 / loudnessBlockStepMs
+)
+
+// LUFS 返回音频片段的综合响度(Integrated Loudness),单位为LUFS
+//
+// 计算过程(ITU-R BS.1770-4):
+//  1. 对每个声道做K计权滤波(高架滤波 + 高通滤波)
+//  2. 以400ms为块、100ms为步长(75%重叠)计算每块的均方值
+//  3. 丢弃低于-70 LUFS的块(绝对门限)
+//  4. 丢弃低于剩余块平均响度-10 LU的块(相对门限)
+//  5. 对剩余块求平均得到综合响度
+//
+// 注意:
+//   - 音频时长不足400ms时返回错误
+//   - 全部被门限丢弃(如静音)时返回负无穷
+//   - 所有声道权重均为1.0(仅支持单声道和立体声)
+func (seg *AudioSegment) LUFS() (float64, error) {
+	// A step of the blocks should have at least one frame.
+	if seg.frameRate < loudnessMinimumFrameRate {
+		return 0, NewAudioSegmentError("invalid frame rate %d for loudness measurement", seg.frameRate)
+	}
+
+	blockSize := int(seg.frameRate) * loudnessBlockMs / 1000
+	stepSize := int(seg.frameRate) * loudnessBlockStepMs / 1000
+	if int(seg.FrameCount()) < blockSize {
+		return 0, NewAudioSegmentError("audio should be at least %dms long to measure loudness", loudnessBlockMs)
+	}
+
+	channels, err := seg.ToFloat64()
+	if err != nil {
+		return 0, err
+	}
+
+	// Mean square of every block, per channel.
+	frameCount := len(channels[0])
+	blockCount := (frameCount-blockSize)/stepSize + 1
+	powers := make([][]float64, len(channels))
+	for i, samples := range channels {
+		filtered := kWeighting(samples, float64(seg.frameRate))
+
+		powers[i] = make([]float64, blockCount)
+		for j := 0; j < blockCount; j++ {
+			var sum float64
+			for _, v := range filtered[j*stepSize : j*stepSize+blockSize] {
+				sum += v * v
+			}
+			powers[i][j] = sum / float64(blockSize)
+		}
+	}
+
+	blockLoudness := func(j int) float64 {
+		var sum float64
+		for i := range powers {
+			sum += powers[i][j]
+		}
+		return loudnessOffset + 10*math.Log10(sum)
+	}
+
+	gatedLoudness := func(blocks []int) float64 {
+		var sum float64
+		for i := range powers {
+			var channelSum float64
+			for _, j := range blocks {
+				channelSum += powers[i][j]
+			}
+			sum += channelSum / float64(len(blocks))
+		}
+		return loudnessOffset + 10*math.Log10(sum)
+	}
+
+	// Absolute gating
+	var absGated []int
+	for j := 0; j < blockCount; j++ {
+		if blockLoudness(j) > loudnessAbsoluteGate {
+			absGated = append(absGated, j)
+		}
+	}
+	if len(absGated) == 0 {
+		return math.Inf(-1), nil
+	}
+
+	// Relative gating
+	relativeThreshold := gatedLoudness(absGated) + loudnessRelativeGate
+	var relGated []int
+	for _, j := range absGated {
+		if blockLoudness(j) > relativeThreshold {
+			relGated = append(relGated, j)
+		}
+	}
+	if len(relGated) == 0 {
+		return math.Inf(-1), nil
+	}
+
+	return gatedLoudness(relGated), nil
+}
+
+// NormalizeLUFS 调整音频增益,使综合响度达到目标值
+//
+// 参数:
+//   - target: 目标响度(LUFS),如播客常用的-16 LUFS
+//
+// 注意:
+//   - 静音音频无法归一化,将返回错误
+//   - 提升增益可能导致削波
+func (seg *AudioSegment) NormalizeLUFS(target float64) (*AudioSegment, error) {
+	loudness, err := seg.LUFS()
+	if err != nil {
+		return nil, err
+	}
+
+	if math.IsInf(loudness, -1) {
+		return nil, NewAudioSegmentError("can't normalize loudness of silent audio")
+	}
+
+	return seg.ApplyGain(Volume(target - loudness))
+}
+
+// MatchDBFS 调整音频增益,使dBFS(基于RMS)达到目标值
+//
+// 参数:
+//   - target: 目标音量(dBFS)
+//
+// 注意:
+//   - 静音音频无法调整,将返回错误
+//   - 提升增益可能导致削波
+func (seg *AudioSegment) MatchDBFS(target Volume) (*AudioSegment, error) {
+	dBFS := seg.DBFS()
+	if math.IsInf(float64(dBFS), 0) {
+		return nil, NewAudioSegmentError("can't match dBFS of silent audio")
+	}
+
+	return seg.ApplyGain(target - dBFS)
+}
+
+// MatchTargetAmplitude 调整音频增益,使dBFS达到目标值,与MatchDBFS相同
+//
+// 参数:
+//   - target: 目标音量(dBFS)
+//
+// 注意:
+//   - 静音音频或增益失败时返回错误
+func (seg *AudioSegment) MatchTargetAmplitude(target Volume) (*AudioSegment, error) {
+	return seg.MatchDBFS(target)
+}
+
+// MatchLoudness 调整source的增益,使其响度与target相同,例如拼接广告时统一音量
+//
+// 参数:
+//   - target: 参考音频片段
+//   - source: 需要调整的音频片段
+//
+// 说明:
+//   - 两者都不短于400ms时按LUFS匹配,否则(或任意一方被门限全部丢弃时)按dBFS匹配
+//   - 返回调整后的source,target不会被修改
+//
+// 注意:
+//   - source或target为静音时返回错误
+func MatchLoudness(target, source *AudioSegment) (*AudioSegment, error) {
+	if target == nil || source == nil {
+		return nil, NewAudioSegmentError("segments should not be nil")
+	}
+
+	targetLUFS, err := target.LUFS()
+	if err == nil {
+		var sourceLUFS float64
+		sourceLUFS, err = source.LUFS()
+		if err == nil && !math.IsInf(targetLUFS, 0) && !math.IsInf(sourceLUFS, 0) {
+			return source.ApplyGain(Volume(targetLUFS - sourceLUFS))
+		}
+	}
+
+	targetDBFS := target.DBFS()
+	if math.IsInf(float64(targetDBFS), 0) {
+		return nil, NewAudioSegmentError("can't match loudness of silent audio")
+	}
+	return source.MatchDBFS(targetDBFS)
+}
+
+// AppendMatched 把音频片段追加到当前片段之后,追加前把每个片段的dBFS调整到与当前片段相同
+//
+// 参数:
+//   - segments: 需要追加的音频片段
+//
+// 说明:
+//   - 通过MatchTargetAmplitude调整每个追加的片段,当前片段保持不变,之后与Append相同
+//   - 适合把不同来源的片段拼接成合集,避免拼接处音量突变
+//   - 静音的追加片段无法调整,原样拼接
+//
+// 注意:
+//   - 当前片段为静音时返回错误
+//   - 提升增益可能导致削波,可以之后使用Limit
+func (seg *AudioSegment) AppendMatched(segments ...*AudioSegment) (*AudioSegment, error) {
+	target := seg.DBFS()
+	if math.IsInf(float64(target), 0) {
+		return nil, NewAudioSegmentError("can't match dBFS of silent audio")
+	}
+
+	matched := make([]*AudioSegment, 0, len(segments))
+	for i, s := range segments {
+		if s == nil {
+			return nil, NewAudioSegmentError("segment %d to append should not be nil", i)
+		}
+		if math.IsInf(float64(s.DBFS()), 0) {
+			matched = append(matched, s)
+			continue
+		}
+		m, err := s.MatchTargetAmplitude(target)
+		if err != nil {
+			return nil, err
+		}
+		matched = append(matched, m)
+	}
+	return seg.Append(matched...)
+}
+
+// kWeighting applies the two stage K-weighting filter of BS.1770 to samples.
+// The coefficients are calculated for the given sample rate, see libebur128.
+func kWeighting(samples []float64, frameRate float64) []float64 {
+	// Stage 1: high shelf, models the acoustic effects of the head
+	f0 := 1681.974450955533
+	g := 3.999843853973347
+	q := 0.7071752369554196
+
+	k := math.Tan(math.Pi * f0 / frameRate)
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := &biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// Stage 2: high pass, the RLB weighting curve
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+
+	k = math.Tan(math.Pi * f0 / frameRate)
+	a0 = 1 + k/q + k*k
+	highPass := &biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	filtered := make([]float64, len(samples))
+	for i, v := range samples {
+		filtered[i] = highPass.process(shelf.process(v))
+	}
+	return filtered
+}
@@ -0,0 +1,146 @@
+package godub
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSineSegment generates a 16-bit sine tone, all channels carry the same signal.
+func newSineSegment(freq, amplitude float64, duration int64, frameRate uint32, channels uint16) *AudioSegment {
+	frames := int(int64(frameRate) * duration / 1000)
+	data := make([]byte, frames*int(channels)*2)
+	for i := 0; i < frames; i++ {
+		v := int16(amplitude * 32767 * math.Sin(2*math.Pi*freq*float64(i)/float64(frameRate)))
+		for c := 0; c < int(channels); c++ {
+			binary.LittleEndian.PutUint16(data[(i*int(channels)+c)*2:], uint16(v))
+		}
+	}
+
+	seg, _ := NewAudioSegment(
+		data,
+		Channels(channels),
+		SampleWidth(2),
+		FrameWidth(uint32(channels)*2),
+		FrameRate(frameRate),
+	)
+	return seg
+}
+
+func TestLUFS(t *testing.T) {
+	// A 1kHz tone with mean square 0.125 reads about -9.0 LUFS on a single channel.
+	seg := newSineSegment(1000, 0.5, 2000, 48000, 1)
+	loudness, err := seg.LUFS()
+	assert.NoError(t, err)
+	assert.InDelta(t, -9.03, loudness, 0.1)
+
+	// Stereo sums the power of both channels.
+	seg = newSineSegment(1000, 0.5, 2000, 48000, 2)
+	loudness, err = seg.LUFS()
+	assert.NoError(t, err)
+	assert.InDelta(t, -6.02, loudness, 0.1)
+
+	short := newSineSegment(1000, 0.5, 300, 48000, 1)
+	_, err = short.LUFS()
+	assert.Error(t, err)
+
+	// Frame rates too low for a 100ms step are rejected instead of dividing by zero
+	data := newSegment16(1000, -1000, 1000, -1000, 1000, -1000, 1000, -1000, 1000, -1000).RawData()
+	for _, rate := range []uint32{2, 5} {
+		low, _ := NewAudioSegment(data, SampleWidth(2), FrameRate(rate), Channels(1), FrameWidth(2))
+		_, err = low.LUFS()
+		assert.Error(t, err)
+		_, err = MatchLoudness(low, low)
+		assert.NoError(t, err)
+	}
+	low, _ := NewAudioSegment(data, SampleWidth(2), FrameRate(10), Channels(1), FrameWidth(2))
+	_, err = low.LUFS()
+	assert.NoError(t, err)
+}
+
+func TestNormalizeLUFS(t *testing.T) {
+	seg := newSineSegment(1000, 0.5, 2000, 44100, 1)
+	normalized, err := seg.NormalizeLUFS(-16)
+	assert.NoError(t, err)
+
+	loudness, err := normalized.LUFS()
+	assert.NoError(t, err)
+	assert.InDelta(t, -16, loudness, 0.1)
+}
+
+func TestMatchDBFS(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 500, 8000, 1)
+	matched, err := seg.MatchDBFS(-20)
+	assert.NoError(t, err)
+	assert.InDelta(t, -20, float64(matched.DBFS()), 0.1)
+
+	silence, _ := NewSilentAudioSegmentWith(500, 8000, 2, 1)
+	_, err = silence.MatchDBFS(-20)
+	assert.Error(t, err)
+}
+
+func TestMatchLoudness(t *testing.T) {
+	target := newSineSegment(1000, 0.5, 2000, 44100, 1)
+	source := newSineSegment(1000, 0.1, 1000, 44100, 1)
+	matched, err := MatchLoudness(target, source)
+	assert.NoError(t, err)
+
+	targetLUFS, _ := target.LUFS()
+	matchedLUFS, err := matched.LUFS()
+	assert.NoError(t, err)
+	assert.InDelta(t, targetLUFS, matchedLUFS, 0.1)
+
+	// Too short for LUFS, dBFS is matched
+	short := newSineSegment(440, 0.1, 200, 44100, 1)
+	matched, err = MatchLoudness(target, short)
+	assert.NoError(t, err)
+	assert.InDelta(t, float64(target.DBFS()), float64(matched.DBFS()), 0.1)
+
+	silence, _ := NewSilentAudioSegmentWith(1000, 44100, 2, 1)
+	_, err = MatchLoudness(silence, source)
+	assert.Error(t, err)
+	_, err = MatchLoudness(target, silence)
+	assert.Error(t, err)
+}
+
+func TestMatchTargetAmplitude(t *testing.T) {
+	seg := newSineSegment(440, 0.05, 500, 8000, 1)
+	matched, err := seg.MatchTargetAmplitude(-20)
+	assert.NoError(t, err)
+	assert.InDelta(t, -20, float64(matched.DBFS()), 0.1)
+
+	silence, _ := NewSilentAudioSegmentWith(500, 8000, 2, 1)
+	_, err = silence.MatchTargetAmplitude(-20)
+	assert.Error(t, err)
+}
+
+func TestAppendMatched(t *testing.T) {
+	first := newSineSegment(440, 0.5, 500, 8000, 1)
+	quiet := newSineSegment(440, 0.05, 500, 8000, 1)
+	pause, _ := NewSilentAudioSegmentWith(200, 8000, 2, 1)
+
+	joined, err := first.AppendMatched(quiet, pause, quiet)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1700), joined.Duration())
+
+	head, _ := joined.Slice(0, 500)
+	assert.Equal(t, first.RawData(), head.RawData())
+	for _, r := range [][2]int64{{500, 1000}, {1200, 1700}} {
+		chunk, _ := joined.Slice(r[0], r[1])
+		assert.InDelta(t, float64(first.DBFS()), float64(chunk.DBFS()), 0.1)
+	}
+	gap, _ := joined.Slice(1000, 1200)
+	assert.Equal(t, pause.RawData(), gap.RawData())
+
+	// Plain Append keeps the levels
+	plain, _ := first.Append(quiet)
+	tail, _ := plain.Slice(500, 1000)
+	assert.Equal(t, quiet.RawData(), tail.RawData())
+
+	_, err = pause.AppendMatched(quiet)
+	assert.Error(t, err)
+	_, err = first.AppendMatched(nil)
+	assert.Error(t, err)
+}
@@ -25,3 +25,10 @@ func Channels(v uint16) AudioSegmentOption {
 		s.channels = v
 	}
 }
+
+// Metadata sets the tags (artist, title...) of the segment, the map is copied.
+func Metadata(v map[string]string) AudioSegmentOption {
+	return func(s *AudioSegment) {
+		s.metadata = copyMetadata(v)
+	}
+}
@@ -0,0 +1,64 @@
+package godub
+
+import "github.com/wonglyxng/godub/audioop"
+
+// PCMLayout describes the byte layout of raw PCM exported by RawPCM, the zero value
+// is signed, little-endian and interleaved.
+type PCMLayout struct {
+	// BigEndian stores the bytes of samples in big-endian order.
+	BigEndian bool
+	// Unsigned stores samples with an offset of half the full scale, e.g. 0x8000 is
+	// silence of 16-bit audio.
+	Unsigned bool
+	// Planar stores all the samples of a channel after another instead of interleaving
+	// them frame by frame.
+	Planar bool
+}
+
+// RawPCM 按指定的字节布局导出原始PCM数据
+//
+// 参数:
+//   - opts: 字节序、有无符号以及是否交错存储
+//
+// 说明:
+//   - 与RawData不同,返回的是新的切片,不共享内部缓冲区
+//   - 内部格式为小端序,8位无符号,其余有符号,按需转换符号和字节序
+//   - 非交错(Planar)时依次存放每个声道的全部采样
+//   - 采样宽度保持不变,需要其他宽度时先调用ForkWithSampleWidth
+func (seg *AudioSegment) RawPCM(opts PCMLayout) ([]byte, error) {
+	width := int(seg.sampleWidth)
+	data := make([]byte, len(seg.data))
+	copy(data, seg.data)
+
+	// 8-bit is unsigned internally while the other widths are signed, the sign is
+	// converted by flipping the most significant bit.
+	if (width == 1) != opts.Unsigned {
+		for i := width - 1; i < len(data); i += width {
+			data[i] ^= 0x80
+		}
+	}
+
+	if opts.Planar && seg.channels > 1 {
+		data = deinterleave(data, int(seg.channels), width)
+	}
+
+	if opts.BigEndian && width > 1 {
+		return audioop.Byteswap(data, width)
+	}
+	return data, nil
+}
+
+// deinterleave rearranges interleaved samples to be stored channel by channel.
+func deinterleave(data []byte, channels, width int) []byte {
+	frameWidth := channels * width
+	frameCount := len(data) / frameWidth
+	planar := make([]byte, frameCount*frameWidth)
+	for i := 0; i < frameCount; i++ {
+		for c := 0; c < channels; c++ {
+			src := i*frameWidth + c*width
+			dst := (c*frameCount + i) * width
+			copy(planar[dst:dst+width], data[src:src+width])
+		}
+	}
+	return planar
+}
@@ -0,0 +1,45 @@
+package godub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawPCM(t *testing.T) {
+	// Stereo 16-bit frames: (1, -2), (3, 4)
+	stereo, err := NewAudioSegment([]byte{0x01, 0x00, 0xFE, 0xFF, 0x03, 0x00, 0x04, 0x00},
+		SampleWidth(2), FrameRate(1000), Channels(2), FrameWidth(4))
+	assert.NoError(t, err)
+
+	data, err := stereo.RawPCM(PCMLayout{})
+	assert.NoError(t, err)
+	assert.Equal(t, stereo.RawData(), data)
+	data[0] = 0x7F
+	assert.Equal(t, byte(0x01), stereo.RawData()[0])
+
+	data, err = stereo.RawPCM(PCMLayout{BigEndian: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x01, 0xFF, 0xFE, 0x00, 0x03, 0x00, 0x04}, data)
+
+	data, err = stereo.RawPCM(PCMLayout{Unsigned: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x80, 0xFE, 0x7F, 0x03, 0x80, 0x04, 0x80}, data)
+
+	data, err = stereo.RawPCM(PCMLayout{Planar: true, BigEndian: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x01, 0x00, 0x03, 0xFF, 0xFE, 0x00, 0x04}, data)
+
+	// 8-bit audio is unsigned internally
+	seg8, err := NewAudioSegment([]byte{0x80, 0xFF, 0x00},
+		SampleWidth(1), FrameRate(1000), Channels(1), FrameWidth(1))
+	assert.NoError(t, err)
+
+	data, err = seg8.RawPCM(PCMLayout{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x7F, 0x80}, data)
+
+	data, err = seg8.RawPCM(PCMLayout{Unsigned: true, BigEndian: true})
+	assert.NoError(t, err)
+	assert.Equal(t, seg8.RawData(), data)
+}
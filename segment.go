@@ -2,34 +2,48 @@ package godub
 
 import (
 	"bytes"
+	"encoding/binary"
+	"io"
 	"math"
+	"sync/atomic"
+	"time"
 
 	"fmt"
 
-	"encoding/binary"
-
 	"github.com/wonglyxng/godub/audioop"
 	"github.com/wonglyxng/godub/utils"
 	"github.com/wonglyxng/godub/wav"
 )
 
 var (
-	ValidChannels = utils.NewSet(1, 2)
+	ValidChannels     = utils.NewSet(1, 2)
+	ValidSampleWidths = utils.NewSet(1, 2, 3, 4)
 )
 
 // AudioSegment represents an segment of audio that can be
 // manipulated using Go code.
-// AudioSegment is **immutable**.
+// AudioSegment is **immutable**, so it's safe for concurrent use by multiple goroutines.
 type AudioSegment struct {
 	sampleWidth uint16
 	frameRate   uint32
 	frameWidth  uint32
 	channels    uint16
 	data        []byte
+	// metadata are tags like artist and title, it's never modified once set, so that
+	// derived segments can share it.
+	metadata map[string]string
+	// sourceSampleWidth is the sample width before 24-bit audio is converted to 32-bit,
+	// 0 if the data isn't converted. It's kept by derived segments of the same width, so
+	// that the audio can be exported back to 24-bit.
+	sourceSampleWidth uint16
 
 	// Cached values, because audio segment is immutable
-	// it's safe to store it.
-	rms *float64
+	// it's safe to store it. They're filled lazily with atomic pointers, so that
+	// a segment can be measured from multiple goroutines at the same time.
+	rms        atomic.Pointer[float64]
+	max        atomic.Pointer[float64]
+	frameCount atomic.Pointer[float64]
+	duration   atomic.Pointer[int64]
 }
 
 func (seg *AudioSegment) String() string {
@@ -46,34 +60,78 @@ func NewAudioSegment(data []byte, opts ...AudioSegmentOption) (*AudioSegment, er
 		opt(seg)
 	}
 
-	// FIXME: check if sample params are all valid or not.
+	if err := seg.validate(); err != nil {
+		return nil, err
+	}
+
 	// Convert 24-bit audio to 32-bit audio. Package audioop only supports 32-bit data.
 	if seg.sampleWidth == 3 {
-		bytesLen := len(data)/int(seg.sampleWidth) + len(data)
-		buf := make([]byte, bytesLen)
-
-		offset := 0
-		for i := 0; i < len(data); i += 3 {
-			b0, b1, b2 := data[i], data[i+1], data[i+2]
-
-			var padding byte
-			if b2 > 127 {
-				padding = 0xFF
-			}
-
-			w := bytes.NewBuffer(buf[offset:])
-			binary.Write(w, binary.LittleEndian, []byte{padding, b0, b1, b2})
-
-			// Next available position to write
-			offset += 4
+		buf := make([]byte, len(data)/3*4)
+
+		for i, offset := 0, 0; i+2 < len(data); i, offset = i+3, offset+4 {
+			// Both are little-endian, the 24-bit sample becomes the three most significant
+			// bytes and the new lowest byte is zero. So the 32-bit sample keeps the same
+			// ratio to full scale as the 24-bit one.
+			buf[offset] = 0x00
+			buf[offset+1] = data[i]
+			buf[offset+2] = data[i+1]
+			buf[offset+3] = data[i+2]
 		}
 
 		seg.data = buf
 		seg.sampleWidth = 4
+		seg.frameWidth = uint32(seg.channels) * 4
+		seg.sourceSampleWidth = 3
 	}
 	return seg, nil
 }
 
+// validate checks that the sample params are consistent with each other and the data.
+func (seg *AudioSegment) validate() error {
+	if !ValidSampleWidths.Has(int(seg.sampleWidth)) {
+		return NewAudioSegmentError("invalid sample width %d, should be 1, 2, 3 or 4", seg.sampleWidth)
+	}
+
+	if !ValidChannels.Has(int(seg.channels)) {
+		return NewAudioSegmentError("%w %d", ErrInvalidChannels, seg.channels)
+	}
+
+	if seg.frameRate == 0 {
+		return NewAudioSegmentError("frame rate should be greater than 0")
+	}
+
+	if seg.frameWidth != uint32(seg.sampleWidth)*uint32(seg.channels) {
+		return NewAudioSegmentError(
+			"frame width %d doesn't match sample width %d * channels %d",
+			seg.frameWidth, seg.sampleWidth, seg.channels)
+	}
+
+	if len(seg.data)%int(seg.frameWidth) != 0 {
+		return NewAudioSegmentError(
+			"data length %d is not a whole number of frames (frame width %d)",
+			len(seg.data), seg.frameWidth)
+	}
+
+	return nil
+}
+
+// NewAudioSegmentFromReader 从io.Reader读取原始PCM数据创建音频片段
+//
+// 参数:
+//   - r: 原始PCM数据(无文件头)
+//   - opts: 采样参数,原始PCM没有文件头,因此需要调用方直接提供
+//
+// 注意:
+//   - AudioSegment保存在内存中,因此仍然会把整个数据流读入内存,只是不需要调用方自己io.ReadAll
+//   - 与NewAudioSegment相同,24位音频会被转换为32位
+func NewAudioSegmentFromReader(r io.Reader, opts ...AudioSegmentOption) (*AudioSegment, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewAudioSegment(data, opts...)
+}
+
 func NewEmptyAudioSegment() (*AudioSegment, error) {
 	return NewAudioSegment(
 		[]byte{},
@@ -85,21 +143,57 @@ func NewEmptyAudioSegment() (*AudioSegment, error) {
 }
 
 func NewSilentAudioSegment(duration int, frameRate uint32) (*AudioSegment, error) {
-	frames := int(float64(frameRate) * (float64(duration) / 1000.0))
-	data := bytes.Repeat([]byte("\x00\x00"), frames)
-	return NewAudioSegment(
-		data,
-		Channels(1),
-		SampleWidth(2),
-		FrameWidth(2),
+	return NewSilentAudioSegmentWith(int64(duration), frameRate, 2, 1)
+}
+
+// NewSilentAudioSegmentWith 创建指定格式的静音音频片段
+//
+// 参数:
+//   - duration: 时长(毫秒)
+//   - frameRate: 帧率
+//   - sampleWidth: 采样宽度(1/2/3/4字节)
+//   - channels: 声道数
+//
+// 说明:
+//   - 直接生成与目标格式一致的静音,避免额外的同步/转换
+//   - 8位音频是无符号的,静音值为0x80;其它位宽静音值为0
+func NewSilentAudioSegmentWith(duration int64, frameRate uint32, sampleWidth uint16, channels uint16) (*AudioSegment, error) {
+	if duration < 0 {
+		return nil, NewAudioSegmentError("duration should be positive")
+	}
+
+	// Validate params with an empty segment first
+	seg, err := NewAudioSegment(
+		[]byte{},
+		Channels(channels),
+		SampleWidth(sampleWidth),
+		FrameWidth(uint32(sampleWidth)*uint32(channels)),
 		FrameRate(frameRate),
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := int(float64(frameRate) * (float64(duration) / 1000.0))
+	return seg.derive(seg.silentData(frames))
 }
 
 func NewAudioSegmentFromWaveAudio(waveAudio *wav.WaveAudio) (*AudioSegment, error) {
+	data := waveAudio.RawData
 	sampleWidth := waveAudio.BitsPerSample / 8
+
+	if waveAudio.Format == wav.AudioFormatIEEEFloat {
+		// Floating point samples are converted to 32-bit PCM.
+		ret, err := floatToPCM32(data, sampleWidth)
+		if err != nil {
+			return nil, err
+		}
+		data = ret
+		sampleWidth = 4
+	}
+
 	return NewAudioSegment(
-		waveAudio.RawData,
+		data,
 		Channels(waveAudio.Channels),
 		SampleWidth(sampleWidth),
 		FrameRate(waveAudio.SampleRate),
@@ -107,6 +201,30 @@ func NewAudioSegmentFromWaveAudio(waveAudio *wav.WaveAudio) (*AudioSegment, erro
 	)
 }
 
+// floatToPCM32 converts 32/64-bit floating point samples in [-1, 1] to 32-bit PCM,
+// samples beyond the range are clipped.
+func floatToPCM32(data []byte, sampleWidth uint16) ([]byte, error) {
+	if sampleWidth != 4 && sampleWidth != 8 {
+		return nil, NewAudioSegmentError("unsupported floating point sample width %d", sampleWidth)
+	}
+
+	width := int(sampleWidth)
+	count := len(data) / width
+	out := make([]byte, count*4)
+	for i := 0; i < count; i++ {
+		var v float64
+		if width == 4 {
+			v = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+		} else {
+			v = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+		}
+
+		v = math.Max(-1, math.Min(1, v)) * math.MaxInt32
+		binary.LittleEndian.PutUint32(out[i*4:], uint32(int32(math.Round(v))))
+	}
+	return out, nil
+}
+
 func (seg *AudioSegment) AsWaveAudio() *wav.WaveAudio {
 	waveAudio := wav.WaveAudio{
 		Format:        wav.AudioFormatPCM,
@@ -118,6 +236,47 @@ func (seg *AudioSegment) AsWaveAudio() *wav.WaveAudio {
 	return &waveAudio
 }
 
+// SourceSampleWidth 返回音频片段原始的采样宽度
+//
+// 说明:
+//   - 24位音频在内部被转换为32位,SampleWidth返回4,而本方法返回3
+//   - 没有经过转换的音频返回SampleWidth(),改变了采样宽度的处理(如ForkWithSampleWidth)之后也是如此
+//   - 导出时可以通过Exporter.WithSourceSampleWidth写回原始的24位
+func (seg *AudioSegment) SourceSampleWidth() uint16 {
+	if seg.sourceSampleWidth != 0 {
+		return seg.sourceSampleWidth
+	}
+	return seg.sampleWidth
+}
+
+// asSourceWaveAudio is like AsWaveAudio, but 24-bit audio is converted back to 24-bit.
+// Samples are rounded to 24-bit, and clipped at full scale.
+func (seg *AudioSegment) asSourceWaveAudio() *wav.WaveAudio {
+	waveAudio := seg.AsWaveAudio()
+	if seg.sourceSampleWidth != 3 {
+		return waveAudio
+	}
+
+	waveAudio.RawData = to24Bit(seg.data)
+	waveAudio.BitsPerSample = 24
+	return waveAudio
+}
+
+// to24Bit converts 32-bit samples to 24-bit, rounded and clipped at full scale.
+func to24Bit(data []byte) []byte {
+	converted := make([]byte, len(data)/4*3)
+	for i, offset := 0, 0; i+3 < len(data); i, offset = i+4, offset+3 {
+		v := (int64(int32(binary.LittleEndian.Uint32(data[i:]))) + 0x80) >> 8
+		if v > 0x7FFFFF {
+			v = 0x7FFFFF
+		}
+		converted[offset] = byte(v)
+		converted[offset+1] = byte(v >> 8)
+		converted[offset+2] = byte(v >> 16)
+	}
+	return converted
+}
+
 // Operations
 
 // Slice 从音频片段中截取指定时间范围的部分
@@ -137,11 +296,11 @@ func (seg *AudioSegment) AsWaveAudio() *wav.WaveAudio {
 //   - 对于缺失的帧会用静音填充(最多2ms)
 func (seg *AudioSegment) Slice(start, end int64) (*AudioSegment, error) {
 	if start > end {
-		return nil, NewAudioSegmentError("start should be smaller than end")
+		return nil, NewAudioSegmentError("%w: start should be smaller than end", ErrInvalidRange)
 	}
 
 	if start < 0 || end < 0 {
-		return nil, NewAudioSegmentError("start or end should be positive")
+		return nil, NewAudioSegmentError("%w: start or end should be positive", ErrInvalidRange)
 	}
 
 	audioLength := seg.Duration()
@@ -172,22 +331,63 @@ func (seg *AudioSegment) Slice(start, end int64) (*AudioSegment, error) {
 				missingFrames)
 		}
 
-		if silence, err := audioop.Mul(data[:seg.frameWidth], int(seg.sampleWidth), 0); err != nil {
-			silences := bytes.Repeat(silence, missingFrames)
-			data = utils.ConcatenateByteSlice(data, silences)
-		}
+		data = utils.ConcatenateByteSlice(data, seg.silentData(missingFrames))
 	}
 
 	return seg.derive(data)
 }
 
+// SliceTime 与Slice相同,但使用time.Duration表示起止时间
+//
+// 说明:
+//   - 时间直接换算为帧,不会取整到毫秒
+//   - 如果end超过音频长度,将截取到音频末尾
+func (seg *AudioSegment) SliceTime(start, end time.Duration) (*AudioSegment, error) {
+	if start > end {
+		return nil, NewAudioSegmentError("%w: start should be smaller than end", ErrInvalidRange)
+	}
+
+	if start < 0 || end < 0 {
+		return nil, NewAudioSegmentError("%w: start or end should be positive", ErrInvalidRange)
+	}
+
+	toFrame := func(d time.Duration) int {
+		frame := int(int64(d) * int64(seg.frameRate) / int64(time.Second))
+		return int(math.Min(float64(frame), seg.FrameCount()))
+	}
+
+	return seg.SliceFrames(toFrame(start), toFrame(end))
+}
+
+// SliceFrames 按帧索引截取音频片段[startFrame, endFrame)
+//
+// 说明:
+//   - 直接按frameWidth索引原始数据,没有毫秒取整,适合采样精确的循环剪辑
+//   - startFrame必须小于等于endFrame,且都不能超出总帧数
+func (seg *AudioSegment) SliceFrames(startFrame, endFrame int) (*AudioSegment, error) {
+	if startFrame > endFrame {
+		return nil, NewAudioSegmentError("%w: start frame should be smaller than end frame", ErrInvalidRange)
+	}
+
+	if startFrame < 0 || endFrame < 0 {
+		return nil, NewAudioSegmentError("%w: start frame or end frame should be positive", ErrInvalidRange)
+	}
+
+	if frameCount := int(seg.FrameCount()); endFrame > frameCount {
+		return nil, NewAudioSegmentError("%w: end frame %d is out of range, the segment has %d frames", ErrInvalidRange, endFrame, frameCount)
+	}
+
+	frameWidth := int(seg.frameWidth)
+	return seg.derive(seg.data[startFrame*frameWidth : endFrame*frameWidth])
+}
+
 func (seg *AudioSegment) SliceIndex(startIndex, endIndex int) (*AudioSegment, error) {
 	if startIndex > endIndex {
-		return nil, NewAudioSegmentError("start should be smaller than end")
+		return nil, NewAudioSegmentError("%w: start should be smaller than end", ErrInvalidRange)
 	}
 
 	if startIndex < 0 || endIndex < 0 {
-		return nil, NewAudioSegmentError("start or end should be positive")
+		return nil, NewAudioSegmentError("%w: start or end should be positive", ErrInvalidRange)
 	}
 
 	expectedLength := endIndex - startIndex
@@ -207,10 +407,7 @@ func (seg *AudioSegment) SliceIndex(startIndex, endIndex int) (*AudioSegment, er
 				missingFrames)
 		}
 
-		if silence, err := audioop.Mul(data[:seg.frameWidth], int(seg.sampleWidth), 0); err != nil {
-			silences := bytes.Repeat(silence, missingFrames)
-			data = utils.ConcatenateByteSlice(data, silences)
-		}
+		data = utils.ConcatenateByteSlice(data, seg.silentData(missingFrames))
 	}
 
 	return seg.derive(data)
@@ -223,39 +420,245 @@ func (seg *AudioSegment) Add(other *AudioSegment) (*AudioSegment, error) {
 func (seg *AudioSegment) Append(segments ...*AudioSegment) (*AudioSegment, error) {
 	combined := []*AudioSegment{seg}
 	combined = append(combined, segments...)
+	return Concat(combined...)
+}
+
+// AppendWithGap 在当前音频片段后追加gap毫秒的静音,再追加other,例如在有声书的章节之间插入停顿
+//
+// 说明:
+//   - 只同步一次采样参数,静音与同步后的格式一致,再一次性拼接
+//   - gap为0时与Append相同,不能为负数
+func (seg *AudioSegment) AppendWithGap(other *AudioSegment, gap int64) (*AudioSegment, error) {
+	if other == nil {
+		return nil, NewAudioSegmentError("the segment to append should not be nil")
+	}
 
-	results, err := syncSegments(combined...)
+	if gap < 0 {
+		return nil, NewAudioSegmentError("gap should be positive")
+	}
+
+	results, err := syncSegments(seg, other)
 	if err != nil {
 		return nil, err
 	}
+	left, right := results[0], results[1]
+
+	gapFrames := int(gap * int64(left.frameRate) / 1000)
+	return left.derive(utils.ConcatenateByteSlice(left.data, left.silentData(gapFrames), right.data))
+}
 
-	data := make([][]byte, 0)
+// Concat 将多个音频片段按顺序拼接为一个
+//
+// 说明:
+//   - 所有片段只同步一次采样参数,再一次性拼接全部数据
+//   - 相比多次调用Append,避免了重复同步带来的O(N²)重采样开销
+//   - 空列表返回空音频片段,只有一个片段时原样返回
+func Concat(segments ...*AudioSegment) (*AudioSegment, error) {
+	if len(segments) == 0 {
+		return NewEmptyAudioSegment()
+	}
+
+	if len(segments) == 1 {
+		return segments[0], nil
+	}
+
+	results, err := syncSegments(segments...)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([][]byte, 0, len(results))
 	for _, r := range results {
 		data = append(data, r.data)
 	}
-	return seg.derive(utils.ConcatenateByteSlice(data...))
+	return results[0].derive(utils.ConcatenateByteSlice(data...))
+}
+
+// Pipe 按顺序对音频片段执行一系列处理,任何一步出错时立即返回
+//
+// 参数:
+//   - ops: 处理函数,每个函数的输入是上一步的结果
+//
+// 说明:
+//   - 避免每一步都写`seg, err = seg.X(); if err != nil`
+//   - 出错时返回该步的错误,没有ops时原样返回seg
+//   - nil的处理函数视为错误
+//
+// 示例:
+//
+//	out, err := seg.Pipe(
+//		func(s *AudioSegment) (*AudioSegment, error) { return s.ApplyGain(-3) },
+//		func(s *AudioSegment) (*AudioSegment, error) { return s.RemoveClicks(0.5) },
+//	)
+func (seg *AudioSegment) Pipe(ops ...func(*AudioSegment) (*AudioSegment, error)) (*AudioSegment, error) {
+	result := seg
+	for i, op := range ops {
+		if op == nil {
+			return nil, NewAudioSegmentError("pipe op %d should not be nil", i)
+		}
+
+		next, err := op(result)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return nil, NewAudioSegmentError("pipe op %d returned a nil segment", i)
+		}
+		result = next
+	}
+	return result, nil
 }
 
 func (seg *AudioSegment) Equal(other *AudioSegment) bool {
 	return bytes.Equal(seg.data, other.data)
 }
 
+// EqualApprox 判断两个音频片段是否近似相等
+//
+// 参数:
+//   - other: 要比较的音频片段
+//   - tolerance: 每个采样允许的最大差值
+//
+// 说明:
+//   - 时长不同时直接返回false
+//   - 先同步两个音频片段的采样参数,再逐个比较解码后的采样值
+//   - 适合在测试中验证不应明显改变音频的处理(存在取整误差)
+func (seg *AudioSegment) EqualApprox(other *AudioSegment, tolerance int32) bool {
+	if other == nil || seg.DurationTime() != other.DurationTime() {
+		return false
+	}
+
+	syncedSegments, err := syncSegments(seg, other)
+	if err != nil {
+		return false
+	}
+	a, b := syncedSegments[0], syncedSegments[1]
+	if len(a.data) != len(b.data) {
+		return false
+	}
+
+	samplesA, err := a.signedSamples()
+	if err != nil {
+		return false
+	}
+
+	samplesB, err := b.signedSamples()
+	if err != nil {
+		return false
+	}
+
+	for i := range samplesA {
+		diff := int64(samplesA[i]) - int64(samplesB[i])
+		if diff > int64(tolerance) || -diff > int64(tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
 func (seg *AudioSegment) ApplyGain(volumeChange Volume) (*AudioSegment, error) {
-	data, err := audioop.Mul(seg.data, int(seg.sampleWidth), volumeChange.ToRatio(true))
+	data := seg.toSigned(seg.data, true)
+	if err := audioop.MulInto(data, data, int(seg.sampleWidth), volumeChange.ToRatio(true)); err != nil {
+		return nil, err
+	}
+	return seg.derive(seg.unsignedData(data))
+}
+
+// ApplyGainSafe 与ApplyGain相同,同时返回削波的采样数
+//
+// 参数:
+//   - volumeChange: 音量增益(dB)
+//
+// 返回:
+//   - *AudioSegment: 调整后的音频片段
+//   - clipped: 结果中达到满刻度的采样数,即结果的ClipCount()
+//   - err: 错误信息
+//
+// 说明:
+//   - 超出满刻度的采样被截断到满刻度,不会回绕
+//   - 调用方可以在clipped > 0时减小增益重试,实现自动的增益调整
+func (seg *AudioSegment) ApplyGainSafe(volumeChange Volume) (result *AudioSegment, clipped int, err error) {
+	result, err = seg.ApplyGain(volumeChange)
+	if err != nil {
+		return nil, 0, err
+	}
+	return result, result.ClipCount(), nil
+}
+
+// ApplyGainToChannel 只调整指定声道的音量,其他声道保持不变
+//
+// 参数:
+//   - channel: 声道索引,从0开始(立体声中0为左声道,1为右声道)
+//   - gain: 音量增益(dB)
+//
+// 说明:
+//   - 先解交织为单声道,对指定声道调用ApplyGain,再重新交织
+//   - 适合修正某一路麦克风音量过大等问题
+func (seg *AudioSegment) ApplyGainToChannel(channel int, gain Volume) (*AudioSegment, error) {
+	if channel < 0 || channel >= int(seg.channels) {
+		return nil, NewAudioSegmentError(
+			"%w: channel %d is out of range, the segment has %d channels", ErrInvalidChannels, channel, seg.channels)
+	}
+
+	monoSegments, err := seg.SplitToMono()
 	if err != nil {
 		return nil, err
 	}
-	return seg.derive(data)
+
+	adjusted, err := monoSegments[channel].ApplyGain(gain)
+	if err != nil {
+		return nil, err
+	}
+	monoSegments[channel] = adjusted
+
+	return seg.derive(interleave(monoSegments))
+}
+
+// Balance 调整立体声的左右平衡,衰减position所指方向的对侧声道
+//
+// 参数:
+//   - position: 平衡位置,范围[-1, 1],0为不变,正数衰减左声道(偏右),负数衰减右声道(偏左)
+//
+// 说明:
+//   - 对侧声道的幅度乘以1-|position|,±1时对侧声道为静音,另一侧声道保持不变
+//   - 与声像(pan)不同,不会把单声道声源移动到某个位置,只是重新平衡已有的立体声像,
+//     所以要求音频是立体声
+//   - 适合修正左右声道音量不一致的录音
+func (seg *AudioSegment) Balance(position float64) (*AudioSegment, error) {
+	if !(position >= -1 && position <= 1) {
+		return nil, NewAudioSegmentError("balance position should be in [-1, 1], got %v", position)
+	}
+	if seg.channels != 2 {
+		return nil, NewAudioSegmentError("%w: balance requires stereo audio, the segment has %d channels", ErrInvalidChannels, seg.channels)
+	}
+
+	if position == 0 {
+		return seg, nil
+	}
+
+	channel := 0
+	if position < 0 {
+		channel = 1
+	}
+	return seg.ApplyGainToChannel(channel, NewVolumeFromRatio(1-math.Abs(position), 1, true))
 }
 
 func (seg *AudioSegment) Repeat(count int) (*AudioSegment, error) {
 	return seg.derive(bytes.Repeat(seg.data, count))
 }
 
+// Reverse 返回倒序播放的音频片段
+//
+// 说明:
+//   - 按帧倒序,每一帧内各声道的采样顺序保持不变,所以多声道音频不会交换声道
 func (seg *AudioSegment) Reverse() (*AudioSegment, error) {
-	data, err := audioop.Reverse(seg.data, int(seg.sampleWidth))
-	if err != nil {
-		return nil, err
+	// audioop.Reverse reverses samples, which would swap the channels of each frame.
+	frameWidth := int(seg.frameWidth)
+	frameCount := len(seg.data) / frameWidth
+	data := make([]byte, frameCount*frameWidth)
+	for i := 0; i < frameCount; i++ {
+		src := seg.data[i*frameWidth : (i+1)*frameWidth]
+		copy(data[(frameCount-i-1)*frameWidth:], src)
 	}
 	return seg.derive(data)
 }
@@ -265,30 +668,19 @@ func (seg *AudioSegment) ForkWithSampleWidth(sampleWidth int) (*AudioSegment, er
 		return seg, nil
 	}
 
-	data := seg.data
-
+	// 8-bit audio is unsigned, bias it to signed before the conversion (and back if
+	// the target is 8-bit) in the same pass, so the data is copied only once.
+	bias, bias2 := 0, 0
 	if seg.sampleWidth == 1 {
-		if ret, err := audioop.Bias(data, 1, -128); err != nil {
-			return nil, err
-		} else {
-			data = ret
-		}
+		bias = -128
 	}
-
-	if len(data) > 0 {
-		if ret, err := audioop.Lin2Lin(data, int(seg.sampleWidth), sampleWidth); err != nil {
-			return nil, err
-		} else {
-			data = ret
-		}
+	if sampleWidth == 1 {
+		bias2 = 128
 	}
 
-	if sampleWidth == 1 {
-		if ret, err := audioop.Bias(data, 1, 128); err != nil {
-			return nil, err
-		} else {
-			data = ret
-		}
+	data, err := audioop.Lin2LinBias(seg.data, int(seg.sampleWidth), sampleWidth, bias, bias2)
+	if err != nil {
+		return nil, err
 	}
 
 	frameWidth := int(seg.channels) * sampleWidth
@@ -303,7 +695,7 @@ func (seg *AudioSegment) ForkWithFrameRate(frameRate int) (*AudioSegment, error)
 	converted := seg.data
 	if len(seg.data) > 0 {
 		ret, _, err := audioop.Ratecv(
-			seg.data,
+			seg.toSigned(seg.data, false),
 			int(seg.sampleWidth),
 			int(seg.channels),
 			int(seg.frameRate),
@@ -314,15 +706,18 @@ func (seg *AudioSegment) ForkWithFrameRate(frameRate int) (*AudioSegment, error)
 		if err != nil {
 			return nil, err
 		}
-		converted = ret
+		converted = seg.unsignedData(ret)
 	}
 
+	// The cached RMS isn't carried: the linear interpolation of Ratecv attenuates the
+	// high frequencies, e.g. a 3kHz sine loses about a quarter of its RMS from 8kHz
+	// to 16kHz, so the RMS has to be measured again on the converted data.
 	return seg.derive(converted, FrameRate(uint32(frameRate)))
 }
 
 func (seg *AudioSegment) ForkWithChannels(channels uint16) (*AudioSegment, error) {
 	if !ValidChannels.Has(int(channels)) {
-		return nil, NewAudioSegmentError("invalid channels")
+		return nil, NewAudioSegmentError("%w %d", ErrInvalidChannels, channels)
 	}
 
 	// Feature: copy-on-write
@@ -344,14 +739,57 @@ func (seg *AudioSegment) ForkWithChannels(channels uint16) (*AudioSegment, error
 		fac = 0.5
 	}
 
-	converted, err := convertFunc(seg.data, int(seg.sampleWidth), fac, fac)
+	converted, err := convertFunc(seg.toSigned(seg.data, false), int(seg.sampleWidth), fac, fac)
 	if err != nil {
 		return nil, err
 	}
 
-	return seg.derive(converted, Channels(channels), FrameWidth(uint32(frameWidth)))
+	return seg.derive(seg.unsignedData(converted), Channels(channels), FrameWidth(uint32(frameWidth)))
 }
 
+// SplitToMono 将多声道音频拆分为多个单声道音频片段,按声道顺序返回
+//
+// 说明:
+//   - 单声道音频直接返回包含自身的列表
+//   - 对交织(interleaved)的帧数据按声道逐个采样解交织
+func (seg *AudioSegment) SplitToMono() ([]*AudioSegment, error) {
+	if seg.channels == 1 {
+		return []*AudioSegment{seg}, nil
+	}
+
+	sampleWidth := int(seg.sampleWidth)
+	frameWidth := int(seg.frameWidth)
+	frameCount := int(seg.FrameCount())
+
+	monoSegments := make([]*AudioSegment, 0, seg.channels)
+	for c := 0; c < int(seg.channels); c++ {
+		data := make([]byte, frameCount*sampleWidth)
+		for i := 0; i < frameCount; i++ {
+			offset := i*frameWidth + c*sampleWidth
+			copy(data[i*sampleWidth:(i+1)*sampleWidth], seg.data[offset:offset+sampleWidth])
+		}
+
+		mono, err := seg.derive(data, Channels(1), FrameWidth(uint32(sampleWidth)))
+		if err != nil {
+			return nil, err
+		}
+		monoSegments = append(monoSegments, mono)
+	}
+	return monoSegments, nil
+}
+
+// OverlayClipping decides how Overlay deals with sums beyond full scale.
+type OverlayClipping int
+
+const (
+	// OverlaySaturate hard-clamps the overlaid samples to the max/min possible value,
+	// it's the default behavior.
+	OverlaySaturate OverlayClipping = iota
+	// OverlayHeadroom scales both inputs down by half (about -6dB) before adding them,
+	// so the sum never goes beyond full scale.
+	OverlayHeadroom
+)
+
 type OverlayConfig struct {
 	// Position to start overlaying, milliseconds
 	Position int64
@@ -361,6 +799,11 @@ type OverlayConfig struct {
 	// until it matches the original segment length, default to 1.
 	LoopCount         int
 	GainDuringOverlay Volume
+	// Clipping protection of the overlaid part, default to OverlaySaturate.
+	Clipping OverlayClipping
+	// ExtendTail extends the result to include the part of `other` beyond the end of the
+	// base, instead of cutting it. It's ignored if LoopToEnd is true.
+	ExtendTail bool
 }
 
 // Overlay overlays the given audio segment on the current segment.
@@ -369,27 +812,50 @@ type OverlayConfig struct {
 // 参数:
 //   - other: 要叠加的音频片段
 //   - config: 叠加配置,包含:
-//   - Position: 开始叠加的位置(毫秒)
+//   - Position: 开始叠加的位置(毫秒),可以为负数
 //   - LoopToEnd: 是否循环叠加直到原始音频结束
 //   - LoopCount: 循环次数(LoopToEnd为true时忽略)
 //   - GainDuringOverlay: 叠加时的音量增益
+//   - Clipping: 削波保护方式,默认OverlaySaturate(超出满刻度时截断而不是回绕)
+//   - ExtendTail: other超出原始音频结尾时延长结果,而不是截断(LoopToEnd为true时忽略)
 //
 // 注意:
 //   - 如果other为nil,返回原始音频段
 //   - 叠加前会先同步两个音频段的采样参数
 //   - LoopCount默认为1,当LoopToEnd为true时设为-1表示无限循环
+//   - Position为负数时,other会在原始音频开始之前播放(pre-roll),
+//     结果在开头延长-Position毫秒,这部分只有other的内容
+//   - 超出原始音频结尾的部分默认会被截断,结果长度不会超过原始音频(pre-roll除外);
+//     ExtendTail为true时结果延长到other(包括LoopCount次循环)结束,延长的部分只有other的内容,
+//     适合叠加会延续到原始音频之后的混响尾音或音效
+//   - config不会被修改
 func (seg *AudioSegment) Overlay(other *AudioSegment, config *OverlayConfig) (*AudioSegment, error) {
 	if other == nil {
 		return seg.derive(seg.data)
 	}
 
-	if config.LoopCount == 0 {
-		config.LoopCount = 1
+	if config.Position < 0 {
+		// Pre-roll: extend the base on the left with silence, so that `other`
+		// starts at the beginning of the result.
+		preRollFrames := int(-config.Position * int64(seg.frameRate) / 1000)
+		padded, err := seg.derive(utils.ConcatenateByteSlice(seg.silentData(preRollFrames), seg.data))
+		if err != nil {
+			return nil, err
+		}
+
+		paddedConfig := *config
+		paddedConfig.Position = 0
+		return padded.Overlay(other, &paddedConfig)
+	}
+
+	loopCount := config.LoopCount
+	if loopCount == 0 {
+		loopCount = 1
 	}
 
 	if config.LoopToEnd {
 		// Set to -1, so that we can loop until the end.
-		config.LoopCount = -1
+		loopCount = -1
 	}
 
 	syncedSegments, err := syncSegments(seg, other)
@@ -398,87 +864,201 @@ func (seg *AudioSegment) Overlay(other *AudioSegment, config *OverlayConfig) (*A
 	}
 	segment, other := syncedSegments[0], syncedSegments[1]
 
-	// Dest buffer to save overlaid data.
-	var dest []byte
-	destBuf := bytes.NewBuffer(dest)
+	if config.ExtendTail && loopCount > 0 {
+		// Extend the base on the right with silence, so that `other` isn't cut.
+		// parsePosition is clamped to the duration, the position may be beyond the end.
+		position := int(config.Position * int64(segment.frameRate) / 1000)
+		end := position*int(segment.frameWidth) + loopCount*len(other.data)
+		if missing := (end - len(segment.data)) / int(segment.frameWidth); missing > 0 {
+			padded, err := segment.derive(utils.ConcatenateByteSlice(segment.data, segment.silentData(missing)))
+			if err != nil {
+				return nil, err
+			}
 
-	// Cut the left part, and store it to the buffer.
-	if r, err := segment.Slice(0, config.Position); err != nil {
-		return nil, err
-	} else {
-		_, err := destBuf.Write(r.data)
-		if err != nil {
-			return nil, err
+			paddedConfig := *config
+			paddedConfig.ExtendTail = false
+			return padded.Overlay(other, &paddedConfig)
 		}
 	}
 
-	rSegment, err := segment.Slice(config.Position, segment.Duration())
-	if err != nil {
-		return nil, err
+	// Dest buffer to save overlaid data, it starts as a (signed) copy of the base
+	// and `other` is mixed into it in place.
+	dest := segment.toSigned(segment.data, true)
+
+	// The left part before the position is kept as it is.
+	offset := segment.parsePosition(config.Position) * int(segment.frameWidth)
+	if offset > len(dest) {
+		offset = len(dest)
 	}
 
 	sampleWidth := int(segment.sampleWidth)
-	rSegLen := len(rSegment.data)
-	rSegData := rSegment.data
+	rSegLen := len(dest) - offset
+	rSegData := dest[offset:]
 
-	otherSegLen := len(other.data)
-	otherSegData := other.data
+	otherSegData := other.toSigned(other.data, false)
+	if config.Clipping == OverlayHeadroom {
+		r, err := audioop.Mul(otherSegData, sampleWidth, 0.5)
+		if err != nil {
+			return nil, err
+		}
+		otherSegData = r
+	}
+	otherSegLen := len(otherSegData)
 
+	baseRatio := 1.0
+	if config.GainDuringOverlay > 0 {
+		baseRatio = config.GainDuringOverlay.ToRatio(true)
+	}
+	if config.Clipping == OverlayHeadroom {
+		baseRatio *= 0.5
+	}
+
+	// Stop at the end of the base, an empty `other` would never get there.
 	pos := 0
-	for i := config.LoopCount; i != 0; i -= 1 {
-		remainingLen := rSegLen - pos
-		if remainingLen < 0 {
-			remainingLen = 0
-		}
-
-		if otherSegLen >= remainingLen {
-			otherSegData = otherSegData[:remainingLen]
-			otherSegLen = remainingLen
-			// Mark this is the last round.
-			i = 1
-		}
-
-		var overlaidBytes []byte
-		if config.GainDuringOverlay > 0 {
-			adjustedBytes, err := audioop.Mul(
-				rSegData[pos:pos+otherSegLen],
-				sampleWidth,
-				config.GainDuringOverlay.ToRatio(true),
-			)
+	for i := loopCount; i != 0 && pos < rSegLen && otherSegLen > 0; i -= 1 {
+		// The last round may only overlay a part of `other`.
+		n := min(otherSegLen, rSegLen-pos)
+		baseBytes := rSegData[pos : pos+n]
+		if baseRatio != 1 {
+			r, err := audioop.Mul(baseBytes, sampleWidth, baseRatio)
 			if err != nil {
 				return nil, err
 			}
+			copy(baseBytes, r)
+		}
 
-			r, err := audioop.Add(adjustedBytes, otherSegData, sampleWidth)
-			if err != nil {
-				return nil, err
-			}
+		if err := audioop.AddInto(baseBytes, otherSegData[:n], sampleWidth); err != nil {
+			return nil, err
+		}
 
-			overlaidBytes = r
-		} else {
-			r, err := audioop.Add(rSegData[pos:pos+otherSegLen], otherSegData, sampleWidth)
+		// Move to the next position
+		pos += n
+	}
+
+	return segment.derive(segment.unsignedData(dest))
+}
+
+// OverlayLayer describes one layer to be overlaid by OverlayMany.
+type OverlayLayer struct {
+	Segment *AudioSegment
+	// Position to start overlaying, milliseconds
+	Position int64
+	// Gain applied to this layer before mixing
+	Gain Volume
+}
+
+// OverlayMany 在当前音频片段上一次性叠加多个音频片段
+//
+// 参数:
+//   - layers: 要叠加的图层列表,每个图层有各自的音频片段、起始位置(毫秒)和增益
+//
+// 说明:
+//   - 所有音频片段只同步一次采样参数,然后在同一个缓冲区中依次混音
+//   - 相比多次调用Overlay,避免了重复同步和重复分配内存
+//   - 超出原始音频长度的图层部分会被截断,起始位置超出原始音频的图层将被忽略
+//   - 结果长度与原始音频相同
+func (seg *AudioSegment) OverlayMany(layers []OverlayLayer) (*AudioSegment, error) {
+	segments := []*AudioSegment{seg}
+	for i, layer := range layers {
+		if layer.Segment == nil {
+			return nil, NewAudioSegmentError("segment of layer %d should not be nil", i)
+		}
+		if layer.Position < 0 {
+			return nil, NewAudioSegmentError("position of layer %d should be positive", i)
+		}
+		segments = append(segments, layer.Segment)
+	}
+
+	syncedSegments, err := syncSegments(segments...)
+	if err != nil {
+		return nil, err
+	}
+	base := syncedSegments[0]
+	sampleWidth := int(base.sampleWidth)
+
+	dest := base.toSigned(base.data, true)
+
+	for i, layer := range layers {
+		if layer.Position >= base.Duration() {
+			continue
+		}
+
+		other := syncedSegments[i+1]
+		start := base.parsePosition(layer.Position) * int(base.frameWidth)
+		end := start + len(other.data)
+		if end > len(dest) {
+			end = len(dest)
+		}
+
+		layerData := other.toSigned(other.data, false)[:end-start]
+		if layer.Gain != 0 {
+			layerData, err = audioop.Mul(layerData, sampleWidth, layer.Gain.ToRatio(true))
 			if err != nil {
 				return nil, err
 			}
-
-			overlaidBytes = r
 		}
 
-		_, err := destBuf.Write(overlaidBytes)
-		if err != nil {
+		if err := audioop.AddInto(dest[start:end], layerData, sampleWidth); err != nil {
 			return nil, err
 		}
+	}
 
-		// Move to the next position
-		pos += otherSegLen
+	return base.derive(base.unsignedData(dest))
+}
+
+// Mix 将多个音频片段混合为一个(多轨缩混)
+//
+// 参数:
+//   - segments: 需要混合的音频片段
+//
+// 说明:
+//   - 所有音频片段先同步采样参数,较短的片段在末尾用静音补齐到最长片段的长度
+//   - 混合时对各片段的采样取平均值而不是求和,因此结果不会削波
+//   - 与Overlay不同,结果长度为最长片段的长度
+func Mix(segments ...*AudioSegment) (*AudioSegment, error) {
+	if len(segments) == 0 {
+		return nil, NewAudioSegmentError("at least one segment is required to mix")
 	}
 
-	_, err = destBuf.Write(rSegData[pos:])
+	syncedSegments, err := syncSegments(segments...)
 	if err != nil {
 		return nil, err
 	}
+	first := syncedSegments[0]
+	sampleWidth := int(first.sampleWidth)
+
+	maxLen := 0
+	for _, s := range syncedSegments {
+		if len(s.data) > maxLen {
+			maxLen = len(s.data)
+		}
+	}
+
+	factor := 1.0 / float64(len(syncedSegments))
+	var mixed []byte
+	for _, s := range syncedSegments {
+		data := s.data
+		if missingFrames := (maxLen - len(data)) / int(s.frameWidth); missingFrames > 0 {
+			data = utils.ConcatenateByteSlice(data, s.silentData(missingFrames))
+		}
+
+		scaled, err := audioop.Mul(s.toSigned(data, false), sampleWidth, factor)
+		if err != nil {
+			return nil, err
+		}
+
+		if mixed == nil {
+			mixed = scaled
+			continue
+		}
+
+		mixed, err = audioop.Add(mixed, scaled, sampleWidth)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return segment.derive(destBuf.Bytes())
+	return first.derive(first.unsignedData(mixed))
 }
 
 // RMS returns the value of root mean square
@@ -486,33 +1066,74 @@ func (seg *AudioSegment) Overlay(other *AudioSegment, config *OverlayConfig) (*A
 //
 // 计算过程:
 //  1. 如果已经缓存了RMS值,直接返回
-//  2. 对于1字节采样宽度的音频,先转换为2字节后再计算
+//  2. 对于1字节采样宽度的音频,直接去掉无符号偏移后计算,结果按1字节的刻度返回(与MaxPossibleAmplitude一致)
 //  3. 使用audioop.RMS计算均方根值
 //
 // 注意:
 //   - 如果计算过程中发生错误,将返回0
 func (seg *AudioSegment) RMS() float64 {
-	if seg.rms != nil {
-		return *seg.rms
+	if cached := seg.rms.Load(); cached != nil {
+		return *cached
 	}
 
 	if seg.sampleWidth == 1 {
-		if r, err := seg.ForkWithSampleWidth(2); err != nil {
+		// Measure the unsigned samples directly, in the 8-bit scale of
+		// MaxPossibleAmplitude. It's not rounded since the scale is coarse.
+		r, err := audioop.RMSBias(seg.data, 1, -128)
+		if err != nil {
 			return 0
-		} else {
-			return r.RMS()
 		}
+		seg.rms.Store(&r)
+		return r
 	} else {
 		r, err := audioop.RMS(seg.data, int(seg.sampleWidth))
 		if err != nil {
 			return 0
 		}
 		rms := float64(r)
-		seg.rms = &rms
+		seg.rms.Store(&rms)
 		return rms
 	}
 }
 
+// RMSPerChannel 返回每个声道各自的均方根值
+//
+// 说明:
+//   - RMS会把所有声道合在一起计算,无法发现例如右声道无声的问题
+//   - 先通过SplitToMono拆分声道,再分别计算每个声道的RMS
+func (seg *AudioSegment) RMSPerChannel() ([]float64, error) {
+	monoSegments, err := seg.SplitToMono()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]float64, 0, len(monoSegments))
+	for _, mono := range monoSegments {
+		result = append(result, mono.RMS())
+	}
+	return result, nil
+}
+
+// LoudestChannel 返回RMS最大的声道索引(从0开始)
+//
+// 说明:
+//   - 基于RMSPerChannel,例如从多声道的多话筒录音中选出说话人所在的声道
+//   - 单声道音频返回0;RMS相同时返回索引较小的声道
+func (seg *AudioSegment) LoudestChannel() (int, error) {
+	rms, err := seg.RMSPerChannel()
+	if err != nil {
+		return 0, err
+	}
+
+	loudest := 0
+	for i, v := range rms {
+		if v > rms[loudest] {
+			loudest = i
+		}
+	}
+	return loudest, nil
+}
+
 // DBFS returns the value of dB Full Scale
 // DBFS 返回音频片段的dB全幅度值(dB Full Scale)
 //
@@ -561,16 +1182,49 @@ func (seg *AudioSegment) MaxDBFS() Volume {
 // Max 返回音频片段中的最大振幅值
 //
 // 说明:
-//   - 使用audioop.Max计算原始数据中的最大值
+//   - 使用audioop.Max计算原始数据中的最大值,8位音频会先去掉无符号偏移
 //   - 如果计算出错则返回0
+//   - 与RMS一样,结果会被缓存
 func (seg *AudioSegment) Max() float64 {
-	if r, err := audioop.Max(seg.data, int(seg.sampleWidth)); err != nil {
+	if cached := seg.max.Load(); cached != nil {
+		return *cached
+	}
+
+	if r, err := audioop.Max(seg.toSigned(seg.data, false), int(seg.sampleWidth)); err != nil {
 		return 0
 	} else {
-		return float64(r)
+		maxValue := float64(r)
+		seg.max.Store(&maxValue)
+		return maxValue
 	}
 }
 
+// PeakPosition 返回绝对值最大的采样所在的帧索引和采样值
+//
+// 说明:
+//   - 与Max不同,同时返回峰值的位置,采样值保留符号,可用于把鼓点等瞬态对齐到网格
+//   - 所有声道一起比较,多个采样同为最大值时返回最早的一个
+//   - 8位音频会先去掉无符号偏移,返回有符号的采样值
+//   - 空音频或计算出错时返回(0, 0)
+func (seg *AudioSegment) PeakPosition() (frame int, sample int32) {
+	samples, err := seg.signedSamples()
+	if err != nil || len(samples) == 0 {
+		return 0, 0
+	}
+
+	peak, peakIndex := int64(-1), 0
+	for i, v := range samples {
+		magnitude := int64(v)
+		if magnitude < 0 {
+			magnitude = -magnitude
+		}
+		if magnitude > peak {
+			peak, peakIndex = magnitude, i
+		}
+	}
+	return peakIndex / int(seg.channels), samples[peakIndex]
+}
+
 // Duration 返回音频片段的时长(毫秒)
 //
 // 计算方式:
@@ -580,11 +1234,28 @@ func (seg *AudioSegment) Max() float64 {
 // 注意:
 //   - 如果帧率为0,将返回0
 func (seg *AudioSegment) Duration() int64 {
+	if cached := seg.duration.Load(); cached != nil {
+		return *cached
+	}
+
+	if seg.frameRate == 0 {
+		return 0
+	}
+	mills := int64(math.Round(1000.0 * (seg.FrameCount() / float64(seg.frameRate))))
+	seg.duration.Store(&mills)
+	return mills
+}
+
+// DurationTime 返回音频片段的时长(time.Duration)
+//
+// 说明:
+//   - 与Duration不同,结果不会取整到毫秒
+//   - 如果帧率为0,将返回0
+func (seg *AudioSegment) DurationTime() time.Duration {
 	if seg.frameRate == 0 {
 		return 0
 	}
-	mills := math.Round(1000.0 * (seg.FrameCount() / float64(seg.frameRate)))
-	return int64(mills)
+	return time.Duration(int64(seg.FrameCount()) * int64(time.Second) / int64(seg.frameRate))
 }
 
 // FrameCount 返回音频片段的总帧数
@@ -596,8 +1267,14 @@ func (seg *AudioSegment) Duration() int64 {
 // 注意:
 //   - 如果frameWidth为0,将返回0
 func (seg *AudioSegment) FrameCount() float64 {
+	if cached := seg.frameCount.Load(); cached != nil {
+		return *cached
+	}
+
 	if seg.frameWidth > 0 {
-		return float64(len(seg.data) / int(seg.frameWidth))
+		frameCount := float64(len(seg.data) / int(seg.frameWidth))
+		seg.frameCount.Store(&frameCount)
+		return frameCount
 	} else {
 		return 0
 	}
@@ -648,6 +1325,66 @@ func (seg *AudioSegment) Len() int {
 	return len(seg.data)
 }
 
+// WithMetadata 返回带有指定元数据(标签)的音频片段,音频数据不会被复制
+//
+// 参数:
+//   - metadata: 标签,例如{"artist": "...", "title": "..."},传入nil或空map会清除元数据
+//
+// 说明:
+//   - 元数据会被复制,之后修改传入的map不会影响音频片段
+//   - 由音频片段处理得到的新片段(Slice、ApplyGain、Overlay等)会继承元数据,
+//     多个片段合并时继承第一个片段的元数据
+//   - Exporter在没有调用WithTags时,默认使用元数据作为标签
+func (seg *AudioSegment) WithMetadata(metadata map[string]string) (*AudioSegment, error) {
+	derived, err := seg.derive(seg.data)
+	if err != nil {
+		return nil, err
+	}
+	derived.metadata = copyMetadata(metadata)
+	return derived, nil
+}
+
+// Metadata returns a copy of the tags of the segment, it's nil if there're none.
+func (seg *AudioSegment) Metadata() map[string]string {
+	return copyMetadata(seg.metadata)
+}
+
+// EachFrame 逐帧遍历音频,每帧解码出各声道的采样后回调f
+//
+// 参数:
+//   - f: 回调函数,frameIndex为帧索引,samples为该帧各声道的采样(长度为声道数)
+//
+// 说明:
+//   - 不会一次解码全部采样,适合大文件或实时可视化等流式场景
+//   - f返回错误时停止遍历,并返回该错误
+//   - 8位音频会先去掉无符号偏移,采样值为有符号数
+//
+// 注意:
+//   - samples在每次回调之间会被复用,需要保留时应复制一份
+func (seg *AudioSegment) EachFrame(f func(frameIndex int, samples []int32) error) error {
+	if f == nil {
+		return NewAudioSegmentError("frame callback should not be nil")
+	}
+
+	frameWidth := int(seg.frameWidth)
+	samples := make([]int32, seg.channels)
+	for i := 0; (i+1)*frameWidth <= len(seg.data); i++ {
+		frame := seg.data[i*frameWidth : (i+1)*frameWidth]
+		for c := range samples {
+			sample, err := seg.signedSample(frame, c)
+			if err != nil {
+				return err
+			}
+			samples[c] = sample
+		}
+
+		if err := f(i, samples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Private functions & methods
 // sync will make sure every input segments have identical channels, frame rate and sample width.
 // sync 确保所有输入的音频片段具有相同的声道数、采样率和采样宽度
@@ -724,22 +1461,106 @@ func syncSegments(segments ...*AudioSegment) ([]*AudioSegment, error) {
 //   - 可通过opts覆盖继承的参数
 //   - 实现了音频段的不可变特性
 func (seg *AudioSegment) derive(data []byte, opts ...AudioSegmentOption) (*AudioSegment, error) {
-	ret, err := NewAudioSegment(
-		data,
+	// Options are applied in order, so the given opts override the inherited ones
+	// before the params are validated.
+	inherited := []AudioSegmentOption{
 		SampleWidth(seg.sampleWidth),
 		FrameRate(seg.frameRate),
 		FrameWidth(seg.frameWidth),
 		Channels(seg.channels),
-	)
+	}
+	derived, err := NewAudioSegment(data, append(inherited, opts...)...)
 	if err != nil {
 		return nil, err
 	}
+	if derived.metadata == nil {
+		derived.metadata = seg.metadata
+	}
+	if derived.sourceSampleWidth == 0 && derived.sampleWidth == seg.sampleWidth {
+		derived.sourceSampleWidth = seg.sourceSampleWidth
+	}
+	return derived, nil
+}
+
+// copyMetadata copies the tags, it returns nil for no tags.
+func copyMetadata(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
 
-	for _, opt := range opts {
-		opt(ret)
+	copied := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
+
+// silentData returns `frames` frames of silence in the format of the segment.
+func (seg *AudioSegment) silentData(frames int) []byte {
+	silence := byte(0)
+	if seg.sampleWidth == 1 {
+		// 8-bit audio is unsigned, the midpoint is silence.
+		silence = 0x80
+	}
+	return bytes.Repeat([]byte{silence}, frames*int(seg.frameWidth))
+}
+
+// toSigned returns data in the format of the segment with signed samples, which audioop
+// expects. 8-bit audio is unsigned, its samples are biased by -128 into a copy. Other data
+// is copied only if copied is true, otherwise it's returned as is and must not be modified.
+func (seg *AudioSegment) toSigned(data []byte, copied bool) []byte {
+	if seg.sampleWidth != 1 && !copied {
+		return data
+	}
+	signed := make([]byte, len(data))
+	copy(signed, data)
+	if seg.sampleWidth == 1 {
+		flipSignBit(signed)
+	}
+	return signed
+}
+
+// signedSample returns the i-th sample of data in the format of the segment as a signed
+// value, like toSigned without a copy.
+func (seg *AudioSegment) signedSample(data []byte, i int) (int32, error) {
+	if seg.sampleWidth == 1 {
+		return int32(data[i]) - 128, nil
 	}
+	return audioop.GetSample(data, int(seg.sampleWidth), i)
+}
 
-	return ret, nil
+// unsignedData is the reverse of toSigned, the 8-bit samples are biased by +128
+// in place, so `data` must be owned by the caller.
+func (seg *AudioSegment) unsignedData(data []byte) []byte {
+	if seg.sampleWidth == 1 {
+		flipSignBit(data)
+	}
+	return data
+}
+
+// flipSignBit flips the top bit of every 8-bit sample in place, which is adding or
+// subtracting 128 with wraparound.
+func flipSignBit(data []byte) {
+	for i := range data {
+		data[i] ^= 0x80
+	}
+}
+
+// interleave merges mono segments of the same format and length into the frames
+// of a multi-channel segment, it's the reverse of SplitToMono.
+func interleave(monoSegments []*AudioSegment) []byte {
+	sampleWidth := int(monoSegments[0].sampleWidth)
+	channels := len(monoSegments)
+	frameCount := int(monoSegments[0].FrameCount())
+
+	data := make([]byte, frameCount*sampleWidth*channels)
+	for c, mono := range monoSegments {
+		for i := 0; i < frameCount; i++ {
+			offset := (i*channels + c) * sampleWidth
+			copy(data[offset:offset+sampleWidth], mono.data[i*sampleWidth:(i+1)*sampleWidth])
+		}
+	}
+	return data
 }
 
 func (seg *AudioSegment) parsePosition(val int64) int {
@@ -0,0 +1,671 @@
+package godub
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wonglyxng/godub/audioop"
+)
+
+// newSegment16 builds a mono 16-bit segment at 1kHz, so that one frame is one millisecond.
+func newSegment16(samples ...int16) *AudioSegment {
+	data := make([]byte, 0, len(samples)*2)
+	for _, v := range samples {
+		data = append(data, byte(v), byte(v>>8))
+	}
+	seg, _ := NewAudioSegment(data, Channels(1), SampleWidth(2), FrameWidth(2), FrameRate(1000))
+	return seg
+}
+
+func TestOverlayMany(t *testing.T) {
+	base := newSegment16(1, 1, 1, 1, 1, 1)
+
+	result, err := base.OverlayMany([]OverlayLayer{
+		{Segment: newSegment16(10, 10), Position: 1},
+		{Segment: newSegment16(100, 100, 100), Position: 4},
+		{Segment: newSegment16(1000), Position: 10},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 11, 11, 1, 101, 101).RawData(), result.RawData())
+
+	_, err = base.OverlayMany([]OverlayLayer{{Segment: nil}})
+	assert.Error(t, err)
+}
+
+func newSegment32(samples ...int32) *AudioSegment {
+	data := make([]byte, 0, len(samples)*4)
+	for _, v := range samples {
+		data = append(data, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+	}
+	seg, _ := NewAudioSegment(data, Channels(1), SampleWidth(4), FrameWidth(4), FrameRate(1000))
+	return seg
+}
+
+func TestOverlayClipping(t *testing.T) {
+	for _, tone := range []*AudioSegment{
+		newSineSegment(100, 1, 1000, 1000, 1),
+		newSegment32(2147483647, 2000000000, -2000000000, -2147483648),
+	} {
+		sampleWidth := int(tone.SampleWidth())
+		original, _ := audioop.GetSamples(tone.RawData(), sampleWidth)
+
+		overlaid, err := tone.Overlay(tone, &OverlayConfig{})
+		assert.NoError(t, err)
+		saturated, _ := audioop.GetSamples(overlaid.RawData(), sampleWidth)
+		for i := range original {
+			// Saturation keeps the sign of the sum instead of wrapping around.
+			assert.False(t, original[i] > 0 && saturated[i] <= 0, "sample %d wrapped", i)
+			assert.False(t, original[i] < 0 && saturated[i] >= 0, "sample %d wrapped", i)
+		}
+
+		overlaid, err = tone.Overlay(tone, &OverlayConfig{Clipping: OverlayHeadroom})
+		assert.NoError(t, err)
+		scaled, _ := audioop.GetSamples(overlaid.RawData(), sampleWidth)
+		for i := range original {
+			assert.InDelta(t, original[i], scaled[i], 1)
+		}
+	}
+}
+
+func TestMix(t *testing.T) {
+	mixed, err := Mix(
+		newSegment16(32767, 100, 100),
+		newSegment16(32767, 300),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(32766, 200, 50).RawData(), mixed.RawData())
+
+	_, err = Mix()
+	assert.Error(t, err)
+}
+
+func TestNewAudioSegmentValidation(t *testing.T) {
+	validOpts := []AudioSegmentOption{Channels(1), SampleWidth(2), FrameWidth(2), FrameRate(1000)}
+	_, err := NewAudioSegment([]byte{0, 0}, validOpts...)
+	assert.NoError(t, err)
+
+	for _, opts := range [][]AudioSegmentOption{
+		{Channels(1), SampleWidth(5), FrameWidth(5), FrameRate(1000)},
+		{Channels(3), SampleWidth(2), FrameWidth(6), FrameRate(1000)},
+		{Channels(1), SampleWidth(2), FrameWidth(2), FrameRate(0)},
+		{Channels(2), SampleWidth(2), FrameWidth(2), FrameRate(1000)},
+	} {
+		_, err := NewAudioSegment([]byte{0, 0}, opts...)
+		assert.Error(t, err)
+	}
+
+	// Not a whole number of frames
+	_, err = NewAudioSegment([]byte{0, 0, 0}, validOpts...)
+	assert.Error(t, err)
+}
+
+func TestNewAudioSegment24Bit(t *testing.T) {
+	data := []byte{
+		0xFF, 0xFF, 0x7F, // 8388607, max positive value
+		0x00, 0x00, 0x80, // -8388608, min negative value
+		0xFF, 0xFF, 0xFF, // -1
+		0x01, 0x00, 0x00, // 1
+	}
+	seg, err := NewAudioSegment(data, Channels(2), SampleWidth(3), FrameWidth(6), FrameRate(1000))
+	assert.NoError(t, err)
+	assert.Equal(t, len(data)/3*4, seg.Len())
+	assert.Equal(t, uint16(4), seg.SampleWidth())
+	assert.Equal(t, uint32(8), seg.FrameWidth())
+	assert.Equal(t, float64(2), seg.FrameCount())
+
+	samples, err := audioop.GetSamples(seg.RawData(), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{8388607 << 8, -8388608 << 8, -1 << 8, 1 << 8}, samples)
+
+	// A full scale 24-bit sample is still full scale after conversion.
+	assert.InDelta(t, 0, float64(seg.MaxDBFS()), 0.001)
+}
+
+func TestSourceSampleWidth(t *testing.T) {
+	data := []byte{
+		0xFF, 0xFF, 0x7F,
+		0x00, 0x00, 0x80,
+		0xFF, 0xFF, 0xFF,
+		0x01, 0x00, 0x00,
+	}
+	seg, err := NewAudioSegment(data, Channels(2), SampleWidth(3), FrameWidth(6), FrameRate(1000))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(3), seg.SourceSampleWidth())
+	assert.Equal(t, uint16(2), newSegment16(1, 2).SourceSampleWidth())
+
+	// Kept by derived segments, but not after the width is changed
+	reversed, _ := seg.Reverse()
+	assert.Equal(t, uint16(3), reversed.SourceSampleWidth())
+	converted, _ := seg.ForkWithSampleWidth(2)
+	assert.Equal(t, uint16(2), converted.SourceSampleWidth())
+	// Also by the processing in float64
+	stretched, _ := seg.TimeStretch(1.5)
+	assert.Equal(t, uint16(3), stretched.SourceSampleWidth())
+
+	waveAudio := seg.asSourceWaveAudio()
+	assert.Equal(t, uint16(24), waveAudio.BitsPerSample)
+	assert.Equal(t, data, waveAudio.RawData)
+
+	// Exported as true 24-bit
+	var buf bytes.Buffer
+	assert.NoError(t, NewExporter(&buf).WithDstFormat("wav").WithSourceSampleWidth(true).Export(seg))
+	loaded, err := NewLoader().Load(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, seg.RawData(), loaded.RawData())
+	assert.Equal(t, uint16(3), loaded.SourceSampleWidth())
+	assert.Equal(t, 44+len(data), buf.Len())
+
+	buf.Reset()
+	assert.NoError(t, NewExporter(&buf).WithDstFormat("wav").Export(seg))
+	assert.Equal(t, 44+seg.Len(), buf.Len())
+
+	// Processed samples are rounded to 24-bit
+	loud, _ := NewAudioSegment(newSegment32(0x7FFFFFFF, 0x180).RawData(), Channels(1), SampleWidth(4), FrameWidth(4), FrameRate(1000))
+	loud.sourceSampleWidth = 3
+	assert.Equal(t, []byte{0xFF, 0xFF, 0x7F, 0x02, 0x00, 0x00}, loud.asSourceWaveAudio().RawData)
+}
+
+func TestDurationTime(t *testing.T) {
+	seg := newSineSegment(100, 0.5, 1000, 8000, 1)
+	slice, err := seg.SliceTime(0, 1500*time.Microsecond)
+	assert.NoError(t, err)
+
+	// 12 frames at 8kHz, Duration can only say 2ms.
+	assert.Equal(t, float64(12), slice.FrameCount())
+	assert.Equal(t, 1500*time.Microsecond, slice.DurationTime())
+	assert.Equal(t, int64(2), slice.Duration())
+
+	slice, err = seg.SliceTime(500*time.Millisecond, 2*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, slice.DurationTime())
+
+	_, err = seg.SliceTime(time.Second, 0)
+	assert.Error(t, err)
+}
+
+func TestSliceFrames(t *testing.T) {
+	seg := newSegment16(0, 1, 2, 3, 4, 5)
+
+	slice, err := seg.SliceFrames(2, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(2, 3, 4).RawData(), slice.RawData())
+
+	slice, err = seg.SliceFrames(6, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, slice.Len())
+
+	for _, r := range [][2]int{{3, 2}, {-1, 2}, {0, 7}} {
+		_, err = seg.SliceFrames(r[0], r[1])
+		assert.Error(t, err)
+	}
+}
+
+func TestConcat(t *testing.T) {
+	empty, err := Concat()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, empty.Len())
+
+	seg := newSegment16(1, 2)
+	single, err := Concat(seg)
+	assert.NoError(t, err)
+	assert.Same(t, seg, single)
+
+	joined, err := Concat(seg, newSegment16(3), newSegment16(4, 5))
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 2, 3, 4, 5).RawData(), joined.RawData())
+}
+
+func TestAppendWithGap(t *testing.T) {
+	joined, err := newSegment16(1, 2).AppendWithGap(newSegment16(3), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 2, 0, 0, 0, 3).RawData(), joined.RawData())
+
+	joined, err = newSegment16(1, 2).AppendWithGap(newSegment16(3), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 2, 3).RawData(), joined.RawData())
+
+	// The gap is silent in the synced format, e.g. 0x80 for 8-bit audio
+	eightBit, _ := NewAudioSegment([]byte{0x90}, SampleWidth(1), FrameRate(1000), Channels(1), FrameWidth(1))
+	joined, err = eightBit.AppendWithGap(eightBit, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x90, 0x80, 0x80, 0x90}, joined.RawData())
+
+	stereo := newSineSegment(440, 0.5, 100, 8000, 2)
+	joined, err = newSineSegment(440, 0.5, 100, 4000, 1).AppendWithGap(stereo, 500)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(2), joined.Channels())
+	assert.Equal(t, uint32(8000), joined.FrameRate())
+	assert.Equal(t, int64(700), joined.Duration())
+
+	_, err = stereo.AppendWithGap(stereo, -1)
+	assert.Error(t, err)
+	_, err = stereo.AppendWithGap(nil, 1)
+	assert.Error(t, err)
+}
+
+func TestRMSPerChannel(t *testing.T) {
+	// The right channel is dead.
+	stereo, err := NewAudioSegment(
+		newSegment16(100, 0, -100, 0, 100, 0, -100, 0).RawData(),
+		Channels(2), SampleWidth(2), FrameWidth(4), FrameRate(1000),
+	)
+	assert.NoError(t, err)
+
+	monoSegments, err := stereo.SplitToMono()
+	assert.NoError(t, err)
+	assert.Len(t, monoSegments, 2)
+	assert.Equal(t, newSegment16(100, -100, 100, -100).RawData(), monoSegments[0].RawData())
+
+	rms, err := stereo.RMSPerChannel()
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{100, 0}, rms)
+}
+
+func TestApplyGainSafe(t *testing.T) {
+	seg := newSegment16(1000, -1000, 20000, -20000)
+
+	result, clipped, err := seg.ApplyGainSafe(6.0206)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, clipped)
+	assert.Equal(t, newSegment16(2000, -2000, 32767, -32768).RawData(), result.RawData())
+
+	expected, _ := seg.ApplyGain(6.0206)
+	assert.True(t, expected.Equal(result))
+
+	_, clipped, err = seg.ApplyGainSafe(3)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, clipped)
+}
+
+func TestLoudestChannel(t *testing.T) {
+	stereo, _ := NewAudioSegment(
+		newSegment16(10, 100, -10, -100, 10, 100).RawData(),
+		Channels(2), SampleWidth(2), FrameWidth(4), FrameRate(1000),
+	)
+	loudest, err := stereo.LoudestChannel()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, loudest)
+
+	loudest, err = newSegment16(1, 2, 3).LoudestChannel()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, loudest)
+
+	// Ties go to the first channel
+	silence, _ := NewSilentAudioSegmentWith(100, 8000, 2, 2)
+	loudest, err = silence.LoudestChannel()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, loudest)
+}
+
+func TestEqualApprox(t *testing.T) {
+	seg := newSegment16(100, -100, 1000)
+
+	assert.True(t, seg.EqualApprox(newSegment16(101, -99, 998), 2))
+	assert.False(t, seg.EqualApprox(newSegment16(101, -99, 997), 2))
+	assert.False(t, seg.EqualApprox(newSegment16(100, -100), 2))
+}
+
+func TestNewSilentAudioSegmentWith(t *testing.T) {
+	seg, err := NewSilentAudioSegmentWith(10, 8000, 3, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 80*2*4, seg.Len())
+	assert.Equal(t, int64(10), seg.Duration())
+	assert.Equal(t, float64(0), seg.Max())
+
+	seg, err = NewSilentAudioSegmentWith(10, 8000, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{0x80}, 80), seg.RawData())
+
+	_, err = NewSilentAudioSegmentWith(10, 8000, 5, 1)
+	assert.Error(t, err)
+}
+
+func TestOverlayNegativePosition(t *testing.T) {
+	base := newSegment16(1, 1, 1)
+	overlaid, err := base.Overlay(newSegment16(10, 20, 30), &OverlayConfig{Position: -2})
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(10, 20, 31, 1, 1).RawData(), overlaid.RawData())
+
+	// A layer starting at -500ms
+	base = newSineSegment(100, 0.5, 1000, 8000, 1)
+	other := newSineSegment(200, 0.5, 1000, 8000, 1)
+	overlaid, err = base.Overlay(other, &OverlayConfig{Position: -500})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), overlaid.Duration())
+
+	head, _ := overlaid.Slice(0, 500)
+	expected, _ := other.Slice(0, 500)
+	assert.True(t, expected.Equal(head))
+}
+
+func TestOverlayBounds(t *testing.T) {
+	base := newSegment16(1, 1, 1, 1, 1)
+	long := newSegment16(10, 20, 30, 40, 50, 60, 70, 80)
+
+	// A long `other` onto a short base at a late position, it's cut at the end.
+	overlaid, err := base.Overlay(long, &OverlayConfig{Position: 3, LoopToEnd: true})
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 1, 1, 11, 21).RawData(), overlaid.RawData())
+
+	// Looping a short `other` ends in the middle of it.
+	overlaid, err = base.Overlay(newSegment16(10, 20), &OverlayConfig{Position: 2, LoopToEnd: true})
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 1, 11, 21, 11).RawData(), overlaid.RawData())
+
+	// Beyond the end, nothing's overlaid.
+	for _, position := range []int64{5, 100} {
+		overlaid, err = base.Overlay(long, &OverlayConfig{Position: position, LoopCount: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, base.RawData(), overlaid.RawData())
+	}
+
+	// An empty `other` doesn't loop forever.
+	empty := newSegment16()
+	overlaid, err = base.Overlay(empty, &OverlayConfig{LoopToEnd: true})
+	assert.NoError(t, err)
+	assert.Equal(t, base.RawData(), overlaid.RawData())
+
+	// The config is not modified.
+	config := &OverlayConfig{LoopToEnd: true}
+	_, err = base.Overlay(long, config)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, config.LoopCount)
+}
+
+func TestOverlayExtendTail(t *testing.T) {
+	base := newSegment16(1, 1, 1, 1, 1)
+	long := newSegment16(10, 20, 30, 40, 50)
+
+	// The tail of `other` rings out past the base
+	overlaid, err := base.Overlay(long, &OverlayConfig{Position: 3, ExtendTail: true})
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 1, 1, 11, 21, 30, 40, 50).RawData(), overlaid.RawData())
+
+	// Loops are extended too, and the gap after the base is silent
+	overlaid, err = base.Overlay(newSegment16(10, 20), &OverlayConfig{Position: 6, LoopCount: 2, ExtendTail: true})
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 1, 1, 1, 1, 0, 10, 20, 10, 20).RawData(), overlaid.RawData())
+
+	// Nothing to extend
+	overlaid, err = base.Overlay(newSegment16(10, 20), &OverlayConfig{Position: 1, ExtendTail: true})
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 11, 21, 1, 1).RawData(), overlaid.RawData())
+
+	// Ignored with LoopToEnd
+	overlaid, err = base.Overlay(long, &OverlayConfig{Position: 3, LoopToEnd: true, ExtendTail: true})
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(1, 1, 1, 11, 21).RawData(), overlaid.RawData())
+
+	// With pre-roll
+	overlaid, err = base.Overlay(newSegment16(10, 20, 30, 40, 50, 60, 70, 80), &OverlayConfig{Position: -2, ExtendTail: true})
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(10, 20, 31, 41, 51, 61, 71, 80).RawData(), overlaid.RawData())
+}
+
+func BenchmarkForkWithSampleWidth8Bit(b *testing.B) {
+	seg, err := NewAudioSegment(bytes.Repeat([]byte{0x80, 0x90, 0x70, 0xff}, 25<<20),
+		SampleWidth(1), FrameRate(8000), Channels(1), FrameWidth(1))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := seg.ForkWithSampleWidth(2); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRMS8Bit(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(128 + 100*math.Sin(float64(i)/10))
+	}
+	seg, err := NewAudioSegment(data, SampleWidth(1), FrameRate(8000), Channels(1), FrameWidth(1))
+	assert.NoError(t, err)
+
+	// The RMS is in the 8-bit scale, the level is the same as the 16-bit fork.
+	forked, err := seg.ForkWithSampleWidth(2)
+	assert.NoError(t, err)
+	assert.InDelta(t, forked.RMS()/256, seg.RMS(), 0.01)
+	assert.InDelta(t, float64(forked.DBFS()), float64(seg.DBFS()), 0.01)
+}
+
+func BenchmarkMaxRepeated(b *testing.B) {
+	seg := newSineSegment(440, 0.5, 60*1000, 44100, 2)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		seg.Max()
+		seg.Duration()
+	}
+}
+
+func TestCachedValuesConcurrent(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 100, 8000, 1)
+
+	var wg sync.WaitGroup
+	results := make([]float64, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = seg.RMS() + seg.Max() + float64(seg.Duration()) + seg.FrameCount()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		assert.Equal(t, results[0], r)
+	}
+}
+
+func TestForkWithFrameRateRMS(t *testing.T) {
+	// RMS isn't rate-invariant through Ratecv, the cached value must not be carried.
+	seg := newSineSegment(3000, 0.5, 500, 8000, 1)
+	rms := seg.RMS()
+
+	resampled, err := seg.ForkWithFrameRate(16000)
+	assert.NoError(t, err)
+	fresh, _ := NewAudioSegment(resampled.RawData(), SampleWidth(2), FrameRate(16000), Channels(1), FrameWidth(2))
+	assert.Equal(t, fresh.RMS(), resampled.RMS())
+	assert.Less(t, resampled.RMS(), rms*0.9)
+}
+
+func TestApplyGainToChannel(t *testing.T) {
+	stereo, err := NewAudioSegment(newSegment16(1000, 2000, -1000, -2000).RawData(),
+		SampleWidth(2), FrameRate(1000), Channels(2), FrameWidth(4))
+	assert.NoError(t, err)
+
+	adjusted, err := stereo.ApplyGainToChannel(1, -6.0206)
+	assert.NoError(t, err)
+	samples, _ := audioop.GetSamples(adjusted.RawData(), 2)
+	assert.Equal(t, int32(1000), samples[0])
+	assert.InDelta(t, 1000, samples[1], 1)
+	assert.Equal(t, int32(-1000), samples[2])
+	assert.InDelta(t, -1000, samples[3], 1)
+
+	_, err = stereo.ApplyGainToChannel(2, -6)
+	assert.Error(t, err)
+}
+
+func TestBalance(t *testing.T) {
+	stereo, err := NewAudioSegment(newSegment16(1000, 2000, -1000, -2000).RawData(),
+		SampleWidth(2), FrameRate(1000), Channels(2), FrameWidth(4))
+	assert.NoError(t, err)
+
+	right, err := stereo.Balance(0.5)
+	assert.NoError(t, err)
+	samples, _ := audioop.GetSamples(right.RawData(), 2)
+	assert.Equal(t, []int32{500, 2000, -500, -2000}, samples)
+
+	left, err := stereo.Balance(-1)
+	assert.NoError(t, err)
+	samples, _ = audioop.GetSamples(left.RawData(), 2)
+	assert.Equal(t, []int32{1000, 0, -1000, 0}, samples)
+
+	same, err := stereo.Balance(0)
+	assert.NoError(t, err)
+	assert.Same(t, stereo, same)
+
+	_, err = stereo.Balance(1.5)
+	assert.Error(t, err)
+	_, err = stereo.Balance(math.NaN())
+	assert.Error(t, err)
+	_, err = newSegment16(1, 2).Balance(0.5)
+	assert.Error(t, err)
+}
+
+func TestReverseStereo(t *testing.T) {
+	left := newSineSegment(440, 0.5, 100, 8000, 1)
+	right := newSineSegment(1000, 0.2, 100, 8000, 1)
+	stereo, err := NewAudioSegment(interleave([]*AudioSegment{left, right}),
+		SampleWidth(2), FrameRate(8000), Channels(2), FrameWidth(4))
+	assert.NoError(t, err)
+
+	reversed, err := stereo.Reverse()
+	assert.NoError(t, err)
+
+	channels, err := reversed.SplitToMono()
+	assert.NoError(t, err)
+	expectedLeft, _ := left.Reverse()
+	expectedRight, _ := right.Reverse()
+	assert.Equal(t, expectedLeft.RawData(), channels[0].RawData())
+	assert.Equal(t, expectedRight.RawData(), channels[1].RawData())
+
+	// Reversing twice gives the original audio
+	twice, _ := reversed.Reverse()
+	assert.Equal(t, stereo.RawData(), twice.RawData())
+
+	mono, err := newSegment16(1, 2, 3).Reverse()
+	assert.NoError(t, err)
+	assert.Equal(t, newSegment16(3, 2, 1).RawData(), mono.RawData())
+}
+
+func TestPeakPosition(t *testing.T) {
+	frame, sample := newSegment16(1, -5, 3, 5, -2).PeakPosition()
+	assert.Equal(t, 1, frame)
+	assert.Equal(t, int32(-5), sample)
+
+	// The frame index of a stereo sample
+	stereo, err := NewAudioSegment([]byte{1, 0, 2, 0, 3, 0, 9, 0, 4, 0, 0, 0},
+		SampleWidth(2), FrameRate(1000), Channels(2), FrameWidth(4))
+	assert.NoError(t, err)
+	frame, sample = stereo.PeakPosition()
+	assert.Equal(t, 1, frame)
+	assert.Equal(t, int32(9), sample)
+
+	// 8-bit samples are signed
+	seg8, err := NewAudioSegment([]byte{0x80, 0x90, 0x20, 0x80},
+		SampleWidth(1), FrameRate(1000), Channels(1), FrameWidth(1))
+	assert.NoError(t, err)
+	frame, sample = seg8.PeakPosition()
+	assert.Equal(t, 2, frame)
+	assert.Equal(t, int32(-96), sample)
+
+	empty, _ := NewEmptyAudioSegment()
+	frame, sample = empty.PeakPosition()
+	assert.Equal(t, 0, frame)
+	assert.Equal(t, int32(0), sample)
+}
+
+func TestPipe(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 100, 8000, 1)
+
+	var calls []int
+	out, err := seg.Pipe(
+		func(s *AudioSegment) (*AudioSegment, error) { calls = append(calls, 0); return s.ApplyGain(-6) },
+		func(s *AudioSegment) (*AudioSegment, error) { calls = append(calls, 1); return s.Slice(0, 50) },
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, calls)
+	expected, _ := seg.ApplyGain(-6)
+	expected, _ = expected.Slice(0, 50)
+	assert.True(t, expected.Equal(out))
+
+	// Short-circuits on error
+	calls = nil
+	_, err = seg.Pipe(
+		func(s *AudioSegment) (*AudioSegment, error) { calls = append(calls, 0); return s.Slice(50, 0) },
+		func(s *AudioSegment) (*AudioSegment, error) { calls = append(calls, 1); return s, nil },
+	)
+	assert.Error(t, err)
+	assert.Equal(t, []int{0}, calls)
+
+	out, err = seg.Pipe()
+	assert.NoError(t, err)
+	assert.Same(t, seg, out)
+
+	_, err = seg.Pipe(nil)
+	assert.Error(t, err)
+}
+
+func TestEachFrame(t *testing.T) {
+	stereo, err := NewAudioSegment([]byte{1, 0, 2, 0, 3, 0, 0xFC, 0xFF, 5, 0, 6, 0},
+		SampleWidth(2), FrameRate(1000), Channels(2), FrameWidth(4))
+	assert.NoError(t, err)
+
+	var frames [][]int32
+	err = stereo.EachFrame(func(frameIndex int, samples []int32) error {
+		assert.Equal(t, len(frames), frameIndex)
+		frames = append(frames, append([]int32(nil), samples...))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int32{{1, 2}, {3, -4}, {5, 6}}, frames)
+
+	// Returning an error stops the iteration
+	stop := errors.New("stop")
+	count := 0
+	err = stereo.EachFrame(func(frameIndex int, samples []int32) error {
+		count++
+		if frameIndex == 1 {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 2, count)
+
+	seg8, _ := NewAudioSegment([]byte{0x80, 0xFF, 0x00},
+		SampleWidth(1), FrameRate(1000), Channels(1), FrameWidth(1))
+	var samples8 []int32
+	assert.NoError(t, seg8.EachFrame(func(_ int, samples []int32) error {
+		samples8 = append(samples8, samples[0])
+		return nil
+	}))
+	assert.Equal(t, []int32{0, 127, -128}, samples8)
+
+	assert.Error(t, stereo.EachFrame(nil))
+}
+
+func TestMetadata(t *testing.T) {
+	tags := map[string]string{"artist": "godub", "title": "sine"}
+	seg, err := newSineSegment(440, 0.5, 500, 8000, 1).WithMetadata(tags)
+	assert.NoError(t, err)
+	assert.Equal(t, tags, seg.Metadata())
+
+	// The tags are copied both ways
+	tags["title"] = "changed"
+	seg.Metadata()["artist"] = "changed"
+	assert.Equal(t, map[string]string{"artist": "godub", "title": "sine"}, seg.Metadata())
+
+	// Carried through processing
+	sliced, _ := seg.Slice(100, 200)
+	gained, _ := sliced.ApplyGain(-3)
+	stretched, _ := gained.TimeStretch(1.5)
+	joined, _ := Concat(stretched, newSineSegment(880, 0.5, 100, 8000, 1))
+	assert.Equal(t, seg.Metadata(), joined.Metadata())
+
+	cleared, err := seg.WithMetadata(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, cleared.Metadata())
+	assert.Nil(t, newSegment16(1, 2).Metadata())
+}
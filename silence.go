@@ -2,7 +2,9 @@ package godub
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
@@ -11,22 +13,31 @@ import (
 // InvalidFile error type
 type InvalidFile struct {
 	OriginalError string
+	// err is the sentinel error wrapped, e.g. ErrEmptyAudio.
+	err error
 }
 
 func (invalidFile *InvalidFile) Error() string {
 	return fmt.Sprintf("InvalidFile Error: %v", invalidFile.OriginalError)
 }
 
+func (invalidFile *InvalidFile) Unwrap() error {
+	return invalidFile.err
+}
+
 // Check if audio is empty
 func checkEmptyAudio(seg *AudioSegment) error {
 
 	rms := seg.RMS()
 	if rms == 0 {
-		return &InvalidFile{"Empty file. Check audio"}
+		return &InvalidFile{OriginalError: "Empty file. Check audio", err: ErrEmptyAudio}
 	}
 	return nil
 }
 
+// defaultSilenceChunkSize is the default chunk size (ms) of LeadingSilence and TrailingSilence.
+const defaultSilenceChunkSize = 10
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -41,12 +52,6 @@ func max(a, b int) int {
 	return b
 }
 
-func matchTargetAmp(sound *AudioSegment, targetDBFS Volume) *AudioSegment {
-	changeInDBFS := targetDBFS - sound.DBFS()
-	ret, _ := sound.ApplyGain(changeInDBFS)
-	return ret
-}
-
 func DetectSilence(seg *AudioSegment, minSilenceLen int64, silenceThresh Volume, seekStep int) [][]int64 {
 	segLen := seg.Duration()
 
@@ -65,69 +70,216 @@ func DetectSilence(seg *AudioSegment, minSilenceLen int64, silenceThresh Volume,
 
 	// check successive (1 ms by default) chunk of sound for silence
 	// try a chunk at every "seek step" (or every chunk for a seek step == 1)
-	lastSliceStart := segLen - minSilenceLen
+	sliceStarts := sliceStartPositions(segLen-minSilenceLen, int64(seekStep))
+
+	for _, i := range sliceStarts {
+		audioSlice, _ := seg.Slice(i, i+minSilenceLen)
+		if audioSlice.RMS() <= silThresh {
+			silenceStarts = append(silenceStarts, i)
+
+		}
+	}
+	return combineSilentRanges(silenceStarts, minSilenceLen, int64(seekStep))
+}
+
+// SilenceRange is a silent range [Start, End) of audio.
+type SilenceRange struct {
+	Start time.Duration
+	End   time.Duration
+}
 
+// Duration returns the length of the range.
+func (r SilenceRange) Duration() time.Duration {
+	return r.End - r.Start
+}
+
+// DetectSilenceRanges 是DetectSilence使用time.Duration的版本
+//
+// 参数:
+//   - seg: 待检测的音频片段
+//   - minSilenceLen: 最短静音长度
+//   - silenceThresh: 静音阈值(dBFS)
+//   - seekStep: 检测步长,不足1毫秒时按1毫秒处理
+//
+// 返回:
+//   - []SilenceRange: 静音区间列表
+//
+// 说明:
+//   - 与DetectSilence的检测结果相同(精度为毫秒),只是参数和返回值都带有单位,避免秒与毫秒混用
+func DetectSilenceRanges(seg *AudioSegment, minSilenceLen time.Duration, silenceThresh Volume, seekStep time.Duration) []SilenceRange {
+	step := max(int(seekStep.Milliseconds()), 1)
+	return toSilenceRanges(DetectSilence(seg, minSilenceLen.Milliseconds(), silenceThresh, step))
+}
+
+// toSilenceRanges converts [start, end] pairs of milliseconds to SilenceRange.
+func toSilenceRanges(ranges [][]int64) []SilenceRange {
+	silenceRanges := make([]SilenceRange, 0, len(ranges))
+	for _, r := range ranges {
+		silenceRanges = append(silenceRanges, SilenceRange{
+			Start: time.Duration(r[0]) * time.Millisecond,
+			End:   time.Duration(r[1]) * time.Millisecond,
+		})
+	}
+	return silenceRanges
+}
+
+// DetectSilenceFrames 是DetectSilence的帧精度版本
+//
+// 参数:
+//   - seg: 待检测的音频片段
+//   - minSilenceFrames: 最短静音长度(帧)
+//   - silenceThresh: 静音阈值(dBFS)
+//   - seekStep: 检测步长(帧)
+//
+// 返回:
+//   - [][]int64: 静音区间列表,每个区间为[起始帧, 结束帧)
+//
+// 说明:
+//   - 毫秒版本在低采样率(如8kHz电话音频)下1ms只有8帧,较短的间隙容易被漏检
+//   - 本函数直接按帧索引原始数据计算RMS,不经过毫秒取整
+func DetectSilenceFrames(seg *AudioSegment, minSilenceFrames int64, silenceThresh Volume, seekStep int) [][]int64 {
+	frameCount := int64(seg.FrameCount())
+
+	if minSilenceFrames <= 0 || seekStep <= 0 || frameCount < minSilenceFrames {
+		var emp [][]int64
+		return emp
+	}
+
+	var silThresh = silenceThresh.ToRatio(true) * seg.MaxPossibleAmplitude()
+
+	var silenceStarts []int64
+	for _, i := range sliceStartPositions(frameCount-minSilenceFrames, int64(seekStep)) {
+		if calculateRMSForFrames(seg, i, i+minSilenceFrames) <= silThresh {
+			silenceStarts = append(silenceStarts, i)
+		}
+	}
+
+	return combineSilentRanges(silenceStarts, minSilenceFrames, int64(seekStep))
+}
+
+// sliceStartPositions returns every start position from 0 to lastSliceStart
+// stepped by seekStep. lastSliceStart is always included to make sure the
+// last portion of the audio is searched.
+func sliceStartPositions(lastSliceStart int64, seekStep int64) []int64 {
 	var sliceStarts []int64
-	for i := int64(0); i < lastSliceStart+1; i += int64(seekStep) {
+	for i := int64(0); i < lastSliceStart+1; i += seekStep {
 		sliceStarts = append(sliceStarts, i)
 	}
 
-	// guarantee lastSliceStart is included in the range
-	// to make sure the last portion of the audio is searched
-	if (lastSliceStart % int64(seekStep)) != 0 {
+	if (lastSliceStart % seekStep) != 0 {
 		sliceStarts = append(sliceStarts, lastSliceStart)
 	}
 
-	for _, i := range sliceStarts {
-		audioSlice, _ := seg.Slice(i, i+minSilenceLen)
-		if audioSlice.RMS() <= silThresh {
-			silenceStarts = append(silenceStarts, i)
+	return sliceStarts
+}
 
-		}
-	}
+// combineSilentRanges combines the detected silence starts into ranges (start - end).
+// The unit of positions is decided by the caller, either milliseconds or frames.
+func combineSilentRanges(silenceStarts []int64, minSilenceLen int64, seekStep int64) [][]int64 {
 	// short circuit when there is no silence
 	if len(silenceStarts) == 0 {
 		var silentRanges [][]int64
 		return silentRanges
 	}
 
-	// combine the silence we detected into ranges (start ms - end ms)
 	var silentRanges [][]int64
 
 	prevI, silenceStarts := silenceStarts[0], silenceStarts[1:]
 	currentRangeStart := prevI
 
 	for _, silenceStartI := range silenceStarts {
-		var continuous bool
-		var silenceHasGap bool
-		if silenceStartI == prevI+int64(seekStep) {
-			continuous = true
-		} else {
-			continuous = false
-		}
+		continuous := silenceStartI == prevI+seekStep
 
 		// sometimes two small blips are enough for one particular slice to be
 		// non-silent, despite the silence all running together. Just combine
 		// the two overlapping silent ranges.
-
-		if silenceStartI > prevI+minSilenceLen {
-			silenceHasGap = true
-		} else {
-			silenceHasGap = false
-		}
+		silenceHasGap := silenceStartI > prevI+minSilenceLen
 
 		if !continuous && silenceHasGap {
 			silentRanges = append(silentRanges, []int64{currentRangeStart, prevI + minSilenceLen})
 			currentRangeStart = silenceStartI
 		}
 		prevI = silenceStartI
-
 	}
 	silentRanges = append(silentRanges, []int64{currentRangeStart, prevI + minSilenceLen})
 
 	return silentRanges
 }
 
+// MergeCloseRanges 合并间隔小于gap的相邻静音区间
+//
+// 参数:
+//   - ranges: 按起始位置排序的区间列表,例如DetectSilence或DetectSilenceConcurrent的结果
+//   - gap: 间隔阈值,单位与ranges相同(毫秒或帧)
+//
+// 说明:
+//   - 静音中夹杂的短促噪声会把一段静音分成多段,合并后得到连续的静音区间
+//   - 下一个区间的起始位置减去上一个区间的结束位置小于gap时,两个区间合并为一个
+//   - gap <= 0时不合并,返回的总是新的列表,不修改ranges
+func MergeCloseRanges(ranges [][]int64, gap int64) [][]int64 {
+	var merged [][]int64
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r[0]-merged[n-1][1] < gap {
+			merged[n-1][1] = max64(merged[n-1][1], r[1])
+			continue
+		}
+		merged = append(merged, []int64{r[0], r[1]})
+	}
+	return merged
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// SilenceRangesToEDL 把静音区间转换为CSV格式的剪辑列表(EDL),便于导入视频剪辑软件
+//
+// 参数:
+//   - ranges: 按起始位置排序的静音区间(毫秒),例如DetectSilence的结果
+//   - frameRate: 视频帧率,例如25或29.97,用于计算时间码
+//
+// 返回:
+//   - CSV文本,第一行是表头event,type,start,end,之后每行一个区间
+//
+// 说明:
+//   - 静音区间的type为cut,静音之前和静音之间的部分为keep,按时间顺序排列
+//   - 时间码格式为HH:MM:SS:FF,毫秒四舍五入到最近的帧,帧号按取整后的帧率计算(不丢帧)
+//   - ranges中不包含音频的总时长,最后一个静音之后的部分不会输出
+//   - frameRate不是正数时返回空字符串
+func SilenceRangesToEDL(ranges [][]int64, frameRate float64) string {
+	if frameRate <= 0 {
+		return ""
+	}
+
+	timecode := func(ms int64) string {
+		frames := int64(math.Round(float64(ms) * frameRate / 1000))
+		base := max64(int64(math.Round(frameRate)), 1)
+		seconds := frames / base
+		return fmt.Sprintf("%02d:%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60, frames%base)
+	}
+
+	var b strings.Builder
+	b.WriteString("event,type,start,end\n")
+	event := 0
+	write := func(kind string, start, end int64) {
+		event++
+		fmt.Fprintf(&b, "%d,%s,%s,%s\n", event, kind, timecode(start), timecode(end))
+	}
+
+	var prevEnd int64
+	for _, r := range ranges {
+		if r[0] > prevEnd {
+			write("keep", prevEnd, r[0])
+		}
+		write("cut", r[0], r[1])
+		prevEnd = r[1]
+	}
+	return b.String()
+}
+
 func DetectNonsilent(seg *AudioSegment, minSilenceLen int64, silenceThresh Volume, seekStep int) [][]int64 {
 
 	silentRanges := DetectSilence(seg, minSilenceLen, silenceThresh, seekStep)
@@ -166,75 +318,257 @@ func DetectNonsilent(seg *AudioSegment, minSilenceLen int64, silenceThresh Volum
 
 // SplitOnSilence ...
 func SplitOnSilence(seg *AudioSegment, minSilenceLen int64, silenceThresh Volume, keepSilence int, seekStep int) ([]*AudioSegment, [][]float32, error) {
+	return SplitOnSilenceWithOptions(seg, SplitOnSilenceOptions{
+		MinSilenceLen: minSilenceLen,
+		SilenceThresh: silenceThresh,
+		KeepSilence:   keepSilence,
+		SeekStep:      seekStep,
+	})
+}
+
+// KeepSilenceMode decides how the silence around the chunks is kept by SplitOnSilenceWithOptions.
+type KeepSilenceMode int
+
+const (
+	// KeepSilenceFixed keeps up to KeepSilence ms of silence on both sides of every chunk,
+	// it's the behavior of SplitOnSilence. The silence between two chunks may be kept by both.
+	KeepSilenceFixed KeepSilenceMode = iota
+	// KeepSilenceHalf splits the silence between two chunks at the middle, each chunk gets
+	// one half. The leading and trailing silence of the audio is kept entirely, so the
+	// chunks join back into the original audio without duplicated or missing silence.
+	KeepSilenceHalf
+)
+
+// SplitOnSilenceOptions configures SplitOnSilenceWithOptions, the fields are the params of SplitOnSilence.
+type SplitOnSilenceOptions struct {
+	// MinSilenceLen is the minimum length (ms) of silence to split at.
+	MinSilenceLen int64
+	SilenceThresh Volume
+	// KeepSilence is the silence (ms) kept on both sides of chunks, only for KeepSilenceFixed.
+	KeepSilence     int
+	KeepSilenceMode KeepSilenceMode
+	// SeekStep is the step (ms) of silence detection.
+	SeekStep int
+	// Concurrent detects silence with DetectNonsilentConcurrent.
+	Concurrent bool
+}
 
+// SplitOnSilenceWithOptions 在静音处切分音频,与SplitOnSilence相同,但可以选择静音的保留方式
+//
+// 参数:
+//   - seg: 待切分的音频片段
+//   - opts: 切分配置,见SplitOnSilenceOptions
+//
+// 返回:
+//   - []*AudioSegment: 切分后的音频片段
+//   - [][]float32: 每个片段的[起始, 结束]时间(秒)
+//
+// 说明:
+//   - KeepSilenceFixed: 每个片段两侧各保留最多KeepSilence毫秒的静音,相邻片段之间的静音可能被重复保留
+//   - KeepSilenceHalf: 相邻片段之间的静音从中间平分,首尾的静音全部保留,片段拼接后与原音频相同
+//   - 检测静音前音频会先被归一化到-20 dBFS
+func SplitOnSilenceWithOptions(seg *AudioSegment, opts SplitOnSilenceOptions) ([]*AudioSegment, [][]float32, error) {
 	chunks := []*AudioSegment{}
 	var timings [][]float32
 
 	err := checkEmptyAudio(seg)
-
 	if err != nil {
 		return chunks, timings, err
 	}
 
-	normAudio, _ := seg.derive(seg.RawData())
-
-	normAudio = matchTargetAmp(seg, -20.0)
+	normAudio, err := seg.MatchTargetAmplitude(-20.0)
+	if err != nil {
+		return chunks, timings, err
+	}
 
-	notSilenceRanges := DetectNonsilent(normAudio, minSilenceLen, silenceThresh, seekStep)
+	var notSilenceRanges [][]int64
+	if opts.Concurrent {
+		notSilenceRanges = DetectNonsilentConcurrent(normAudio, opts.MinSilenceLen, opts.SilenceThresh, opts.SeekStep)
+	} else {
+		notSilenceRanges = DetectNonsilent(normAudio, opts.MinSilenceLen, opts.SilenceThresh, opts.SeekStep)
+	}
 
-	startMin := int64(0)
+	if len(notSilenceRanges) == 0 {
+		return chunks, timings, nil
+	}
 
 	if len(notSilenceRanges) == 1 {
 		chunks = append(chunks, seg)
-		timings = append(timings, []float32{0.0, float32(seg.Len())})
+		timings = append(timings, []float32{0.0, float32(seg.Duration()) / 1000.0})
 		return chunks, timings, nil
+	}
 
+	for _, r := range chunkRanges(notSilenceRanges, seg.Duration(), opts) {
+		chunk, _ := seg.Slice(r[0], r[1])
+		if chunk != nil {
+			chunks = append(chunks, chunk)
+			timings = append(timings, []float32{float32(r[0]) / 1000, float32(r[1]) / 1000.0})
+		}
 	}
-	for i := 0; i < len(notSilenceRanges)-1; i++ {
-		endMax := notSilenceRanges[i][1] + (notSilenceRanges[i+1][0]-notSilenceRanges[i][1]+1)/2
-		startI := max(int(startMin), int(notSilenceRanges[i][0])-keepSilence)
-		endI := min(int(endMax), int(notSilenceRanges[i][1])+keepSilence)
+	return chunks, timings, nil
+}
 
-		temp1, _ := seg.Slice(int64(startI), int64(endI))
-		if temp1 != nil {
-			chunks = append(chunks, temp1)
-			timings = append(timings, []float32{float32(startI) / 1000, float32(endI) / 1000.0})
+// chunkRanges extends the nonsilent ranges with the kept silence, see KeepSilenceMode.
+func chunkRanges(notSilenceRanges [][]int64, duration int64, opts SplitOnSilenceOptions) [][]int64 {
+	// The middle of the silence after the i-th range.
+	middle := func(i int) int64 {
+		if i == len(notSilenceRanges)-1 {
+			return duration
+		}
+		return notSilenceRanges[i][1] + (notSilenceRanges[i+1][0]-notSilenceRanges[i][1]+1)/2
+	}
 
+	ranges := make([][]int64, 0, len(notSilenceRanges))
+	start := int64(0)
+	for i, r := range notSilenceRanges {
+		end := middle(i)
+		if opts.KeepSilenceMode == KeepSilenceFixed {
+			keepSilence := int64(opts.KeepSilence)
+			start = int64(max(int(start), int(r[0]-keepSilence)))
+			end = int64(min(int(end), int(r[1]+keepSilence)))
 		}
+		ranges = append(ranges, []int64{start, end})
 
-		startMin = notSilenceRanges[i][1]
+		if opts.KeepSilenceMode == KeepSilenceFixed {
+			start = r[1]
+		} else {
+			start = end
+		}
 	}
+	return ranges
+}
 
-	startI := max(int(startMin), int(notSilenceRanges[len(notSilenceRanges)-1][0])-keepSilence)
-	endI := min(int(seg.Duration()), int(notSilenceRanges[len(notSilenceRanges)-1][1])+keepSilence)
-	temp2, _ := seg.Slice(int64(startI), int64(endI))
-	if temp2 != nil {
-		chunks = append(chunks, temp2)
-		timings = append(timings, []float32{float32(startI) / 1000, float32(endI) / 1000.0})
+// LeadingSilence 返回音频开头静音部分的长度(毫秒)
+//
+// 参数:
+//   - silenceThreshold: 静音阈值(dBFS),低于该值的块视为静音
+//   - chunkSize: 检测块的长度(毫秒),小于等于0时使用10ms
+//
+// 说明:
+//   - 从开头按块检测,遇到第一个不是静音的块为止
+//   - 结果是chunkSize的整数倍,整段都是静音时返回Duration()
+func (seg *AudioSegment) LeadingSilence(silenceThreshold Volume, chunkSize int) int64 {
+	if chunkSize <= 0 {
+		chunkSize = defaultSilenceChunkSize
+	}
 
+	duration := seg.Duration()
+	trimMS := int64(0)
+	for trimMS < duration {
+		chunk, err := seg.Slice(trimMS, trimMS+int64(chunkSize))
+		if err != nil || chunk.DBFS() >= silenceThreshold {
+			return trimMS
+		}
+		trimMS += int64(chunkSize)
 	}
-	return chunks, timings, nil
+
+	return duration
 }
 
-func detectLeadingSilence(sound *AudioSegment, silenceThreshold Volume, chunkSize int) int64 {
+// TrailingSilence 返回音频末尾静音部分的长度(毫秒),与LeadingSilence对称
+//
+// 说明:
+//   - 从末尾向前按块检测,遇到第一个不是静音的块为止
+//   - 整段都是静音时返回Duration()
+func (seg *AudioSegment) TrailingSilence(silenceThreshold Volume, chunkSize int) int64 {
+	if chunkSize <= 0 {
+		chunkSize = defaultSilenceChunkSize
+	}
+
+	duration := seg.Duration()
 	trimMS := int64(0)
-	for trimMS < sound.Duration() {
-		temp1, _ := sound.Slice(trimMS, trimMS+int64(chunkSize))
-		if temp1.DBFS() < silenceThreshold {
-			trimMS += int64(chunkSize)
-		} else {
-			break
+	for trimMS < duration {
+		start := duration - trimMS - int64(chunkSize)
+		if start < 0 {
+			start = 0
+		}
+		chunk, err := seg.Slice(start, duration-trimMS)
+		if err != nil || chunk.DBFS() >= silenceThreshold {
+			return trimMS
 		}
+		trimMS += int64(chunkSize)
+	}
+
+	return duration
+}
+
+// AutoTrim 去掉音频开头和末尾的静音,返回中间的部分
+//
+// 参数:
+//   - threshold: 静音阈值(dBFS),低于该值的块视为静音
+//   - chunkSize: 检测块的长度(毫秒),小于等于0时使用10ms
+//
+// 说明:
+//   - 即按LeadingSilence和TrailingSilence的结果截取,精度为chunkSize
+//   - 整段都低于阈值时返回与seg格式相同的空音频片段
+func (seg *AudioSegment) AutoTrim(threshold Volume, chunkSize int) (*AudioSegment, error) {
+	duration := seg.Duration()
+	leading := seg.LeadingSilence(threshold, chunkSize)
+	if leading >= duration {
+		return seg.derive(nil)
 	}
 
-	return trimMS
+	trailing := seg.TrailingSilence(threshold, chunkSize)
+	return seg.Slice(leading, duration-trailing)
+}
+
+// SplitOption configures SplitAudio and SplitAudioConcurrent.
+type SplitOption func(*splitConfig)
+
+// Logger receives the diagnostics of splitting, *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+type splitConfig struct {
+	onProgress func(segmentsDone int, posSeconds float64)
+	logger     Logger
+}
+
+// WithProgress sets a callback which is called every time a segment is split off,
+// with the number of segments done and the position (seconds) reached so far.
+func WithProgress(onProgress func(segmentsDone int, posSeconds float64)) SplitOption {
+	return func(c *splitConfig) {
+		c.onProgress = onProgress
+	}
+}
+
+// WithLogger sets the logger of diagnostics such as falling back to the threshold
+// when no silence is found. Nothing is logged by default.
+func WithLogger(logger Logger) SplitOption {
+	return func(c *splitConfig) {
+		c.logger = logger
+	}
+}
+
+func newSplitConfig(opts []SplitOption) *splitConfig {
+	c := &splitConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *splitConfig) logf(format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, args...)
+	}
+}
+
+func (c *splitConfig) progress(segmentsDone int, posSeconds float64) {
+	if c.onProgress != nil {
+		c.onProgress(segmentsDone, posSeconds)
+	}
 }
 
 // SplitAudio 将音频文件按照指定的目标长度在静音处切分成多个片段
 // audioFile: 音频文件路径
 // targetLen: 目标长度(秒)，默认30分钟
 // win: 检测窗口大小(秒)，默认60秒
-func SplitAudio(audioFile string, targetLen float64, win float64) ([][]float64, error) {
+// opts: 可选配置,如WithProgress设置进度回调,WithLogger设置日志(默认不输出任何内容)
+func SplitAudio(audioFile string, targetLen float64, win float64, opts ...SplitOption) ([][]float64, error) {
+	config := newSplitConfig(opts)
+
 	if targetLen == 0 {
 		targetLen = 30 * 60 // 默认30分钟
 	}
@@ -245,17 +579,17 @@ func SplitAudio(audioFile string, targetLen float64, win float64) ([][]float64,
 	// 打开音频文件
 	file, err := os.Open(audioFile)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file: %v", err)
+		return nil, fmt.Errorf("error opening file: %w", err)
 	}
 	defer file.Close()
 
 	// 加载音频
 	audio, err := NewLoader().Load(file)
 	if err != nil {
-		return nil, fmt.Errorf("error loading audio: %v", err)
+		return nil, fmt.Errorf("error loading audio: %w", err)
 	}
 
-	duration := float64(audio.Duration() / 1000) // 转换为秒
+	duration := float64(audio.Duration()) / 1000 // 转换为秒
 	if duration <= targetLen+win {
 		return [][]float64{{0, duration}}, nil
 	}
@@ -267,6 +601,7 @@ func SplitAudio(audioFile string, targetLen float64, win float64) ([][]float64,
 	for pos < duration {
 		if duration-pos <= targetLen {
 			segments = append(segments, []float64{pos, duration})
+			config.progress(len(segments), duration)
 			break
 		}
 
@@ -277,17 +612,17 @@ func SplitAudio(audioFile string, targetLen float64, win float64) ([][]float64,
 		// 切片获取检测区间的音频
 		windowAudio, err := audio.Slice(ws, we)
 		if err != nil {
-			return nil, fmt.Errorf("error slicing audio: %v", err)
+			return nil, fmt.Errorf("error slicing audio: %w", err)
 		}
 
 		// 检测静音区域
-		silenceRegions := DetectSilence(windowAudio, int64(safeMargin*1000), Volume(-30), 1)
+		silenceRegions := DetectSilenceRanges(windowAudio, time.Duration(safeMargin*float64(time.Second)), Volume(-30), time.Millisecond)
 
-		// 将毫秒单位的静音区域转换为秒，并调整偏移
+		// 将静音区域转换为秒，并调整偏移
 		var validRegions [][]float64
 		for _, region := range silenceRegions {
-			start := float64(region[0])/1000 + (threshold - win)
-			end := float64(region[1])/1000 + (threshold - win)
+			start := region.Start.Seconds() + (threshold - win)
+			end := region.End.Seconds() + (threshold - win)
 
 			// 筛选长度足够且位置适合的静默区域
 			if (end-start) >= (safeMargin*2) &&
@@ -301,14 +636,14 @@ func SplitAudio(audioFile string, targetLen float64, win float64) ([][]float64,
 		if len(validRegions) > 0 {
 			splitAt = validRegions[0][0] + safeMargin // 在静默区域起始点后0.5秒处切分
 		} else {
-			fmt.Printf("No valid silence regions found for %s at %.1fs, using threshold\n",
-				audioFile, threshold)
+			config.logf("No valid silence regions found for %s at %.1fs, using threshold", audioFile, threshold)
 		}
 
 		segments = append(segments, []float64{pos, splitAt})
 		pos = splitAt
+		config.progress(len(segments), pos)
 	}
 
-	fmt.Printf("Audio split completed %d segments\n", len(segments))
+	config.logf("Audio split completed %d segments", len(segments))
 	return segments, nil
 }
@@ -32,18 +32,7 @@ func DetectSilenceConcurrent(seg *AudioSegment, minSilenceLen int64, silenceThre
 
 	// check successive (1 ms by default) chunk of sound for silence
 	// try a chunk at every "seek step" (or every chunk for a seek step == 1)
-	lastSliceStart := segLen - minSilenceLen
-
-	var sliceStarts []int64
-	for i := int64(0); i < lastSliceStart+1; i += int64(seekStep) {
-		sliceStarts = append(sliceStarts, i)
-	}
-
-	// guarantee lastSliceStart is included in the range
-	// to make sure the last portion of the audio is searched
-	if (lastSliceStart % int64(seekStep)) != 0 {
-		sliceStarts = append(sliceStarts, lastSliceStart)
-	}
+	sliceStarts := sliceStartPositions(segLen-minSilenceLen, int64(seekStep))
 
 	// 并发处理音频片段
 	numWorkers := runtime.NumCPU()
@@ -110,54 +99,20 @@ func DetectSilenceConcurrent(seg *AudioSegment, minSilenceLen int64, silenceThre
 		}
 	}
 
-	// short circuit when there is no silence
-	if len(silenceStarts) == 0 {
-		var silentRanges [][]int64
-		return silentRanges
-	}
-
-	// combine the silence we detected into ranges (start ms - end ms)
-	var silentRanges [][]int64
-
-	prevI, silenceStarts := silenceStarts[0], silenceStarts[1:]
-	currentRangeStart := prevI
-
-	for _, silenceStartI := range silenceStarts {
-		var continuous bool
-		var silenceHasGap bool
-		if silenceStartI == prevI+int64(seekStep) {
-			continuous = true
-		} else {
-			continuous = false
-		}
-
-		// sometimes two small blips are enough for one particular slice to be
-		// non-silent, despite the silence all running together. Just combine
-		// the two overlapping silent ranges.
-
-		if silenceStartI > prevI+minSilenceLen {
-			silenceHasGap = true
-		} else {
-			silenceHasGap = false
-		}
-
-		if !continuous && silenceHasGap {
-			silentRanges = append(silentRanges, []int64{currentRangeStart, prevI + minSilenceLen})
-			currentRangeStart = silenceStartI
-		}
-		prevI = silenceStartI
-	}
-	silentRanges = append(silentRanges, []int64{currentRangeStart, prevI + minSilenceLen})
-
-	return silentRanges
+	return combineSilentRanges(silenceStarts, minSilenceLen, int64(seekStep))
 }
 
 // calculateRMSForSegmentOptimized 直接计算音频片段的RMS，避免创建新的AudioSegment
 // 这是性能优化的核心：直接在原始数据上操作，避免内存分配
 func calculateRMSForSegmentOptimized(seg *AudioSegment, start, end int64) float64 {
-	// 将时间转换为字节索引
-	startIndex := seg.parsePosition(start) * int(seg.frameWidth)
-	endIndex := seg.parsePosition(end) * int(seg.frameWidth)
+	// 将时间转换为帧索引
+	return calculateRMSForFrames(seg, int64(seg.parsePosition(start)), int64(seg.parsePosition(end)))
+}
+
+// calculateRMSForFrames 直接计算[startFrame, endFrame)范围内音频数据的RMS
+func calculateRMSForFrames(seg *AudioSegment, startFrame, endFrame int64) float64 {
+	startIndex := int(startFrame) * int(seg.frameWidth)
+	endIndex := int(endFrame) * int(seg.frameWidth)
 
 	if endIndex > len(seg.data) {
 		endIndex = len(seg.data)
@@ -167,9 +122,16 @@ func calculateRMSForSegmentOptimized(seg *AudioSegment, start, end int64) float6
 		return 0
 	}
 
-	// 直接在数据切片上计算RMS
-	dataSlice := seg.data[startIndex:endIndex]
-	rms, err := audioop.RMS(dataSlice, int(seg.sampleWidth))
+	if seg.sampleWidth == 1 {
+		// 8-bit audio is unsigned, measure it like AudioSegment.RMS
+		rms, err := audioop.RMSBias(seg.data[startIndex:endIndex], 1, -128)
+		if err != nil {
+			return 0
+		}
+		return rms
+	}
+
+	rms, err := audioop.RMS(seg.data[startIndex:endIndex], int(seg.sampleWidth))
 	if err != nil {
 		return 0
 	}
@@ -210,59 +172,23 @@ func DetectNonsilentConcurrent(seg *AudioSegment, minSilenceLen int64, silenceTh
 
 // SplitOnSilenceConcurrent 使用并发优化的静音检测进行音频分割
 func SplitOnSilenceConcurrent(seg *AudioSegment, minSilenceLen int64, silenceThresh Volume, keepSilence int, seekStep int) ([]*AudioSegment, [][]float32, error) {
-
-	chunks := []*AudioSegment{}
-	var timings [][]float32
-
-	err := checkEmptyAudio(seg)
-	if err != nil {
-		return chunks, timings, err
-	}
-
-	normAudio, _ := seg.derive(seg.RawData())
-	normAudio = matchTargetAmp(seg, -20.0)
-
-	// 使用并发版本进行静音检测
-	notSilenceRanges := DetectNonsilentConcurrent(normAudio, minSilenceLen, silenceThresh, seekStep)
-
-	startMin := int64(0)
-
-	if len(notSilenceRanges) == 1 {
-		chunks = append(chunks, seg)
-		timings = append(timings, []float32{0.0, float32(seg.Len())})
-		return chunks, timings, nil
-	}
-
-	for i := 0; i < len(notSilenceRanges)-1; i++ {
-		endMax := notSilenceRanges[i][1] + (notSilenceRanges[i+1][0]-notSilenceRanges[i][1]+1)/2
-		startI := max(int(startMin), int(notSilenceRanges[i][0])-keepSilence)
-		endI := min(int(endMax), int(notSilenceRanges[i][1])+keepSilence)
-
-		temp1, _ := seg.Slice(int64(startI), int64(endI))
-		if temp1 != nil {
-			chunks = append(chunks, temp1)
-			timings = append(timings, []float32{float32(startI) / 1000, float32(endI) / 1000.0})
-		}
-
-		startMin = notSilenceRanges[i][1]
-	}
-
-	startI := max(int(startMin), int(notSilenceRanges[len(notSilenceRanges)-1][0])-keepSilence)
-	endI := min(int(seg.Duration()), int(notSilenceRanges[len(notSilenceRanges)-1][1])+keepSilence)
-	temp2, _ := seg.Slice(int64(startI), int64(endI))
-	if temp2 != nil {
-		chunks = append(chunks, temp2)
-		timings = append(timings, []float32{float32(startI) / 1000, float32(endI) / 1000.0})
-	}
-
-	return chunks, timings, nil
+	return SplitOnSilenceWithOptions(seg, SplitOnSilenceOptions{
+		MinSilenceLen: minSilenceLen,
+		SilenceThresh: silenceThresh,
+		KeepSilence:   keepSilence,
+		SeekStep:      seekStep,
+		Concurrent:    true,
+	})
 }
 
-// SplitAudio 将音频文件按照指定的目标长度在静音处切分成多个片段
+// SplitAudioConcurrent 与SplitAudio相同,但直接处理音频片段并使用并发的静音检测
 // audio: 音频文件
 // targetLen: 目标长度(秒)，默认30分钟
 // win: 检测窗口大小(秒)，默认60秒
-func SplitAudioConcurrent(audio *AudioSegment, targetLen float64, win float64) ([][]float64, error) {
+// opts: 可选配置,如WithProgress设置进度回调,WithLogger设置日志(默认不输出任何内容)
+func SplitAudioConcurrent(audio *AudioSegment, targetLen float64, win float64, opts ...SplitOption) ([][]float64, error) {
+	config := newSplitConfig(opts)
+
 	if targetLen == 0 {
 		targetLen = 30 * 60 // 默认30分钟
 	}
@@ -270,7 +196,7 @@ func SplitAudioConcurrent(audio *AudioSegment, targetLen float64, win float64) (
 		win = 60 // 默认60秒
 	}
 
-	duration := float64(audio.Duration() / 1000) // 转换为秒
+	duration := float64(audio.Duration()) / 1000 // 转换为秒
 	if duration <= targetLen+win {
 		return [][]float64{{0, duration}}, nil
 	}
@@ -282,6 +208,7 @@ func SplitAudioConcurrent(audio *AudioSegment, targetLen float64, win float64) (
 	for pos < duration {
 		if duration-pos <= targetLen {
 			segments = append(segments, []float64{pos, duration})
+			config.progress(len(segments), duration)
 			break
 		}
 
@@ -292,17 +219,17 @@ func SplitAudioConcurrent(audio *AudioSegment, targetLen float64, win float64) (
 		// 切片获取检测区间的音频
 		windowAudio, err := audio.Slice(ws, we)
 		if err != nil {
-			return nil, fmt.Errorf("error slicing audio: %v", err)
+			return nil, fmt.Errorf("error slicing audio: %w", err)
 		}
 
 		// 检测静音区域
-		silenceRegions := DetectSilenceConcurrent(windowAudio, int64(safeMargin*1000), Volume(-30), 1)
+		silenceRegions := toSilenceRanges(DetectSilenceConcurrent(windowAudio, int64(safeMargin*1000), Volume(-30), 1))
 
-		// 将毫秒单位的静音区域转换为秒，并调整偏移
+		// 将静音区域转换为秒，并调整偏移
 		var validRegions [][]float64
 		for _, region := range silenceRegions {
-			start := float64(region[0])/1000 + (threshold - win)
-			end := float64(region[1])/1000 + (threshold - win)
+			start := region.Start.Seconds() + (threshold - win)
+			end := region.End.Seconds() + (threshold - win)
 
 			// 筛选长度足够且位置适合的静默区域
 			if (end-start) >= (safeMargin*2) &&
@@ -316,13 +243,14 @@ func SplitAudioConcurrent(audio *AudioSegment, targetLen float64, win float64) (
 		if len(validRegions) > 0 {
 			splitAt = validRegions[0][0] + safeMargin // 在静默区域起始点后0.5秒处切分
 		} else {
-			fmt.Printf("No valid silence regions found for audio file at %.1fs, using threshold\n", threshold)
+			config.logf("No valid silence regions found for audio file at %.1fs, using threshold", threshold)
 		}
 
 		segments = append(segments, []float64{pos, splitAt})
 		pos = splitAt
+		config.progress(len(segments), pos)
 	}
 
-	fmt.Printf("Audio split completed %d segments\n", len(segments))
+	config.logf("Audio split completed %d segments", len(segments))
 	return segments, nil
 }
@@ -0,0 +1,205 @@
+package godub
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectSilenceFrames(t *testing.T) {
+	// 10ms of 8kHz telephony audio with a 6-frame gap, which is shorter than 1ms.
+	var data []byte
+	for i := 0; i < 80; i++ {
+		if i >= 30 && i < 36 {
+			data = append(data, 0x00, 0x00)
+		} else {
+			data = append(data, 0x00, 0x40)
+		}
+	}
+	seg, err := NewAudioSegment(data, Channels(1), SampleWidth(2), FrameWidth(2), FrameRate(8000))
+	assert.NoError(t, err)
+
+	assert.Empty(t, DetectSilence(seg, 1, Volume(-40), 1))
+	assert.Equal(t, [][]int64{{30, 36}}, DetectSilenceFrames(seg, 4, Volume(-40), 1))
+}
+
+func TestSplitAudioProgress(t *testing.T) {
+	tone := newSineSegment(440, 0.5, 10000, 8000, 1)
+	path := filepath.Join(t.TempDir(), "tone.wav")
+	assert.NoError(t, os.WriteFile(path, encodeWav(t, tone), 0644))
+
+	var done []int
+	var positions []float64
+	segments, err := SplitAudio(path, 3, 1, WithProgress(func(segmentsDone int, posSeconds float64) {
+		done = append(done, segmentsDone)
+		positions = append(positions, posSeconds)
+	}))
+	assert.NoError(t, err)
+	assert.Len(t, done, len(segments))
+	for i := range segments {
+		assert.Equal(t, i+1, done[i])
+		assert.Equal(t, segments[i][1], positions[i])
+	}
+	assert.Equal(t, 10.0, positions[len(positions)-1])
+}
+
+func TestSplitAudioConcurrentLogger(t *testing.T) {
+	tone := newSineSegment(440, 0.5, 10000, 8000, 1)
+
+	var buf bytes.Buffer
+	segments, err := SplitAudioConcurrent(tone, 3, 1, WithLogger(log.New(&buf, "", 0)))
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "No valid silence regions found")
+	assert.Contains(t, buf.String(), fmt.Sprintf("Audio split completed %d segments", len(segments)))
+}
+
+func TestLeadingAndTrailingSilence(t *testing.T) {
+	silence, _ := NewSilentAudioSegmentWith(300, 8000, 2, 1)
+	tail, _ := NewSilentAudioSegmentWith(505, 8000, 2, 1)
+	tone := newSineSegment(440, 0.5, 1000, 8000, 1)
+	seg, _ := Concat(silence, tone, tail)
+
+	assert.Equal(t, int64(300), seg.LeadingSilence(-50, 10))
+	assert.Equal(t, int64(500), seg.TrailingSilence(-50, 10))
+
+	// The whole segment is silent
+	assert.Equal(t, tail.Duration(), tail.LeadingSilence(-50, 10))
+	assert.Equal(t, tail.Duration(), tail.TrailingSilence(-50, 10))
+
+	assert.Equal(t, int64(0), tone.LeadingSilence(-50, 10))
+	assert.Equal(t, int64(0), tone.TrailingSilence(-50, 0))
+}
+
+func TestAutoTrim(t *testing.T) {
+	silence, _ := NewSilentAudioSegmentWith(300, 8000, 2, 1)
+	tail, _ := NewSilentAudioSegmentWith(500, 8000, 2, 1)
+	tone := newSineSegment(440, 0.5, 1000, 8000, 1)
+	seg, _ := Concat(silence, tone, tail)
+
+	trimmed, err := seg.AutoTrim(-50, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, tone.RawData(), trimmed.RawData())
+
+	trimmed, err = tone.AutoTrim(-50, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, tone.RawData(), trimmed.RawData())
+
+	// The whole segment is silent
+	trimmed, err = tail.AutoTrim(-50, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, trimmed.Len())
+	assert.Equal(t, tail.FrameRate(), trimmed.FrameRate())
+}
+
+func TestDetectSilenceRanges(t *testing.T) {
+	silence, _ := NewSilentAudioSegmentWith(400, 8000, 2, 1)
+	tone := newSineSegment(440, 0.5, 600, 8000, 1)
+	seg, _ := Concat(tone, silence, tone)
+
+	ranges := DetectSilenceRanges(seg, 200*time.Millisecond, -50, 0)
+	assert.Equal(t, []SilenceRange{{Start: 600 * time.Millisecond, End: 1000 * time.Millisecond}}, ranges)
+	assert.Equal(t, 400*time.Millisecond, ranges[0].Duration())
+
+	assert.Equal(t, toSilenceRanges(DetectSilence(seg, 200, -50, 1)), ranges)
+	assert.Empty(t, DetectSilenceRanges(tone, 200*time.Millisecond, -50, time.Millisecond))
+}
+
+func TestMergeCloseRanges(t *testing.T) {
+	ranges := [][]int64{{0, 100}, {120, 300}, {400, 500}, {505, 600}}
+	assert.Equal(t, [][]int64{{0, 300}, {400, 600}}, MergeCloseRanges(ranges, 50))
+	assert.Equal(t, [][]int64{{0, 100}, {120, 300}, {400, 500}, {505, 600}}, ranges)
+	assert.Equal(t, ranges, MergeCloseRanges(ranges, 0))
+	assert.Empty(t, MergeCloseRanges(nil, 50))
+
+	// A 50ms blip breaks the silence in two
+	silence, _ := NewSilentAudioSegmentWith(400, 8000, 2, 1)
+	tone := newSineSegment(440, 0.5, 600, 8000, 1)
+	blip := newSineSegment(440, 0.5, 50, 8000, 1)
+	seg, _ := Concat(tone, silence, blip, silence, tone)
+
+	serial := DetectSilence(seg, 200, -50, 1)
+	concurrent := DetectSilenceConcurrent(seg, 200, -50, 1)
+	assert.Len(t, serial, 2)
+	assert.Equal(t, [][]int64{{600, 1450}}, MergeCloseRanges(serial, 100))
+	assert.Equal(t, MergeCloseRanges(serial, 100), MergeCloseRanges(concurrent, 100))
+}
+
+func TestSilenceRangesToEDL(t *testing.T) {
+	// 2500ms and 3661020ms are half frames at 25fps, rounded up
+	ranges := [][]int64{{1000, 2500}, {3661020, 3662000}}
+	assert.Equal(t, "event,type,start,end\n"+
+		"1,keep,00:00:00:00,00:00:01:00\n"+
+		"2,cut,00:00:01:00,00:00:02:13\n"+
+		"3,keep,00:00:02:13,01:01:01:01\n"+
+		"4,cut,01:01:01:01,01:01:02:00\n",
+		SilenceRangesToEDL(ranges, 25))
+
+	// Silence from the start has no keep before it
+	assert.Equal(t, "event,type,start,end\n1,cut,00:00:00:00,00:00:00:15\n",
+		SilenceRangesToEDL([][]int64{{0, 500}}, 29.97))
+
+	assert.Equal(t, "event,type,start,end\n", SilenceRangesToEDL(nil, 25))
+	assert.Equal(t, "", SilenceRangesToEDL(ranges, 0))
+}
+
+func TestSplitAudioFractionalDuration(t *testing.T) {
+	tone := newSineSegment(440, 0.5, 2500, 8000, 1)
+
+	segments, err := SplitAudioConcurrent(tone, 3, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{0, 2.5}}, segments)
+}
+
+func TestSplitOnSilence(t *testing.T) {
+	silence, _ := NewSilentAudioSegmentWith(500, 8000, 2, 1)
+	tone := newSineSegment(440, 0.01, 300, 8000, 1)
+	seg, _ := Concat(tone, silence, tone)
+
+	for _, split := range []func(*AudioSegment, int64, Volume, int, int) ([]*AudioSegment, [][]float32, error){
+		SplitOnSilence, SplitOnSilenceConcurrent,
+	} {
+		// The quiet tone is normalized to -20dBFS before detecting silence.
+		chunks, timings, err := split(seg, 200, -40, 0, 10)
+		assert.NoError(t, err)
+		assert.Len(t, chunks, 2)
+		assert.Len(t, timings, 2)
+
+		_, _, err = split(silence, 200, -40, 0, 10)
+		assert.Error(t, err)
+	}
+}
+
+func TestSplitOnSilenceKeepSilenceMode(t *testing.T) {
+	lead, _ := NewSilentAudioSegmentWith(200, 8000, 2, 1)
+	gap, _ := NewSilentAudioSegmentWith(600, 8000, 2, 1)
+	tone := newSineSegment(440, 0.5, 300, 8000, 1)
+	seg, _ := Concat(lead, tone, gap, tone, lead)
+
+	// Fixed keeps up to 400ms on both sides, the first chunk ends at the middle of the
+	// gap but the second one starts 400ms before the tone, 100ms are kept by both.
+	_, timings, err := SplitOnSilenceWithOptions(seg, SplitOnSilenceOptions{
+		MinSilenceLen: 300, SilenceThresh: -40, KeepSilence: 400, SeekStep: 1,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, timings, 2)
+	assert.InDelta(t, 0.8, timings[0][1], 0.002)
+	assert.InDelta(t, 0.7, timings[1][0], 0.002)
+
+	// Half splits the gap at the middle, the chunks join back into the audio.
+	chunks, timings, err := SplitOnSilenceWithOptions(seg, SplitOnSilenceOptions{
+		MinSilenceLen: 300, SilenceThresh: -40, KeepSilenceMode: KeepSilenceHalf, SeekStep: 1,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, timings, 2)
+	assert.Equal(t, timings[0][1], timings[1][0])
+	assert.InDelta(t, 0.8, timings[0][1], 0.002)
+	assert.Equal(t, float32(1.6), timings[1][1])
+	joined, _ := Concat(chunks...)
+	assert.Equal(t, seg.RawData(), joined.RawData())
+}
@@ -0,0 +1,165 @@
+package godub
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// FFT 计算第一个声道连续帧(不重叠)的幅度谱
+//
+// 参数:
+//   - windowSize: 每帧的采样数
+//
+// 返回:
+//   - [][]float64: 每帧一个幅度谱,包含 n/2+1 个频点(从0Hz到奈奎斯特频率)
+//   - error: 错误信息,如果有的话
+//
+// 说明:
+//   - 等价于 FFTWithHop(windowSize, windowSize)
+func (seg *AudioSegment) FFT(windowSize int) ([][]float64, error) {
+	return seg.FFTWithHop(windowSize, windowSize)
+}
+
+// FFTWithHop 计算第一个声道连续帧的幅度谱,相邻帧之间间隔hopSize个采样
+//
+// 参数:
+//   - windowSize: 每帧的采样数
+//   - hopSize: 帧移(采样数),小于windowSize时相邻帧重叠
+//
+// 说明:
+//   - 每帧先乘以Hann窗,再做纯Go实现的基2 FFT
+//   - windowSize不是2的幂时,每帧末尾补零到下一个2的幂n
+//   - 频率分辨率为 帧率 / n,第k个频点对应 k * 帧率 / n Hz
+//     (windowSize为2的幂时即 帧率 / windowSize)
+//   - 采样已归一化到[-1, 1],幅度未做额外归一化
+//   - 音频比一帧短时,补零后返回一帧
+func (seg *AudioSegment) FFTWithHop(windowSize, hopSize int) ([][]float64, error) {
+	if windowSize <= 0 || hopSize <= 0 {
+		return nil, NewAudioSegmentError("window size and hop size should be greater than 0")
+	}
+
+	channels, err := seg.ToFloat64()
+	if err != nil {
+		return nil, err
+	}
+	samples := channels[0]
+
+	n := nextPowerOfTwo(windowSize)
+	window := hannWindow(windowSize)
+
+	var spectra [][]float64
+	for start := 0; start == 0 || start+windowSize <= len(samples); start += hopSize {
+		buf := make([]complex128, n)
+		for i := 0; i < windowSize && start+i < len(samples); i++ {
+			buf[i] = complex(samples[start+i]*window[i], 0)
+		}
+
+		fft(buf)
+
+		magnitudes := make([]float64, n/2+1)
+		for k := range magnitudes {
+			magnitudes[k] = cmplx.Abs(buf[k])
+		}
+		spectra = append(spectra, magnitudes)
+	}
+
+	return spectra, nil
+}
+
+// fft is an in-place iterative radix-2 FFT, len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+
+	// Bit reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], w*x[start+k+size/2]
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
+// hannWindow returns a Hann window of the given size.
+func hannWindow(size int) []float64 {
+	window := make([]float64, size)
+	if size == 1 {
+		window[0] = 1
+		return window
+	}
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size-1))
+	}
+	return window
+}
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// maxDominantFrequencyWindow caps the analysis window of DominantFrequency.
+const maxDominantFrequencyWindow = 1 << 16
+
+// DominantFrequency 估计音频的主频率(Hz),即第一个声道幅度最大的频点
+//
+// 说明:
+//   - 在音频中间取长度为2的幂的窗口(最多65536个采样)做FFT,窗口越长精度越高
+//   - 精度为一个频点的宽度,即 帧率 / 窗口长度
+//   - 忽略直流分量(0Hz)
+//   - 静音或空的音频返回0
+func (seg *AudioSegment) DominantFrequency() (float64, error) {
+	frameCount := int(seg.FrameCount())
+
+	windowSize := 1
+	for windowSize*2 <= frameCount && windowSize*2 <= maxDominantFrequencyWindow {
+		windowSize *= 2
+	}
+
+	start := (frameCount - windowSize) / 2
+	if start < 0 {
+		start = 0
+	}
+	central, err := seg.SliceFrames(start, min(start+windowSize, frameCount))
+	if err != nil {
+		return 0, err
+	}
+
+	spectra, err := central.FFT(windowSize)
+	if err != nil {
+		return 0, err
+	}
+	spectrum := spectra[0]
+
+	peak := 0
+	for k := 1; k < len(spectrum); k++ {
+		if spectrum[k] > spectrum[peak] || peak == 0 {
+			peak = k
+		}
+	}
+
+	if peak == 0 || spectrum[peak] == 0 {
+		return 0, nil
+	}
+
+	return float64(peak) * float64(seg.frameRate) / float64(nextPowerOfTwo(windowSize)), nil
+}
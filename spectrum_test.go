@@ -0,0 +1,64 @@
+package godub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFFT(t *testing.T) {
+	// 1kHz at 8kHz, the peak is at bin 1000 / (8000 / 256) = 32
+	seg := newSineSegment(1000, 0.5, 100, 8000, 2)
+
+	spectra, err := seg.FFT(256)
+	assert.NoError(t, err)
+	assert.Len(t, spectra, 800/256)
+
+	for _, spectrum := range spectra {
+		assert.Len(t, spectrum, 129)
+		peak := 0
+		for k := range spectrum {
+			if spectrum[k] > spectrum[peak] {
+				peak = k
+			}
+		}
+		assert.Equal(t, 32, peak)
+	}
+
+	// Overlapping frames
+	spectra, err = seg.FFTWithHop(256, 128)
+	assert.NoError(t, err)
+	assert.Len(t, spectra, (800-256)/128+1)
+
+	// Zero-padded to 256
+	spectra, err = seg.FFT(200)
+	assert.NoError(t, err)
+	assert.Len(t, spectra[0], 129)
+
+	_, err = seg.FFT(0)
+	assert.Error(t, err)
+}
+
+func TestFFTImpulse(t *testing.T) {
+	x := make([]complex128, 8)
+	x[0] = 1
+	fft(x)
+	for _, v := range x {
+		assert.InDelta(t, 1, real(v), 1e-9)
+		assert.InDelta(t, 0, imag(v), 1e-9)
+	}
+}
+
+func TestDominantFrequency(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 1000, 44100, 1)
+
+	freq, err := seg.DominantFrequency()
+	assert.NoError(t, err)
+	// 32768 samples are analyzed, so the resolution is 44100 / 32768
+	assert.InDelta(t, 440, freq, 44100.0/32768)
+
+	silence, _ := NewSilentAudioSegmentWith(1000, 44100, 2, 1)
+	freq, err = silence.DominantFrequency()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, freq)
+}
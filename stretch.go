@@ -0,0 +1,206 @@
+package godub
+
+import "math"
+
+const (
+	// stretchFrameMs is the length (ms) of the WSOLA frames.
+	stretchFrameMs = 40
+	// stretchToleranceMs is how far (ms) WSOLA searches around the nominal position
+	// for the best matching frame.
+	stretchToleranceMs = 10
+)
+
+// TimeStretch 改变音频的时长而保持音高不变(时间伸缩)
+//
+// 参数:
+//   - factor: 时长的倍数,例如2表示时长变为两倍(变慢),0.5表示时长减半(变快)
+//
+// 说明:
+//   - 使用WSOLA(波形相似重叠相加)算法:按40ms的帧重叠相加,每一帧在±10ms范围内
+//     搜索与上一帧延续部分最相似的位置,以避免相位不连续
+//   - 所有声道使用相同的帧位置,声道之间保持同步
+//   - 结果的帧数为 原帧数 * factor,采样宽度和采样率保持不变
+//   - 与ForkWithFrameRate不同,不会改变音高,与Speedup不同,可以任意倍数伸缩
+//
+// 注意:
+//   - 适合语音和单音,对于复音的音乐或较大的倍数(如超过2倍)可能产生回声或颤动的失真
+//   - 瞬态(如鼓点)可能被模糊或重复
+//   - factor必须大于0
+func (seg *AudioSegment) TimeStretch(factor float64) (*AudioSegment, error) {
+	if !(factor > 0) || math.IsInf(factor, 0) {
+		return nil, NewAudioSegmentError("factor should be greater than 0, got %v", factor)
+	}
+
+	if factor == 1 {
+		return seg, nil
+	}
+
+	channels, err := seg.ToFloat64()
+	if err != nil {
+		return nil, err
+	}
+
+	stretched := wsola(channels, factor, int(seg.frameRate))
+	return seg.deriveFromFloat64(stretched)
+}
+
+// wsola time-stretches the samples by factor with waveform similarity overlap-add.
+func wsola(channels [][]float64, factor float64, frameRate int) [][]float64 {
+	frameCount := len(channels[0])
+	outCount := int(math.Round(float64(frameCount) * factor))
+
+	frameSize := max(frameRate*stretchFrameMs/1000/2*2, 2)
+	synthesisHop := frameSize / 2
+	analysisHop := float64(synthesisHop) / factor
+	tolerance := frameRate * stretchToleranceMs / 1000
+
+	// The frame positions are searched on the downmix, so the channels stay in sync.
+	mono := make([]float64, frameCount)
+	for _, samples := range channels {
+		for i, v := range samples {
+			mono[i] += v / float64(len(channels))
+		}
+	}
+	at := func(samples []float64, i int) float64 {
+		if i < 0 || i >= len(samples) {
+			return 0
+		}
+		return samples[i]
+	}
+
+	window := hannWindow(frameSize)
+	out := make([][]float64, len(channels))
+	for c := range out {
+		out[c] = make([]float64, outCount+frameSize)
+	}
+	norm := make([]float64, outCount+frameSize)
+
+	prevPos := 0
+	for k := 0; k*synthesisHop < outCount; k++ {
+		synthesisPos := k * synthesisHop
+		pos := int(math.Round(float64(k) * analysisHop))
+
+		if k > 0 {
+			// The frame overlapping the previous one best continues its natural
+			// successor, find the most similar one around the nominal position.
+			natural := prevPos + synthesisHop
+			best, bestCorr := pos, math.Inf(-1)
+			for d := -tolerance; d <= tolerance; d++ {
+				candidate := pos + d
+				if candidate < 0 {
+					continue
+				}
+				var corr float64
+				for j := 0; j < frameSize-synthesisHop; j++ {
+					corr += at(mono, natural+j) * at(mono, candidate+j)
+				}
+				if corr > bestCorr {
+					best, bestCorr = candidate, corr
+				}
+			}
+			pos = best
+		}
+
+		for j, w := range window {
+			for c, samples := range channels {
+				out[c][synthesisPos+j] += w * at(samples, pos+j)
+			}
+			norm[synthesisPos+j] += w
+		}
+		prevPos = pos
+	}
+
+	for c := range out {
+		for i := range out[c] {
+			if norm[i] > 1e-6 {
+				out[c][i] /= norm[i]
+			}
+		}
+		out[c] = out[c][:outCount]
+	}
+	return out
+}
+
+// PitchShift 改变音高而保持时长不变
+//
+// 参数:
+//   - semitones: 音高变化的半音数,正数升高,负数降低,12为一个八度
+//
+// 说明:
+//   - 先用TimeStretch把时长伸缩 2^(semitones/12) 倍,再重采样回原时长,音高随之改变
+//   - 质量限制与TimeStretch相同,变化越大失真越明显
+//   - 如果希望时长随音高一起变化(类似磁带变速),使用Varispeed
+func (seg *AudioSegment) PitchShift(semitones float64) (*AudioSegment, error) {
+	ratio, err := semitoneRatio(semitones)
+	if err != nil {
+		return nil, err
+	}
+
+	if ratio == 1 {
+		return seg, nil
+	}
+
+	// Check the ratio before stretching, an out of range one would stretch to a huge length.
+	if _, err = seg.relabeledFrameRate(ratio); err != nil {
+		return nil, err
+	}
+
+	stretched, err := seg.TimeStretch(ratio)
+	if err != nil {
+		return nil, err
+	}
+	return stretched.relabelFrameRate(ratio)
+}
+
+// Varispeed 同时改变音高和时长(类似改变磁带的播放速度)
+//
+// 参数:
+//   - semitones: 音高变化的半音数,正数升高(变快变短),负数降低(变慢变长)
+//
+// 说明:
+//   - 把采样率标记为原来的 2^(semitones/12) 倍,再重采样回原采样率,没有时间伸缩的失真
+//   - 时长变为原来的 1 / 2^(semitones/12)
+func (seg *AudioSegment) Varispeed(semitones float64) (*AudioSegment, error) {
+	ratio, err := semitoneRatio(semitones)
+	if err != nil {
+		return nil, err
+	}
+
+	if ratio == 1 {
+		return seg, nil
+	}
+	return seg.relabelFrameRate(ratio)
+}
+
+// semitoneRatio returns the frequency ratio of semitones, 2^(semitones/12).
+func semitoneRatio(semitones float64) (float64, error) {
+	ratio := math.Pow(2, semitones/12)
+	if math.IsNaN(ratio) || math.IsInf(ratio, 0) || ratio <= 0 {
+		return 0, NewAudioSegmentError("invalid pitch shift of %v semitones", semitones)
+	}
+	return ratio, nil
+}
+
+// relabelFrameRate plays the audio `ratio` times faster: the frames are labeled with
+// the frame rate multiplied by ratio, then resampled back to the frame rate.
+func (seg *AudioSegment) relabelFrameRate(ratio float64) (*AudioSegment, error) {
+	relabeledRate, err := seg.relabeledFrameRate(ratio)
+	if err != nil {
+		return nil, err
+	}
+
+	relabeled, err := seg.derive(seg.data, FrameRate(relabeledRate))
+	if err != nil {
+		return nil, err
+	}
+	return relabeled.ForkWithFrameRate(int(seg.frameRate))
+}
+
+// relabeledFrameRate returns the frame rate multiplied by ratio, or an error if it's out of range.
+func (seg *AudioSegment) relabeledFrameRate(ratio float64) (uint32, error) {
+	rate := math.Round(float64(seg.frameRate) * ratio)
+	if rate < 1 || rate > math.MaxInt32 {
+		return 0, NewAudioSegmentError("pitch shift ratio %v is out of range for frame rate %d", ratio, seg.frameRate)
+	}
+	return uint32(rate), nil
+}
@@ -0,0 +1,76 @@
+package godub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeStretch(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 1000, 16000, 2)
+
+	for _, factor := range []float64{2, 0.5, 1.25} {
+		stretched, err := seg.TimeStretch(factor)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1000*factor), stretched.Duration(), factor)
+		assert.Equal(t, seg.Channels(), stretched.Channels())
+		assert.Equal(t, seg.SampleWidth(), stretched.SampleWidth())
+		assert.Equal(t, seg.FrameRate(), stretched.FrameRate())
+
+		// The pitch and the level are kept
+		freq, err := stretched.DominantFrequency()
+		assert.NoError(t, err)
+		assert.InDelta(t, 440, freq, 5, factor)
+		assert.InDelta(t, float64(seg.DBFS()), float64(stretched.DBFS()), 0.5, factor)
+	}
+
+	same, err := seg.TimeStretch(1)
+	assert.NoError(t, err)
+	assert.Same(t, seg, same)
+
+	for _, factor := range []float64{0, -1} {
+		_, err = seg.TimeStretch(factor)
+		assert.Error(t, err)
+	}
+}
+
+func TestPitchShift(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 1000, 16000, 1)
+
+	for semitones, expected := range map[float64]float64{12: 880, -12: 220, 7: 659.26} {
+		shifted, err := seg.PitchShift(semitones)
+		assert.NoError(t, err)
+		assert.InDelta(t, seg.Duration(), shifted.Duration(), 2, semitones)
+		assert.Equal(t, seg.FrameRate(), shifted.FrameRate())
+
+		freq, err := shifted.DominantFrequency()
+		assert.NoError(t, err)
+		assert.InDelta(t, expected, freq, 8, semitones)
+	}
+
+	same, err := seg.PitchShift(0)
+	assert.NoError(t, err)
+	assert.Same(t, seg, same)
+
+	_, err = seg.PitchShift(10000)
+	assert.Error(t, err)
+}
+
+func TestVarispeed(t *testing.T) {
+	seg := newSineSegment(440, 0.5, 1000, 16000, 1)
+
+	shifted, err := seg.Varispeed(12)
+	assert.NoError(t, err)
+	assert.InDelta(t, 500, shifted.Duration(), 2)
+	assert.Equal(t, seg.FrameRate(), shifted.FrameRate())
+	freq, err := shifted.DominantFrequency()
+	assert.NoError(t, err)
+	assert.InDelta(t, 880, freq, 8)
+
+	shifted, err = seg.Varispeed(-12)
+	assert.NoError(t, err)
+	assert.InDelta(t, 2000, shifted.Duration(), 2)
+
+	_, err = seg.Varispeed(-10000)
+	assert.Error(t, err)
+}
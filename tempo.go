@@ -0,0 +1,98 @@
+package godub
+
+import "math"
+
+const (
+	// Parameters of DetectBPM.
+	bpmMin           = 60
+	bpmMax           = 180
+	bpmLowPassCutoff = 150.0
+	bpmEnvelopeHopMs = 10
+	bpmMinDurationMs = 3000
+)
+
+// DetectBPM 估计音频的节拍速度(BPM)
+//
+// 计算过程:
+//  1. 混缩为单声道并做150Hz低通滤波,保留底鼓/贝斯等低频节奏
+//  2. 以10ms为一块计算能量包络,取能量的正向差分作为起音(onset)强度
+//  3. 对起音强度做自相关,在60–180 BPM对应的周期中找最强的周期
+//
+// 注意:
+//   - 音频时长不足3秒时返回错误
+//   - 精度约为±1 BPM(包络分辨率为10ms,已用抛物线插值细化)
+//   - 可能出现倍频/半频错误,例如把90 BPM识别为180 BPM,
+//     节奏不明显(如人声、环境声)的音频结果没有意义
+func (seg *AudioSegment) DetectBPM() (float64, error) {
+	if seg.Duration() < bpmMinDurationMs {
+		return 0, NewAudioSegmentError("audio should be at least %dms long to detect BPM", bpmMinDurationMs)
+	}
+
+	channels, err := seg.ToFloat64()
+	if err != nil {
+		return 0, err
+	}
+
+	// Downmix and low pass
+	lowPass := newLowPass(bpmLowPassCutoff, float64(seg.frameRate))
+	mono := make([]float64, len(channels[0]))
+	for i := range mono {
+		var sum float64
+		for _, samples := range channels {
+			sum += samples[i]
+		}
+		mono[i] = lowPass.process(sum / float64(len(channels)))
+	}
+
+	// Energy envelope and onset strength
+	hop := int(seg.frameRate) * bpmEnvelopeHopMs / 1000
+	if hop == 0 {
+		return 0, NewAudioSegmentError("frame rate %d is too low to detect BPM", seg.frameRate)
+	}
+	energies := make([]float64, len(mono)/hop)
+	for i := range energies {
+		for _, v := range mono[i*hop : (i+1)*hop] {
+			energies[i] += v * v
+		}
+	}
+	onsets := make([]float64, len(energies))
+	for i := 1; i < len(energies); i++ {
+		onsets[i] = math.Max(0, energies[i]-energies[i-1])
+	}
+
+	// Autocorrelation over the lags of the BPM range
+	hopsPerMinute := 60.0 * 1000 / bpmEnvelopeHopMs
+	minLag := int(math.Floor(hopsPerMinute / bpmMax))
+	maxLag := int(math.Ceil(hopsPerMinute / bpmMin))
+	if maxLag+1 >= len(onsets) {
+		return 0, NewAudioSegmentError("audio is too short to detect BPM")
+	}
+
+	autocorrelation := func(lag int) float64 {
+		var sum float64
+		for i := lag; i < len(onsets); i++ {
+			sum += onsets[i] * onsets[i-lag]
+		}
+		return sum
+	}
+
+	bestLag, best := 0, 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		if r := autocorrelation(lag); r > best {
+			bestLag, best = lag, r
+		}
+	}
+
+	if bestLag == 0 {
+		return 0, NewAudioSegmentError("no periodicity is found in the audio")
+	}
+
+	// Refine the lag with parabolic interpolation
+	lag := float64(bestLag)
+	prev, next := autocorrelation(bestLag-1), autocorrelation(bestLag+1)
+	if denominator := prev - 2*best + next; denominator != 0 {
+		lag += 0.5 * (prev - next) / denominator
+	}
+
+	return hopsPerMinute / lag, nil
+}
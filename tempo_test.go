@@ -0,0 +1,32 @@
+package godub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newClickTrack returns a 8kHz mono track with 50ms low frequency kicks at the given BPM.
+func newClickTrack(bpm float64, duration int64) *AudioSegment {
+	kick := newSineSegment(80, 0.8, 50, 8000, 1)
+	interval := int64(60 * 1000 / bpm)
+
+	track, _ := NewSilentAudioSegmentWith(duration, 8000, 2, 1)
+	var layers []OverlayLayer
+	for pos := int64(0); pos < duration; pos += interval {
+		layers = append(layers, OverlayLayer{Segment: kick, Position: pos})
+	}
+	track, _ = track.OverlayMany(layers)
+	return track
+}
+
+func TestDetectBPM(t *testing.T) {
+	for _, bpm := range []float64{80, 120, 150} {
+		detected, err := newClickTrack(bpm, 8000).DetectBPM()
+		assert.NoError(t, err)
+		assert.InDelta(t, bpm, detected, 2, "bpm %v", bpm)
+	}
+
+	_, err := newClickTrack(120, 1000).DetectBPM()
+	assert.Error(t, err)
+}
@@ -12,7 +12,12 @@ func MapKeys(m map[string]interface{}) (keys []string) {
 }
 
 func ConcatenateByteSlice(items ...[]byte) []byte {
-	result := make([]byte, 0)
+	size := 0
+	for _, item := range items {
+		size += len(item)
+	}
+
+	result := make([]byte, 0, size)
 	for _, item := range items {
 		result = append(result, item...)
 	}
@@ -15,7 +15,8 @@ func NewVolumeFromRatio(ratio float64, denominator float64, useAmplitude bool) V
 	}
 
 	if ratio == 0 {
-		return Volume(math.Inf(1))
+		// Silence is infinitely quiet
+		return Volume(math.Inf(-1))
 	}
 
 	if useAmplitude {
@@ -1,6 +1,7 @@
 package godub
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,4 +21,6 @@ func TestVolume(t *testing.T) {
 	assert.Equal(t, 10, int(Volume(10).ToRatio(false)))
 
 	assert.Equal(t, "2.120dBFS", Volume(2.12).String())
+
+	assert.True(t, math.IsInf(float64(NewVolumeFromRatio(0, 0, true)), -1))
 }
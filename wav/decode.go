@@ -8,6 +8,10 @@ import (
 	"bytes"
 )
 
+// maxFmtChunkSize bounds the fmt chunk read by DecodeHeader, real ones are 16, 18 or
+// 40 bytes, so a corrupted size doesn't make it allocate gigabytes.
+const maxFmtChunkSize = 64 << 10
+
 func Decode(r io.Reader) (*WaveAudio, error) {
 	d, err := NewDecoder(r)
 	if err != nil {
@@ -17,6 +21,12 @@ func Decode(r io.Reader) (*WaveAudio, error) {
 	return d.Decode()
 }
 
+// DecodeBytes is like Decode but reads the WAV audio from a byte slice. `b` is not
+// modified, and RawData of the returned audio doesn't share memory with it.
+func DecodeBytes(b []byte) (*WaveAudio, error) {
+	return Decode(bytes.NewReader(b))
+}
+
 type Decoder struct {
 	buffer []byte
 	chunks []Chunk
@@ -34,37 +44,121 @@ func NewDecoder(r io.Reader) (*Decoder, error) {
 	return d, nil
 }
 
+// DecodeHeader reads the chunks until the start of the audio data, and returns the
+// format of the audio and the size of the data in bytes. RawData of the returned audio
+// is nil, the reader is left at the first byte of the data. The size is 0 or 0xFFFFFFFF
+// if it's unset, e.g. for streamed WAV.
+func DecodeHeader(r io.Reader) (*WaveAudio, uint32, error) {
+	buf := make([]byte, 12, 64)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, 0, err
+	}
+	if !bytes.Equal(buf[0:4], RiffHeader) || !bytes.Equal(buf[8:12], WaveHeader) {
+		return nil, 0, DecodeError("Could not find RIFF/WAVE header in wav data")
+	}
+
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, 0, DecodeError("Could not find data header in wav data")
+		}
+		buf = append(buf, header...)
+
+		size := binary.LittleEndian.Uint32(header[4:8])
+		if bytes.Equal(header[0:4], DataHeader) {
+			d := &Decoder{buffer: buf}
+			d.chunks = d.readChunks()
+			audio, err := d.decodeFormat()
+			if err != nil {
+				return nil, 0, err
+			}
+			return audio, size, nil
+		}
+
+		// Keep the fmt chunk to decode it later, skip the others.
+		body := int64(size) + int64(size%2)
+		if bytes.Equal(header[0:4], FmtHeader) {
+			if body > maxFmtChunkSize {
+				return nil, 0, DecodeError(fmt.Sprintf("The fmt chunk of %d bytes is too large", size))
+			}
+			chunk := make([]byte, body)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return nil, 0, err
+			}
+			buf = append(buf, chunk...)
+		} else {
+			if _, err := io.CopyN(io.Discard, r, body); err != nil {
+				return nil, 0, err
+			}
+			// Keep the chunk list valid for the decoder.
+			buf = buf[:len(buf)-8]
+		}
+	}
+}
+
 func (d *Decoder) Decode() (*WaveAudio, error) {
 	d.patchHeaders()
 
+	audio, err := d.decodeFormat()
+	if err != nil {
+		return nil, err
+	}
+
+	dataChunk := d.findChunk(DataHeader)
+	if dataChunk == nil {
+		return nil, DecodeError("Could not find data header in wav data")
+	}
+
+	pos := dataChunk.Position + 8
+	audio.RawData = d.buffer[pos : uint32(pos)+dataChunk.Size]
+	return audio, nil
+}
+
+// decodeFormat decodes the fmt chunk, RawData of the returned audio is not set.
+func (d *Decoder) decodeFormat() (*WaveAudio, error) {
 	fmtChunk := d.findChunk(FmtHeader)
-	if fmtChunk == nil || fmtChunk.Size < 16 {
+	if fmtChunk == nil || fmtChunk.Size < 16 || fmtChunk.Position+8+16 > len(d.buffer) {
 		return nil, DecodeError("Could not find fmt header in wav data")
 	}
 
 	pos := fmtChunk.Position + 8
 	audioFormat := binary.LittleEndian.Uint16(d.buffer[pos : pos+2])
-	if audioFormat != 1 && audioFormat != 0xFFFE {
-		return nil, DecodeError(fmt.Sprintf("unknown audio format 0x%X in wav data", audioFormat))
-	}
 
 	channels := binary.LittleEndian.Uint16(d.buffer[pos+2 : pos+4])
 	sampleRate := binary.LittleEndian.Uint32(d.buffer[pos+4 : pos+8])
 	// bit depth
 	bitsPerSample := binary.LittleEndian.Uint16(d.buffer[pos+14 : pos+16])
 
-	dataChunk := d.findChunk(DataHeader)
-	if dataChunk == nil {
-		return nil, DecodeError("Could not find data header in wav data")
+	var validBitsPerSample uint16
+	var channelMask uint32
+	if audioFormat == AudioFormatExtensible {
+		// WAVEFORMATEXTENSIBLE: cbSize(2) + ValidBitsPerSample(2) + ChannelMask(4) + SubFormat(16)
+		if fmtChunk.Size >= 40 && pos+40 <= len(d.buffer) {
+			validBitsPerSample = binary.LittleEndian.Uint16(d.buffer[pos+18 : pos+20])
+			channelMask = binary.LittleEndian.Uint32(d.buffer[pos+20 : pos+24])
+
+			subFormat := d.buffer[pos+24 : pos+40]
+			if !bytes.Equal(subFormat[2:], subFormatGUIDSuffix) {
+				return nil, DecodeError(fmt.Sprintf("unknown sub format %X in wav data", subFormat))
+			}
+			audioFormat = binary.LittleEndian.Uint16(subFormat[0:2])
+		} else {
+			// The extension is missing, assume PCM like most of the readers do.
+			audioFormat = AudioFormatPCM
+		}
+	}
+
+	if audioFormat != AudioFormatPCM && audioFormat != AudioFormatIEEEFloat {
+		return nil, DecodeError(fmt.Sprintf("unknown audio format 0x%X in wav data", audioFormat))
 	}
 
-	pos = dataChunk.Position + 8
 	return &WaveAudio{
-		Format:        audioFormat,
-		Channels:      channels,
-		SampleRate:    sampleRate,
-		BitsPerSample: bitsPerSample,
-		RawData:       d.buffer[pos : uint32(pos)+dataChunk.Size],
+		Format:             audioFormat,
+		Channels:           channels,
+		SampleRate:         sampleRate,
+		BitsPerSample:      bitsPerSample,
+		ValidBitsPerSample: validBitsPerSample,
+		ChannelMask:        channelMask,
 	}, nil
 }
 
@@ -73,11 +167,9 @@ func (d *Decoder) readChunks() []Chunk {
 	pos := 12
 	subChunks := make([]Chunk, 0)
 
-	for {
-		if pos+8 >= len(d.buffer) || len(subChunks) >= 10 {
-			break
-		}
-
+	// Chunks are iterated by ID/size, unknown ones (fact, bext, LIST...) are kept
+	// but skipped by the decoder, either before or after `data`.
+	for pos+8 <= len(d.buffer) {
 		header := d.buffer[pos : pos+4]
 		subChunkSize := binary.LittleEndian.Uint32(d.buffer[pos+4 : pos+8])
 		subChunks = append(
@@ -85,11 +177,13 @@ func (d *Decoder) readChunks() []Chunk {
 			Chunk{Header: header, Position: pos, Size: subChunkSize},
 		)
 
-		if bytes.Equal(header, DataHeader) {
-			// `data` is the last subchunk
+		// Chunks are word aligned, an odd sized chunk is followed by a pad byte.
+		next := uint64(pos) + 8 + uint64(subChunkSize) + uint64(subChunkSize%2)
+		if next > uint64(len(d.buffer)) {
+			// Truncated, or the size is unset in a streamed file.
 			break
 		}
-		pos += int(subChunkSize) + 8
+		pos = int(next)
 	}
 
 	return subChunks
@@ -111,11 +205,18 @@ func (d *Decoder) patchHeaders() {
 		return
 	}
 
+	// Keep the sizes if they're valid, the data may be followed by a pad byte
+	// or other chunks. Streamed WAV (e.g. ffmpeg writing to a pipe) leaves them
+	// unset (0 or 0xFFFFFFFF).
+	pos := dataChunk.Position
+	if dataChunk.Size != 0 && uint64(pos)+8+uint64(dataChunk.Size) <= uint64(len(d.buffer)) {
+		return
+	}
+
 	// Set the file size in the RIFF chunk descriptor
 	binary.LittleEndian.PutUint32(d.buffer[4:8], uint32(len(d.buffer)-8))
 
 	// Set the data size in the subchunk
-	pos := dataChunk.Position
 	binary.LittleEndian.PutUint32(d.buffer[pos+4:pos+8], uint32(len(d.buffer)-pos-8))
 
 	// Refresh chunks
@@ -0,0 +1,141 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// extensibleWav builds a WAVE_FORMAT_EXTENSIBLE file with the given sub format code.
+func extensibleWav(subFormat uint16, bitsPerSample, validBits uint16, channelMask uint32, data []byte) []byte {
+	var fmtChunk bytes.Buffer
+	channels := uint16(2)
+	blockAlign := bitsPerSample / 8 * channels
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(AudioFormatExtensible))
+	binary.Write(&fmtChunk, binary.LittleEndian, channels)
+	binary.Write(&fmtChunk, binary.LittleEndian, uint32(48000))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint32(48000)*uint32(blockAlign))
+	binary.Write(&fmtChunk, binary.LittleEndian, blockAlign)
+	binary.Write(&fmtChunk, binary.LittleEndian, bitsPerSample)
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(22))
+	binary.Write(&fmtChunk, binary.LittleEndian, validBits)
+	binary.Write(&fmtChunk, binary.LittleEndian, channelMask)
+	binary.Write(&fmtChunk, binary.LittleEndian, subFormat)
+	fmtChunk.Write(subFormatGUIDSuffix)
+
+	var buf bytes.Buffer
+	buf.Write(RiffHeader)
+	binary.Write(&buf, binary.LittleEndian, uint32(4+8+fmtChunk.Len()+8+len(data)))
+	buf.Write(WaveHeader)
+	buf.Write(FmtHeader)
+	binary.Write(&buf, binary.LittleEndian, uint32(fmtChunk.Len()))
+	buf.Write(fmtChunk.Bytes())
+	buf.Write(DataHeader)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestDecodeExtensible(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+
+	audio, err := Decode(bytes.NewReader(extensibleWav(AudioFormatPCM, 24, 20, 0x3, data)))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(AudioFormatPCM), audio.Format)
+	assert.Equal(t, uint16(2), audio.Channels)
+	assert.Equal(t, uint32(48000), audio.SampleRate)
+	assert.Equal(t, uint16(24), audio.BitsPerSample)
+	assert.Equal(t, uint16(20), audio.ValidBitsPerSample)
+	assert.Equal(t, uint32(0x3), audio.ChannelMask)
+	assert.Equal(t, data, audio.RawData)
+
+	audio, err = Decode(bytes.NewReader(extensibleWav(AudioFormatIEEEFloat, 32, 32, 0x3, data[:8])))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(AudioFormatIEEEFloat), audio.Format)
+
+	// Unknown sub format
+	_, err = Decode(bytes.NewReader(extensibleWav(0x55, 16, 16, 0x3, data)))
+	assert.Error(t, err)
+}
+
+func TestDecodeExtraChunks(t *testing.T) {
+	audio := &WaveAudio{
+		Format:        AudioFormatPCM,
+		Channels:      1,
+		SampleRate:    8000,
+		BitsPerSample: 16,
+		RawData:       []byte{1, 2, 3, 4},
+	}
+
+	var encoded bytes.Buffer
+	assert.NoError(t, Encode(&encoded, audio))
+	plain := encoded.Bytes()
+
+	// An odd sized `bext` chunk (followed by a pad byte) between `fmt ` and `data`,
+	// and a trailing `LIST` chunk after `data`.
+	bext := append([]byte{'b', 'e', 'x', 't', 5, 0, 0, 0}, 'h', 'e', 'l', 'l', 'o', 0)
+	list := append([]byte{'L', 'I', 'S', 'T', 4, 0, 0, 0}, 'I', 'N', 'F', 'O')
+
+	var buf bytes.Buffer
+	buf.Write(plain[:36])
+	buf.Write(bext)
+	buf.Write(plain[36:])
+	buf.Write(list)
+	out := buf.Bytes()
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+
+	decoded, err := Decode(bytes.NewReader(out))
+	assert.NoError(t, err)
+	assert.Equal(t, audio, decoded)
+}
+
+func TestDecodeHeader(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	wav := extensibleWav(AudioFormatPCM, 16, 16, 0x3, data)
+
+	// A `LIST` chunk before `fmt ` is skipped.
+	list := []byte{'L', 'I', 'S', 'T', 3, 0, 0, 0, 'a', 'b', 'c', 0}
+	wav = append(append(append([]byte{}, wav[:12]...), list...), wav[12:]...)
+
+	r := bytes.NewReader(wav)
+	audio, dataSize, err := DecodeHeader(r)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(len(data)), dataSize)
+	assert.Equal(t, uint16(AudioFormatPCM), audio.Format)
+	assert.Equal(t, uint16(2), audio.Channels)
+	assert.Equal(t, uint32(48000), audio.SampleRate)
+	assert.Nil(t, audio.RawData)
+
+	// The reader is left at the data.
+	assert.Equal(t, len(data), r.Len())
+
+	_, _, err = DecodeHeader(bytes.NewReader(wav[:len(wav)-len(data)-4]))
+	assert.Error(t, err)
+
+	_, _, err = DecodeHeader(bytes.NewReader([]byte("not a wav file")))
+	assert.Error(t, err)
+
+	// A huge fmt size is rejected before anything is allocated for it.
+	huge := []byte{'R', 'I', 'F', 'F', 0, 0, 0, 0, 'W', 'A', 'V', 'E', 'f', 'm', 't', ' ', 0xf0, 0xff, 0xff, 0xff}
+	_, _, err = DecodeHeader(bytes.NewReader(huge))
+	assert.IsType(t, DecodeError(""), err)
+}
+
+func TestDecodeBytes(t *testing.T) {
+	data := []byte{1, 2, 3, 4}
+	b := extensibleWav(AudioFormatPCM, 16, 16, 0x3, data)
+
+	audio, err := DecodeBytes(b)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(2), audio.Channels)
+	assert.Equal(t, data, audio.RawData)
+
+	// The audio is a copy.
+	audio.RawData[0] = 0xff
+	assert.Equal(t, byte(1), b[len(b)-len(data)])
+
+	_, err = DecodeBytes(nil)
+	assert.Error(t, err)
+}
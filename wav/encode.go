@@ -3,17 +3,52 @@ package wav
 import (
 	"encoding/binary"
 	"io"
+	"math"
 )
 
 // Encode encodes wave audio to a given writer.
 // WAV file ref: http://www.topherlee.com/software/pcm-tut-wavformat.html
 func Encode(w io.Writer, audio *WaveAudio) error {
+	_, err := audio.WriteTo(w)
+	return err
+}
+
+// WriteTo writes the wave audio as a RIFF/WAVE file, it implements io.WriterTo.
+// A pad byte is appended if the size of the data chunk is odd, as RIFF chunks
+// must be word aligned.
+func (w *WaveAudio) WriteTo(out io.Writer) (int64, error) {
+	cw := &countingWriter{w: out}
+
+	err := EncodeHeader(cw, w, w.DataSize())
+	if err != nil {
+		return cw.n, err
+	}
+
+	// Write raw data directly
+	_, err = cw.Write(w.RawData)
+	if err != nil {
+		return cw.n, err
+	}
+
+	if w.DataSize()%2 == 1 {
+		_, err = cw.Write([]byte{0})
+	}
+	return cw.n, err
+}
+
+// EncodeHeader encodes the 44 bytes header of wave audio with the given data size,
+// the raw data is not written. It's useful to stream the raw data afterwards.
+func EncodeHeader(w io.Writer, audio *WaveAudio, dataSize uint32) error {
 	// Write RIFF header
 	_, err := w.Write(RiffHeader)
 	if err != nil {
 		return err
 	}
-	riffSize := 4 + 8 + 16 + 8 + audio.DataSize()
+	riffSize := 4 + 8 + 16 + 8 + uint64(dataSize) + uint64(dataSize%2)
+	if riffSize > math.MaxUint32 {
+		// The data size is a placeholder of streaming, e.g. 0xFFFFFFFF.
+		riffSize = math.MaxUint32
+	}
 	err = binary.Write(w, binary.LittleEndian, uint32(riffSize))
 	if err != nil {
 		return err
@@ -72,16 +107,22 @@ func Encode(w io.Writer, audio *WaveAudio) error {
 		return err
 	}
 
-	err = binary.Write(w, binary.LittleEndian, audio.DataSize())
-	if err != nil {
-		return err
-	}
-
-	// Write raw data directly
-	_, err = w.Write(audio.RawData)
+	err = binary.Write(w, binary.LittleEndian, dataSize)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,65 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaveAudioWriteTo(t *testing.T) {
+	audio := &WaveAudio{
+		Format:        AudioFormatPCM,
+		Channels:      2,
+		SampleRate:    44100,
+		BitsPerSample: 16,
+		RawData:       []byte{1, 2, 3, 4, 5, 6, 7, 8},
+	}
+
+	var buf bytes.Buffer
+	n, err := audio.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(44+8), n)
+
+	out := buf.Bytes()
+	assert.Equal(t, uint32(36+8), binary.LittleEndian.Uint32(out[4:8]))
+	assert.Equal(t, uint32(44100*4), binary.LittleEndian.Uint32(out[28:32]))
+	assert.Equal(t, uint16(4), binary.LittleEndian.Uint16(out[32:34]))
+	assert.Equal(t, uint32(8), binary.LittleEndian.Uint32(out[40:44]))
+
+	decoded, err := Decode(bytes.NewReader(out))
+	assert.NoError(t, err)
+	assert.Equal(t, audio, decoded)
+}
+
+func TestWaveAudioWriteToPadding(t *testing.T) {
+	audio := &WaveAudio{
+		Format:        AudioFormatPCM,
+		Channels:      1,
+		SampleRate:    8000,
+		BitsPerSample: 8,
+		RawData:       []byte{1, 2, 3},
+	}
+
+	var buf bytes.Buffer
+	n, err := audio.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(44+4), n)
+
+	out := buf.Bytes()
+	assert.Equal(t, byte(0), out[47])
+	assert.Equal(t, uint32(36+4), binary.LittleEndian.Uint32(out[4:8]))
+	// The size of the data chunk doesn't count the pad byte
+	assert.Equal(t, uint32(3), binary.LittleEndian.Uint32(out[40:44]))
+
+	decoded, err := Decode(bytes.NewReader(out))
+	assert.NoError(t, err)
+	assert.Equal(t, audio.RawData, decoded.RawData)
+}
+
+func TestBlockAlign(t *testing.T) {
+	audio := &WaveAudio{Channels: 2, SampleRate: 48000, BitsPerSample: 12}
+	assert.Equal(t, uint16(4), audio.BlockAlign())
+	assert.Equal(t, uint32(192000), audio.ByteRate())
+}
@@ -25,9 +25,19 @@ package wav
 
 const (
 	AudioFormatPCM = 1
+	// AudioFormatIEEEFloat is for 32/64-bit floating point samples.
+	AudioFormatIEEEFloat = 3
+	// AudioFormatExtensible is WAVE_FORMAT_EXTENSIBLE, the actual format is in the SubFormat GUID.
+	AudioFormatExtensible = 0xFFFE
 )
 
 var (
+	// subFormatGUIDSuffix is the common part of the KSDATAFORMAT_SUBTYPE_* GUIDs,
+	// the first 2 bytes of the GUID are the format code.
+	subFormatGUIDSuffix = []byte{
+		0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71,
+	}
+
 	WaveHeader = []byte{'W', 'A', 'V', 'E'}
 	RiffHeader = []byte{'R', 'I', 'F', 'F'}
 	FmtHeader  = []byte{'f', 'm', 't', ' '}
@@ -41,23 +51,44 @@ type Chunk struct {
 }
 
 type WaveAudio struct {
+	// Format is AudioFormatPCM or AudioFormatIEEEFloat, for WAVE_FORMAT_EXTENSIBLE
+	// it's the format of the SubFormat GUID.
 	Format        uint16
 	Channels      uint16
 	SampleRate    uint32
 	BitsPerSample uint16
-	RawData       []byte
+	// ValidBitsPerSample is the number of valid bits in each sample container, e.g. 20-bit
+	// audio in 24-bit containers. It's only set by WAVE_FORMAT_EXTENSIBLE, otherwise 0.
+	ValidBitsPerSample uint16
+	// ChannelMask tells the speaker positions of the channels (SPEAKER_FRONT_LEFT = 0x1,
+	// SPEAKER_FRONT_RIGHT = 0x2...). It's only set by WAVE_FORMAT_EXTENSIBLE, otherwise 0.
+	ChannelMask uint32
+	RawData     []byte
 }
 
 func (w *WaveAudio) DataSize() uint32 {
 	return uint32(len(w.RawData))
 }
 
+// SampleFreq returns the byte rate, see ByteRate.
 func (w *WaveAudio) SampleFreq() uint32 {
-	return (w.SampleRate * uint32(w.BitsPerSample) * uint32(w.Channels)) / 8
+	return w.ByteRate()
 }
 
+// Sound returns the block align, see BlockAlign.
 func (w *WaveAudio) Sound() uint16 {
 	// mono: 8 bit, 1
 	// stereo: 16 bit, 2
-	return (w.BitsPerSample * w.Channels) / 8
+	return w.BlockAlign()
+}
+
+// ByteRate returns the number of bytes per second: SampleRate * BlockAlign.
+func (w *WaveAudio) ByteRate() uint32 {
+	return w.SampleRate * uint32(w.BlockAlign())
+}
+
+// BlockAlign returns the number of bytes per frame. Samples are stored in whole
+// bytes, e.g. a 12-bit sample takes 2 bytes.
+func (w *WaveAudio) BlockAlign() uint16 {
+	return (w.BitsPerSample + 7) / 8 * w.Channels
 }